@@ -5,19 +5,24 @@ import (
   "flag"
   "fmt"
   "os"
+  "path/filepath"
   "strings"
+  "unicode/utf8"
 
   "github.com/jmoiron/sqlx"
+  "github.com/foozio/quran-go/internal/concord"
   "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/internal/dump"
+  "github.com/foozio/quran-go/internal/export"
+  qsearch "github.com/foozio/quran-go/internal/search"
+  "github.com/foozio/quran-go/internal/similar"
+  "github.com/foozio/quran-go/pkg/quran"
 )
 
 func main() {
   ctx := context.Background()
-  path := os.Getenv("QURAN_DB_PATH")
-  if path == "" { path = "quran.db" }
-
-  d, err := db.Open(path); must(err)
-  must(db.Migrate(ctx, d))
+  st, err := db.OpenStore(ctx, db.ConfigFromEnv()); must(err)
+  d := st.DB()
 
   if len(os.Args) < 2 {
     usage()
@@ -26,16 +31,89 @@ func main() {
   cmd := os.Args[1]
   switch cmd {
   case "list":
-    listSurah(d)
+    listSurah(ctx, st)
   case "surah":
     flags := flag.NewFlagSet("surah", flag.ExitOnError)
     n := flags.Int("n", 1, "surah number (1-114)")
     _ = flags.Parse(os.Args[2:])
-    getSurah(d, *n)
+    getSurah(ctx, st, *n)
   case "search":
     q := strings.Join(os.Args[2:], " ")
     if strings.TrimSpace(q) == "" { fmt.Println("Usage: quran-cli search <query>"); return }
-    search(ctx, d, q)
+    search(ctx, st, q)
+  case "similar":
+    flags := flag.NewFlagSet("similar", flag.ExitOnError)
+    n := flags.Int("n", 10, "number of ayah to show")
+    _ = flags.Parse(os.Args[2:])
+    if flags.NArg() != 1 { fmt.Println("Usage: quran-cli similar [-n 10] <surah:ayah>"); return }
+    ref, err := quran.ParseRef(flags.Arg(0))
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    showSimilar(ctx, d, ref, *n)
+  case "freq":
+    flags := flag.NewFlagSet("freq", flag.ExitOnError)
+    col := flags.String("col", "", "arabic or trans (default: from the word's script, trans for the top list)")
+    surah := flags.Int("surah", 0, "count within a surah")
+    juz := flags.Int("juz", 0, "count within a juz")
+    n := flags.Int("n", 20, "words in the top list, or lines with -kwic")
+    kwic := flags.Bool("kwic", false, "list occurrences in context")
+    width := flags.Int("context", 5, "words of context on either side (-kwic)")
+    _ = flags.Parse(os.Args[2:])
+    if flags.NArg() > 1 { fmt.Println("Usage: quran-cli freq [-col arabic|trans] [-surah N | -juz N] [-n 20] [-kwic] [word]"); return }
+    showFreq(ctx, d, flags.Arg(0), *col, concord.Scope{Surah: *surah, Juz: *juz}, *n, *kwic, *width)
+  case "plan":
+    runPlan(ctx, d, os.Args[2:])
+  case "vectors":
+    flags := flag.NewFlagSet("vectors", flag.ExitOnError)
+    in := flags.String("import", "", "JSONL file of {surah, ayah, vector} from another model (default: build TF-IDF/LSA vectors)")
+    model := flags.String("model", "", "model name stored with imported vectors (default: file name)")
+    _ = flags.Parse(os.Args[2:])
+    n, err := buildVectors(ctx, d, *in, *model)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    fmt.Printf("stored %d vectors\n", n)
+  case "export":
+    flags := flag.NewFlagSet("export", flag.ExitOnError)
+    format := flags.String("format", "", "md, html, epub or pdf (default: from -o extension, else md)")
+    trans := flags.String("trans", "", "translation languages, comma-separated (\"none\" to omit)")
+    numbers := flags.Bool("numbers", true, "print verse numbers")
+    title := flags.String("title", "", "document title")
+    out := flags.String("o", "", "output file (default stdout)")
+    _ = flags.Parse(os.Args[2:])
+    sel := strings.Join(flags.Args(), " ")
+    if strings.TrimSpace(sel) == "" { fmt.Println("Usage: quran-cli export [-format md|html|epub|pdf] [-trans id,en] [-o file] <2 | 2:255-260 | juz:30>"); return }
+    opt := export.Options{Title: *title, Numbers: *numbers}
+    opt.SetTrans(*trans)
+    exportDoc(ctx, d, sel, *format, *out, opt)
+  case "dump":
+    flags := flag.NewFlagSet("dump", flag.ExitOnError)
+    format := flags.String("format", dump.FormatJSONL, "jsonl or csv")
+    tables := flags.String("tables", "", "comma-separated tables (default: all)")
+    out := flags.String("o", "dump", "output directory")
+    _ = flags.Parse(os.Args[2:])
+    ts, err := dump.Select(splitList(*tables))
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    if err := dump.Dump(ctx, d, *out, *format, ts); err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    fmt.Printf("dumped %d tables to %s\n", len(ts), *out)
+  case "load":
+    flags := flag.NewFlagSet("load", flag.ExitOnError)
+    format := flags.String("format", "", "jsonl or csv (default: whichever file exists)")
+    tables := flags.String("tables", "", "comma-separated tables (default: all found)")
+    replace := flags.Bool("replace", false, "delete existing rows of loaded tables first")
+    _ = flags.Parse(os.Args[2:])
+    if flags.NArg() != 1 { fmt.Println("Usage: quran-cli load [-format jsonl|csv] [-tables t1,t2] [-replace] <dir>"); return }
+    ts, err := dump.Select(splitList(*tables))
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    counts, err := dump.Load(ctx, d, flags.Arg(0), ts, dump.LoadOptions{Format: *format, Replace: *replace})
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    for _, t := range ts {
+      if n, ok := counts[t.Name]; ok { fmt.Printf("%-12s %d rows\n", t.Name, n) }
+    }
+  case "snapshot":
+    flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+    out := flags.String("o", "internal/db/snapshot/quran.db.zst", "output file")
+    _ = flags.Parse(os.Args[2:])
+    if st.Driver() != db.DriverSQLite { fmt.Fprintln(os.Stderr, "snapshot needs the sqlite backend"); os.Exit(2) }
+    if err := writeSnapshot(ctx, d, *out); err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    fmt.Printf("wrote %s\n", *out)
   case "help", "-h", "--help":
     usage()
   default:
@@ -50,47 +128,138 @@ func usage() {
   fmt.Println("  list                 List all surah")
   fmt.Println("  surah -n <N>         Show ayah for surah N")
   fmt.Println("  search <query>       Search Arabic/translation")
+  fmt.Println("  similar <S:A>        Show ayah on related themes")
+  fmt.Println("  freq [word]          Word frequencies, distribution and -kwic concordance")
+  fmt.Println("  vectors [-import f]  Build (or import) the vectors behind similar")
+  fmt.Println("  plan today           Reading plans: create, list, today, done (see plan -h)")
+  fmt.Println("  export <selection>   Export surah/juz/range to md, html, epub or pdf")
+  fmt.Println("  dump [-o dir]        Dump tables as canonical JSONL/CSV")
+  fmt.Println("  load <dir>           Load tables from a dump directory")
+  fmt.Println("  snapshot [-o file]   Write a compressed snapshot for -tags embeddb builds")
 }
 
-func listSurah(d *sqlx.DB) {
-  type row struct{ Number int `db:"number"`; NameAr string `db:"name_ar"`; Verses int `db:"verses_count"` }
-  var rows []row
-  _ = d.Select(&rows, `SELECT number,name_ar,verses_count FROM surah ORDER BY number`)
+func writeSnapshot(ctx context.Context, d *sqlx.DB, out string) error {
+  if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil { return err }
+  f, err := os.Create(out)
+  if err != nil { return err }
+  defer f.Close()
+  if err := db.WriteSnapshot(ctx, d, f); err != nil { return err }
+  return f.Close()
+}
+
+func listSurah(ctx context.Context, st db.Store) {
+  rows, _ := st.Surahs(ctx)
   for _, s := range rows {
     fmt.Printf("[%3d] %-32s (%d)\n", s.Number, s.NameAr, s.Verses)
   }
 }
 
-func getSurah(d *sqlx.DB, n int) {
+func getSurah(ctx context.Context, st db.Store, n int) {
   fmt.Printf("Surah %d\n", n)
-  type row struct{
-    Number int    `db:"number"`
-    Arabic string `db:"arabic"`
-    Tajweed string `db:"tajweed"`
-    Trans  string `db:"trans"`
-    Audio  string `db:"audio_url"`
-  }
-  var rows []row
-  _ = d.Select(&rows, `SELECT number,arabic,tajweed,trans,audio_url FROM ayah WHERE surah=? ORDER BY number`, n)
+  rows, _ := st.Ayat(ctx, n)
   for _, a := range rows {
-    fmt.Printf("%d:%d\n%s\n", n, a.Number, a.Arabic)
+    fmt.Printf("%d:%d\n%s\n", n, a.Ayah, a.Arabic)
     if strings.TrimSpace(a.Trans) != "" { fmt.Printf("  %s\n", a.Trans) }
   }
 }
 
-func search(ctx context.Context, d *sqlx.DB, q string) {
-  hits, _ := db.SearchAyah(ctx, d, q, 50)
-  for _, h := range hits {
-    fmt.Printf("%d:%d  %s\n", h.Surah, h.Number, stripHTML(h.Snip))
+func search(ctx context.Context, st db.Store, q string) {
+  res, err := st.Search(ctx, q, 50)
+  if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+  if res.Suggestion != "" { fmt.Printf("Did you mean: %s?\n", res.Suggestion) }
+  open, close := "", ""
+  if colorOutput() { open, close = "\x1b[1;33m", "\x1b[0m" }
+  for _, h := range res.Hits {
+    fmt.Printf("%d:%d  %s\n", h.Surah, h.Number, qsearch.Mark(h.Text, h.Matches, open, close, nil))
+  }
+}
+
+// colorOutput reports whether stdout is a terminal and NO_COLOR is unset.
+func colorOutput() bool {
+  if os.Getenv("NO_COLOR") != "" { return false }
+  fi, err := os.Stdout.Stat()
+  return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func showSimilar(ctx context.Context, d *sqlx.DB, ref quran.Ref, n int) {
+  ms, err := similar.NewFinder(d).Similar(ctx, ref, n)
+  if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+  for _, m := range ms {
+    fmt.Printf("%d:%d  (%.2f)  %s\n", m.Surah, m.Number, m.Score, m.Arabic)
+    if strings.TrimSpace(m.Trans) != "" { fmt.Printf("  %s\n", m.Trans) }
+  }
+}
+
+func showFreq(ctx context.Context, d *sqlx.DB, word, col string, sc concord.Scope, n int, kwic bool, width int) {
+  ix, err := concord.Load(ctx, d)
+  if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+  if col == "" { col = concord.Column(word) }
+  if word == "" {
+    words, err := ix.Top(col, sc, n)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    for _, w := range words { fmt.Printf("%7d %6d  %s\n", w.Count, w.Ayat, w.Word) }
+    return
+  }
+  f, err := ix.Freq(col, word, sc)
+  if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+  fmt.Printf("%s (%s): %d occurrences in %d ayah\n", f.Word.Word, f.Column, f.Count, f.Ayat)
+  if f.Count == 0 { return }
+  parts := func(ps []concord.Part) string {
+    out := make([]string, len(ps))
+    for i, p := range ps { out[i] = fmt.Sprintf("%d:%d", p.Number, p.Count) }
+    return strings.Join(out, " ")
+  }
+  fmt.Printf("by surah: %s\n", parts(f.BySurah))
+  fmt.Printf("by juz:   %s\n", parts(f.ByJuz))
+  if !kwic { return }
+  lines, _ := ix.KWIC(col, word, sc, width, n)
+  pad := 0
+  for _, l := range lines { pad = max(pad, utf8.RuneCountInString(l.Left)) }
+  open, close := "[", "]"
+  if colorOutput() { open, close = "\x1b[1;33m", "\x1b[0m" }
+  for _, l := range lines {
+    ref := fmt.Sprintf("%d:%d", l.Surah, l.Number)
+    fmt.Printf("%-8s %s%s %s%s%s %s\n", ref, strings.Repeat(" ", pad-utf8.RuneCountInString(l.Left)), l.Left, open, l.Match, close, l.Right)
   }
 }
 
-func stripHTML(s string) string {
-  s = strings.ReplaceAll(s, "<b>", "")
-  s = strings.ReplaceAll(s, "</b>", "")
-  s = strings.ReplaceAll(s, "<mark>", "")
-  s = strings.ReplaceAll(s, "</mark>", "")
-  return s
+func buildVectors(ctx context.Context, d *sqlx.DB, in, model string) (int, error) {
+  if in == "" { return similar.Build(ctx, d) }
+  f, err := os.Open(in)
+  if err != nil { return 0, err }
+  defer f.Close()
+  if model == "" { model = strings.TrimSuffix(filepath.Base(in), filepath.Ext(in)) }
+  return similar.Import(ctx, d, f, model)
+}
+
+func exportDoc(ctx context.Context, d *sqlx.DB, sel, format, out string, opt export.Options) {
+  rng, err := quran.ParseRange(sel)
+  if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+  if format == "" {
+    format = strings.TrimPrefix(filepath.Ext(out), ".")
+    if format == "" || format == "markdown" { format = export.FormatMarkdown }
+  }
+  if _, ok := export.ContentTypes[format]; !ok { fmt.Fprintf(os.Stderr, "unsupported export format %q\n", format); os.Exit(2) }
+  doc, err := export.Load(ctx, d, rng, opt)
+  if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+  if out == "" {
+    if err := export.Write(ctx, os.Stdout, format, doc); err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    return
+  }
+  f, err := os.Create(out)
+  if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+  err = export.Write(ctx, f, format, doc)
+  if cerr := f.Close(); err == nil { err = cerr }
+  // no half-written file is left behind
+  if err != nil { os.Remove(out); fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+}
+
+func splitList(s string) []string {
+  var out []string
+  for _, p := range strings.Split(s, ",") {
+    if p = strings.TrimSpace(p); p != "" { out = append(out, p) }
+  }
+  return out
 }
 
 func must(err error){ if err != nil { panic(err) } }
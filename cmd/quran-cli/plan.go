@@ -0,0 +1,128 @@
+package main
+
+import (
+  "context"
+  "errors"
+  "flag"
+  "fmt"
+  "os"
+  "strconv"
+  "time"
+
+  "github.com/jmoiron/sqlx"
+  "github.com/foozio/quran-go/internal/export"
+  "github.com/foozio/quran-go/internal/plan"
+)
+
+const planUsage = `Usage:
+  quran-cli plan create [-name N] [-unit juz|page|ayah] [-days 30 | -participants a,b,c] [-start YYYY-MM-DD]
+  quran-cli plan list
+  quran-cli plan show [-id N]
+  quran-cli plan today [-id N] [-participant name] [-date YYYY-MM-DD] [-text]
+  quran-cli plan done [-id N] [-undo] <part>
+  quran-cli plan delete -id N
+-id defaults to the newest plan.`
+
+func runPlan(ctx context.Context, d *sqlx.DB, args []string) {
+  if len(args) == 0 { fmt.Println(planUsage); return }
+  flags := flag.NewFlagSet("plan "+args[0], flag.ExitOnError)
+  id := flags.Int64("id", 0, "plan id (default: the newest)")
+  now := time.Now()
+  switch args[0] {
+  case "create":
+    name := flags.String("name", "", "plan name (default Khatm)")
+    unit := flags.String("unit", plan.UnitJuz, "split by juz, page or ayah")
+    days := flags.Int("days", 30, "number of days")
+    people := flags.String("participants", "", "comma-separated names; one portion each instead of one per day")
+    start := flags.String("start", "", "first day (default today)")
+    _ = flags.Parse(args[1:])
+    s := plan.Spec{Name: *name, Unit: *unit, Mode: plan.ModeDays, Parts: *days, Start: *start}
+    if *people != "" { s.Mode, s.Parts, s.Participants = plan.ModeParticipants, 0, splitList(*people) }
+    p, err := plan.Create(ctx, d, s, now)
+    if err != nil { planFail(err) }
+    fmt.Printf("created plan %d %q: %d portions\n", p.ID, p.Name, len(p.Portions))
+    printPortions(p.Portions)
+  case "list":
+    ps, err := plan.List(ctx, d)
+    if err != nil { planFail(err) }
+    for _, p := range ps {
+      fmt.Printf("%4d  %-24s %-12s by %-4s from %s  %d/%d done\n", p.ID, p.Name, p.Mode, p.Unit, p.Start, p.Done, p.Parts)
+    }
+  case "show":
+    _ = flags.Parse(args[1:])
+    p := loadPlan(ctx, d, *id)
+    fmt.Printf("%s (plan %d): %d/%d done\n", p.Name, p.ID, p.Done, p.Parts)
+    printPortions(p.Portions)
+  case "today":
+    who := flags.String("participant", "", "only this participant's portion")
+    date := flags.String("date", "", "day to show (default today)")
+    text := flags.Bool("text", false, "print the ayah of the portions")
+    _ = flags.Parse(args[1:])
+    if *date != "" {
+      var err error
+      if now, err = time.Parse(plan.DateLayout, *date); err != nil { planFail(fmt.Errorf("%w: date must be YYYY-MM-DD", plan.ErrInvalid)) }
+    }
+    p := loadPlan(ctx, d, *id)
+    today, overdue := p.Today(now, *who)
+    fmt.Printf("%s — %s\n", p.Name, now.Format(plan.DateLayout))
+    if len(today) == 0 { fmt.Println("nothing to read") }
+    printPortions(today)
+    if len(overdue) > 0 {
+      fmt.Printf("overdue:\n")
+      printPortions(overdue)
+    }
+    if !*text { return }
+    for _, o := range today {
+      if o.Done { continue }
+      doc, err := export.Load(ctx, d, o.Range(), export.Options{Numbers: true})
+      if err == nil { err = export.Write(ctx, os.Stdout, export.FormatMarkdown, doc) }
+      if err != nil { planFail(err) }
+    }
+  case "done":
+    undo := flags.Bool("undo", false, "mark the part unread again")
+    _ = flags.Parse(args[1:])
+    part, err := strconv.Atoi(flags.Arg(0))
+    if flags.NArg() != 1 || err != nil { fmt.Println(planUsage); os.Exit(2) }
+    p := loadPlan(ctx, d, *id)
+    if err := plan.SetDone(ctx, d, p.ID, part, !*undo, now); err != nil { planFail(err) }
+    p = loadPlan(ctx, d, p.ID)
+    fmt.Printf("%s: %d/%d done\n", p.Name, p.Done, p.Parts)
+  case "delete":
+    _ = flags.Parse(args[1:])
+    if *id == 0 { fmt.Println(planUsage); os.Exit(2) }
+    if err := plan.Delete(ctx, d, *id); err != nil { planFail(err) }
+    fmt.Printf("deleted plan %d\n", *id)
+  default:
+    fmt.Println(planUsage)
+    os.Exit(2)
+  }
+}
+
+// loadPlan returns plan id, or the newest plan when id is 0.
+func loadPlan(ctx context.Context, d *sqlx.DB, id int64) *plan.Plan {
+  if id == 0 {
+    ps, err := plan.List(ctx, d)
+    if err != nil { planFail(err) }
+    if len(ps) == 0 { planFail(errors.New("no plans yet; create one with quran-cli plan create")) }
+    id = ps[0].ID
+  }
+  p, err := plan.Get(ctx, d, id)
+  if err != nil { planFail(err) }
+  return p
+}
+
+func printPortions(ps []plan.Portion) {
+  for _, o := range ps {
+    mark := " "
+    if o.Done { mark = "✓" }
+    who := o.Day
+    if o.Participant != "" { who = o.Participant }
+    fmt.Printf("%s %3d  %-12s %-14s %4d ayah\n", mark, o.Part, who, o.Range(), o.Ayat)
+  }
+}
+
+func planFail(err error) {
+  fmt.Fprintln(os.Stderr, err)
+  if errors.Is(err, plan.ErrInvalid) || errors.Is(err, plan.ErrNotFound) { os.Exit(2) }
+  os.Exit(1)
+}
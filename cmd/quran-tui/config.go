@@ -0,0 +1,230 @@
+package main
+
+import (
+  "encoding/json"
+  "errors"
+  "fmt"
+  "os"
+  "path/filepath"
+  "sort"
+  "strings"
+
+  "github.com/charmbracelet/bubbles/key"
+  tea "github.com/charmbracelet/bubbletea"
+  "github.com/charmbracelet/lipgloss"
+)
+
+// config is the optional JSON file read from QURAN_TUI_CONFIG, else
+// $XDG_CONFIG_HOME/quran-go/tui.json:
+//
+//    {
+//      "theme": "light",
+//      "colors": {"match": "#d97706"},
+//      "keys": {"down": ["down", "n"], "quit": ["ctrl+q"]}
+//    }
+type config struct {
+  Theme  string              `json:"theme"`
+  Colors map[string]string   `json:"colors"`
+  Keys   map[string][]string `json:"keys"`
+}
+
+// configPath is where the config file is looked up.
+func configPath() string {
+  if p := os.Getenv("QURAN_TUI_CONFIG"); p != "" { return p }
+  dir, err := os.UserConfigDir()
+  if err != nil { return "" }
+  return filepath.Join(dir, "quran-go", "tui.json")
+}
+
+// loadConfig reads path; a missing file gives the defaults.
+func loadConfig(path string) (keyMap, theme, error) {
+  var cfg config
+  if path != "" {
+    b, err := os.ReadFile(path)
+    switch {
+    case errors.Is(err, os.ErrNotExist):
+    case err != nil:
+      return keyMap{}, theme{}, err
+    default:
+      if err := json.Unmarshal(b, &cfg); err != nil { return keyMap{}, theme{}, fmt.Errorf("%s: %w", path, err) }
+    }
+  }
+  keys := defaultKeys()
+  if err := keys.apply(cfg.Keys); err != nil { return keyMap{}, theme{}, fmt.Errorf("%s: %w", path, err) }
+  th, err := newTheme(cfg.Theme, cfg.Colors)
+  if err != nil { return keyMap{}, theme{}, fmt.Errorf("%s: %w", path, err) }
+  return keys, th, nil
+}
+
+// keyMap holds the bindings of the surah list, reader and hifz trainer;
+// text inputs (search, go to) keep fixed keys so every letter can be typed.
+type keyMap struct {
+  Up, Down, PageUp, PageDown, Top, Bottom key.Binding
+  Open, Back, Search, Goto, Pane, Hifz     key.Binding
+  Learn, Help, Quit                        key.Binding
+
+  // hifz trainer; Grade has one key per grade, 0 to 5
+  Less, More, Reveal, Grade key.Binding
+}
+
+func defaultKeys() keyMap {
+  b := func(desc string, keys ...string) key.Binding {
+    return key.NewBinding(key.WithKeys(keys...), key.WithHelp(keyNames(keys), desc))
+  }
+  return keyMap{
+    Up:       b("previous surah or ayah", "up", "k"),
+    Down:     b("next surah or ayah", "down", "j"),
+    PageUp:   b("five ayah back", "pgup"),
+    PageDown: b("five ayah on", "pgdown", " "),
+    Top:      b("first ayah", "home", "g"),
+    Bottom:   b("last ayah", "end", "G"),
+    Open:     b("open surah", "enter"),
+    Back:     b("back to the surah list", "b", "esc"),
+    Search:   b("search", "/"),
+    Goto:     b("go to a reference", ":"),
+    Pane:     b("translation / words pane", "tab"),
+    Hifz:     b("hifz review of the ayah due today", "h"),
+    Learn:    b("add the selected ayah to the hifz deck", "a"),
+    Help:     b("toggle this help", "?"),
+    Quit:     b("quit", "q", "ctrl+c"),
+    Less:     b("show more of the ayah", "left", "-"),
+    More:     b("hide more of the ayah", "right", "+"),
+    Reveal:   b("reveal the ayah", " ", "enter"),
+    Grade:    b("grade recall (0 forgot … 5 perfect)", "0", "1", "2", "3", "4", "5"),
+  }
+}
+
+// keyNames is how keys are listed in the help.
+func keyNames(keys []string) string {
+  names := make([]string, len(keys))
+  for i, k := range keys {
+    if k == " " { k = "space" }
+    names[i] = k
+  }
+  return strings.Join(names, "/")
+}
+
+// bindings names every binding as it appears in the config file, in the
+// order the help lists them.
+func (k *keyMap) bindings() []struct {
+  name string
+  b    *key.Binding
+} {
+  return []struct {
+    name string
+    b    *key.Binding
+  }{
+    {"up", &k.Up}, {"down", &k.Down}, {"page_up", &k.PageUp}, {"page_down", &k.PageDown},
+    {"top", &k.Top}, {"bottom", &k.Bottom}, {"open", &k.Open}, {"back", &k.Back},
+    {"search", &k.Search}, {"goto", &k.Goto}, {"pane", &k.Pane},
+    {"hifz", &k.Hifz}, {"learn", &k.Learn}, {"help", &k.Help}, {"quit", &k.Quit},
+    {"hifz_less", &k.Less}, {"hifz_more", &k.More}, {"hifz_reveal", &k.Reveal}, {"hifz_grade", &k.Grade},
+  }
+}
+
+// grade returns the grade msg gives in the trainer, or -1.
+func (k keyMap) grade(msg tea.KeyMsg) int {
+  for g, s := range k.Grade.Keys() {
+    if msg.String() == s { return g }
+  }
+  return -1
+}
+
+// apply replaces the keys of the named bindings.
+func (k *keyMap) apply(keys map[string][]string) error {
+  byName := map[string]*key.Binding{}
+  for _, x := range k.bindings() { byName[x.name] = x.b }
+  names := make([]string, 0, len(keys))
+  for name := range keys { names = append(names, name) }
+  sort.Strings(names)
+  for _, name := range names {
+    b, ok := byName[name]
+    if !ok { return fmt.Errorf("unknown key binding %q", name) }
+    if len(keys[name]) == 0 { return fmt.Errorf("key binding %q has no keys", name) }
+    if b == &k.Grade && len(keys[name]) != 6 { return fmt.Errorf("key binding %q needs six keys, for grades 0 to 5", name) }
+    b.SetKeys(keys[name]...)
+    b.SetHelp(keyNames(keys[name]), b.Help().Desc)
+  }
+  return nil
+}
+
+// helpView lists the bindings, then the fixed keys of the text inputs.
+func (k keyMap) helpView(th theme) string {
+  b := &strings.Builder{}
+  fmt.Fprintln(b, th.Title.Render("Keys"))
+  for _, x := range k.bindings() {
+    if strings.HasPrefix(x.name, "hifz_") { continue }
+    h := x.b.Help()
+    fmt.Fprintf(b, "%-14s %s\n", h.Key, th.Muted.Render(h.Desc))
+  }
+  fmt.Fprintln(b)
+  fmt.Fprintln(b, th.Title.Render("Search and go to"))
+  fmt.Fprintf(b, "%-14s %s\n", "up/ctrl+p", th.Muted.Render("previous result"))
+  fmt.Fprintf(b, "%-14s %s\n", "down/ctrl+n", th.Muted.Render("next result"))
+  fmt.Fprintf(b, "%-14s %s\n", "enter", th.Muted.Render("open"))
+  fmt.Fprintf(b, "%-14s %s\n", "esc", th.Muted.Render("cancel"))
+  fmt.Fprintln(b)
+  fmt.Fprintln(b, th.Title.Render("Hifz"))
+  for _, x := range k.bindings() {
+    if !strings.HasPrefix(x.name, "hifz_") { continue }
+    h := x.b.Help()
+    fmt.Fprintf(b, "%-14s %s\n", h.Key, th.Muted.Render(h.Desc))
+  }
+  fmt.Fprintln(b)
+  fmt.Fprint(b, th.Muted.Render("mouse wheel scrolls · any key closes"))
+  return b.String()
+}
+
+// theme holds the styles of every coloured element.
+type theme struct {
+  Title, Muted, Cursor, Match, Border, Error lipgloss.Style
+}
+
+var themes = map[string]func() theme{
+  "dark": func() theme {
+    return theme{
+      Title:  lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("111")),
+      Muted:  lipgloss.NewStyle().Foreground(lipgloss.Color("245")),
+      Cursor: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("230")).Background(lipgloss.Color("24")),
+      Match:  lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("220")),
+      Border: lipgloss.NewStyle().Foreground(lipgloss.Color("240")),
+      Error:  lipgloss.NewStyle().Foreground(lipgloss.Color("203")),
+    }
+  },
+  "light": func() theme {
+    return theme{
+      Title:  lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("25")),
+      Muted:  lipgloss.NewStyle().Foreground(lipgloss.Color("242")),
+      Cursor: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("16")).Background(lipgloss.Color("153")),
+      Match:  lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("130")),
+      Border: lipgloss.NewStyle().Foreground(lipgloss.Color("250")),
+      Error:  lipgloss.NewStyle().Foreground(lipgloss.Color("160")),
+    }
+  },
+  "high-contrast": func() theme {
+    return theme{
+      Title:  lipgloss.NewStyle().Bold(true).Underline(true).Foreground(lipgloss.Color("15")),
+      Muted:  lipgloss.NewStyle().Foreground(lipgloss.Color("15")),
+      Cursor: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("0")).Background(lipgloss.Color("11")),
+      Match:  lipgloss.NewStyle().Bold(true).Underline(true).Foreground(lipgloss.Color("0")).Background(lipgloss.Color("14")),
+      Border: lipgloss.NewStyle().Foreground(lipgloss.Color("15")),
+      Error:  lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("9")),
+    }
+  },
+}
+
+// newTheme returns the named theme (dark when empty) with colors overriding
+// the foreground of its elements, or the background of the cursor.
+func newTheme(name string, colors map[string]string) (theme, error) {
+  if name == "" { name = "dark" }
+  mk, ok := themes[name]
+  if !ok { return theme{}, fmt.Errorf("unknown theme %q (use dark, light or high-contrast)", name) }
+  th := mk()
+  styles := map[string]*lipgloss.Style{"title": &th.Title, "muted": &th.Muted, "cursor": &th.Cursor, "match": &th.Match, "border": &th.Border, "error": &th.Error}
+  for el, c := range colors {
+    st, ok := styles[el]
+    if !ok { return theme{}, fmt.Errorf("unknown theme color %q", el) }
+    if el == "cursor" { *st = st.Background(lipgloss.Color(c)) } else { *st = st.Foreground(lipgloss.Color(c)) }
+  }
+  return th, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+
+  tea "github.com/charmbracelet/bubbletea"
+  "github.com/charmbracelet/bubbles/key"
+  "github.com/charmbracelet/lipgloss"
+)
+
+func TestLoadConfig_Defaults(t *testing.T) {
+  keys, _, err := loadConfig(filepath.Join(t.TempDir(), "missing.json"))
+  if err != nil { t.Fatal(err) }
+  if !key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")}, keys.Down) { t.Error("j should move down") }
+  if !key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")}, keys.Help) { t.Error("? should open help") }
+}
+
+func TestLoadConfig_Overrides(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "tui.json")
+  cfg := `{"theme":"high-contrast","colors":{"match":"#d97706"},"keys":{"down":["n"],"quit":["ctrl+q"]}}`
+  if err := os.WriteFile(path, []byte(cfg), 0o644); err != nil { t.Fatal(err) }
+  keys, th, err := loadConfig(path)
+  if err != nil { t.Fatal(err) }
+  if key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")}, keys.Down) { t.Error("j should no longer move down") }
+  if !key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")}, keys.Down) { t.Error("n should move down") }
+  if key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}, keys.Quit) { t.Error("q should no longer quit") }
+  if got := th.Match.GetForeground(); got != lipgloss.Color("#d97706") { t.Errorf("match color = %v", got) }
+  help := keys.helpView(th)
+  for _, want := range []string{"ctrl+q", "next surah or ayah", "mouse wheel"} {
+    if !strings.Contains(help, want) { t.Errorf("help lacks %q:\n%s", want, help) }
+  }
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+  for _, cfg := range []string{
+    `{"keys":{"jump":["x"]}}`,
+    `{"keys":{"up":[]}}`,
+    `{"theme":"solarized"}`,
+    `{"colors":{"background":"0"}}`,
+    `{"theme":`,
+  } {
+    path := filepath.Join(t.TempDir(), "tui.json")
+    if err := os.WriteFile(path, []byte(cfg), 0o644); err != nil { t.Fatal(err) }
+    if _, _, err := loadConfig(path); err == nil { t.Errorf("%s: want error", cfg) }
+  }
+}
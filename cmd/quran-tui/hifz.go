@@ -0,0 +1,116 @@
+package main
+
+import (
+  "fmt"
+  "strings"
+  "time"
+
+  "github.com/charmbracelet/bubbles/key"
+  tea "github.com/charmbracelet/bubbletea"
+  "github.com/foozio/quran-go/internal/hifz"
+)
+
+// openDeck loads the hifz deck on first use.
+func (m *model) openDeck() bool {
+  if m.deck != nil { return true }
+  d, err := hifz.Open(hifz.Path())
+  if err != nil { m.lastErr = err.Error(); return false }
+  m.deck = d
+  return true
+}
+
+// openHifz starts a review of the ayah due today.
+func (m model) openHifz() (model, tea.Cmd) {
+  if !m.openDeck() { return m, nil }
+  m.prev = m.st
+  m.st = stateHifz
+  m.due = m.deck.Due(time.Now())
+  m.showCard()
+  return m, nil
+}
+
+// learn adds the selected ayah of the reader to the deck, due today.
+func (m model) learn() (model, tea.Cmd) {
+  if len(m.ayat) == 0 || !m.openDeck() { return m, nil }
+  n := m.ayat[m.ayCur].Number
+  if m.deck.Add(time.Now(), m.curSurah, n) == 0 {
+    m.status = fmt.Sprintf("%d:%d is already in the hifz deck", m.curSurah, n)
+    return m, nil
+  }
+  if err := m.deck.Save(); err != nil { m.lastErr = err.Error(); return m, nil }
+  m.status = fmt.Sprintf("Added %d:%d to the hifz deck", m.curSurah, n)
+  return m, nil
+}
+
+// showCard loads the text of the first due card, masked as its progress
+// suggests.
+func (m *model) showCard() {
+  m.hzShown = false
+  m.hzAyah = ayahRow{}
+  if len(m.due) == 0 { return }
+  c := m.due[0]
+  m.hzLevel = c.Suggested()
+  err := m.db.Get(&m.hzAyah, m.db.Rebind(`SELECT number, arabic, COALESCE(tajweed,'') AS tajweed, COALESCE(trans,'') AS trans FROM ayah WHERE surah=? AND number=?`), c.Surah, c.Ayah)
+  if err != nil { m.lastErr = fmt.Sprintf("%d:%d: %v", c.Surah, c.Ayah, err) }
+}
+
+func (m model) updateHifz(msg tea.KeyMsg) (model, tea.Cmd) {
+  switch {
+  case key.Matches(msg, m.keys.Back):
+    m.st = m.prev
+  case key.Matches(msg, m.keys.Less):
+    if m.hzLevel > hifz.LevelFull { m.hzLevel-- }
+  case key.Matches(msg, m.keys.More):
+    if m.hzLevel < hifz.LevelHidden { m.hzLevel++ }
+  case key.Matches(msg, m.keys.Reveal):
+    m.hzShown = !m.hzShown
+  case key.Matches(msg, m.keys.Grade):
+    if len(m.due) == 0 { break }
+    c := m.due[0]
+    grade := m.keys.grade(msg)
+    c, err := m.deck.Grade(c.Surah, c.Ayah, grade, time.Now())
+    if err == nil { err = m.deck.Save() }
+    if err != nil { m.lastErr = err.Error(); break }
+    m.lastErr = ""
+    m.due = m.due[1:]
+    // a failed ayah comes back at the end of today's session
+    if grade < hifz.GradePass { m.due = append(m.due, c) }
+    m.status = fmt.Sprintf("%d:%d next review %s", c.Surah, c.Ayah, c.Due)
+    m.showCard()
+  }
+  return m, nil
+}
+
+func (m model) viewHifz() string {
+  b := &strings.Builder{}
+  fmt.Fprint(b, m.header(fmt.Sprintf("Hifz — %d due", len(m.due))))
+  width := m.w
+  if width <= 0 { width = 80 }
+  if len(m.due) == 0 {
+    fmt.Fprintln(b, "Nothing to review today. In a surah, press", m.keys.Learn.Help().Key, "to add the selected ayah.")
+    fmt.Fprint(b, m.statusLine())
+    return b.String()
+  }
+  c := m.due[0]
+  info := fmt.Sprintf("reviewed %d× in a row · every %d days · ease %.2f", c.Reps, c.Interval, c.Ease)
+  if c.Grade < 0 { info = "new" }
+  fmt.Fprintln(b, m.theme.Cursor.Render(fmt.Sprintf("%d:%d", c.Surah, c.Ayah)), m.theme.Muted.Render(info))
+  fmt.Fprintln(b)
+  level := m.hzLevel
+  if m.hzShown { level = hifz.LevelFull }
+  for _, l := range alignRight(wrap(hifz.Mask(m.hzAyah.Arabic, level), width), width) { fmt.Fprintln(b, l) }
+  if m.hzShown && strings.TrimSpace(m.hzAyah.Trans) != "" {
+    fmt.Fprintln(b)
+    for _, l := range wrap(m.hzAyah.Trans, width) { fmt.Fprintln(b, l) }
+  }
+  fmt.Fprintln(b)
+  k := m.keys
+  hints := fmt.Sprintf("%s/%s show/hide more · %s reveal · %s grade · %s back", k.Less.Help().Key, k.More.Help().Key, k.Reveal.Help().Key, k.Grade.Help().Key, k.Back.Help().Key)
+  if m.hzShown {
+    g := k.Grade.Keys()
+    hints = fmt.Sprintf("grade: %s forgot · %s wrong · %s almost · %s hard · %s good · %s perfect", g[0], g[1], g[2], g[3], g[4], g[5])
+  }
+  fmt.Fprintln(b, m.theme.Muted.Render(hints))
+  fmt.Fprint(b, m.statusLine())
+  return b.String()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+  "path/filepath"
+  "testing"
+
+  tea "github.com/charmbracelet/bubbletea"
+)
+
+func runes(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+func TestHifz_LearnAddsOnlyTheSelectedAyah(t *testing.T) {
+  t.Setenv("QURAN_HIFZ_PATH", filepath.Join(t.TempDir(), "hifz.json"))
+  m := model{keys: defaultKeys(), st: stateSurah, curSurah: 2, ayat: []ayahRow{{Number: 1}, {Number: 2}, {Number: 3}}, ayCur: 1}
+
+  // the review key only opens the review
+  m, _ = m.updateSurah(runes("h"))
+  if m.st != stateHifz || len(m.deck.Cards()) != 0 { t.Fatalf("h added cards: %+v", m.deck.Cards()) }
+  m, _ = m.updateHifz(tea.KeyMsg{Type: tea.KeyEsc})
+  if m.st != stateSurah { t.Fatalf("esc: state %v", m.st) }
+
+  m, _ = m.updateSurah(runes("a"))
+  if cs := m.deck.Cards(); len(cs) != 1 || cs[0].Surah != 2 || cs[0].Ayah != 2 { t.Fatalf("learn: %+v", cs) }
+  if m, _ = m.updateSurah(runes("a")); m.status != "2:2 is already in the hifz deck" { t.Fatalf("again: %q", m.status) }
+}
+
+func TestHifz_GradeKeys(t *testing.T) {
+  keys := defaultKeys()
+  if err := keys.apply(map[string][]string{"hifz_grade": {"a", "s", "d", "f", "g", "h"}}); err != nil { t.Fatal(err) }
+  if g := keys.grade(runes("f")); g != 3 { t.Fatalf("f: grade %d", g) }
+  if g := keys.grade(runes("3")); g != -1 { t.Fatalf("3: grade %d", g) }
+  if err := keys.apply(map[string][]string{"hifz_grade": {"1", "2", "3"}}); err == nil { t.Fatal("three grade keys accepted") }
+}
@@ -6,9 +6,14 @@ import (
   "os"
   "strings"
 
+  "github.com/charmbracelet/bubbles/key"
+  "github.com/charmbracelet/bubbles/textinput"
   tea "github.com/charmbracelet/bubbletea"
+  "github.com/charmbracelet/lipgloss"
   "github.com/jmoiron/sqlx"
+  "github.com/foozio/quran-go/internal/concord"
   "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/internal/hifz"
 )
 
 type viewState int
@@ -16,6 +21,9 @@ type viewState int
 const (
   stateList viewState = iota
   stateSurah
+  stateSearch
+  stateGoto
+  stateHifz
 )
 
 type surahRow struct{
@@ -31,9 +39,12 @@ type ayahRow struct{
 }
 
 type model struct {
-  db   *sqlx.DB
-  st   viewState
-  w, h int
+  db    *sqlx.DB
+  st    viewState
+  w, h  int
+  keys  keyMap
+  theme theme
+  help  bool // help overlay shown
 
   // list view
   list     []surahRow
@@ -47,11 +58,32 @@ type model struct {
   // surah view
   curSurah int
   ayat     []ayahRow
-  ayOff    int
+  trans    map[int][]transRow // other translations by ayah
+  ayCur    int                // selected ayah, an index into ayat
+  ayOff    int                // first ayah on screen
+  pane     paneKind
+  words    *concord.Index // loaded when the words pane is first shown
+
+  // go-to prompt
+  gotoIn   textinput.Model
+
+  // search view
+  input    textinput.Model
+  hits     []db.Hit
+  hitCur   int
+  hitOff   int
+  prev     viewState
+
+  // hifz trainer
+  deck     *hifz.Deck // opened on first use
+  due      []hifz.Card
+  hzAyah   ayahRow
+  hzLevel  hifz.Level
+  hzShown  bool
 }
 
-func initialModel(d *sqlx.DB) model {
-  m := model{db: d, st: stateList}
+func initialModel(d *sqlx.DB, keys keyMap, th theme) model {
+  m := model{db: d, st: stateList, keys: keys, theme: th, input: newSearchInput(), gotoIn: newGotoInput()}
   m.loadSurah()
   return m
 }
@@ -70,12 +102,18 @@ func (m *model) loadSurah() {
 func (m *model) loadAyah(n int) {
   m.curSurah = n
   var rows []ayahRow
-  if err := m.db.Select(&rows, `SELECT number, arabic, tajweed, trans FROM ayah WHERE surah=? ORDER BY number`, n); err != nil {
+  err := m.db.Select(&rows, m.db.Rebind(`SELECT number, arabic, COALESCE(tajweed,'') AS tajweed, COALESCE(trans,'') AS trans FROM ayah WHERE surah=? ORDER BY number`), n)
+  var trans []transRow
+  if err == nil { err = m.db.Select(&trans, m.db.Rebind(`SELECT number, lang, text FROM translation WHERE surah=? ORDER BY number, lang`), n) }
+  if err != nil {
     m.lastErr = err.Error()
   } else {
     m.lastErr = ""
   }
   m.ayat = rows
+  m.trans = map[int][]transRow{}
+  for _, t := range trans { m.trans[t.Number] = append(m.trans[t.Number], t) }
+  m.ayCur, m.ayOff = 0, 0
   m.status = fmt.Sprintf("Surah %d — %d ayah", n, len(rows))
 }
 
@@ -84,59 +122,122 @@ func (m model) Init() tea.Cmd { return nil }
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
   switch msg := msg.(type) {
   case tea.KeyMsg:
-    switch msg.String() {
-    case "ctrl+c", "q":
+    if msg.String() == "ctrl+c" { return m, tea.Quit }
+    switch m.st {
+    case stateSearch:
+      return m.updateSearch(msg)
+    case stateGoto:
+      return m.updateGoto(msg)
+    }
+    if m.help { m.help = false; return m, nil }
+    switch {
+    case key.Matches(msg, m.keys.Quit):
       return m, tea.Quit
+    case key.Matches(msg, m.keys.Help):
+      m.help = true
+      return m, nil
     }
     switch m.st {
-    case stateList:
-      switch msg.String() {
-      case "up", "k":
-        if m.cursor > 0 { m.cursor-- }
-        if m.cursor < m.listOff { m.listOff = m.cursor }
-      case "down", "j":
-        if m.cursor < len(m.list)-1 { m.cursor++ }
-        if m.h > 0 {
-          maxVis := m.h - 4
-          if m.cursor >= m.listOff+maxVis { m.listOff = m.cursor - maxVis + 1 }
-        }
-      case "enter":
-        if len(m.list) > 0 {
-          n := m.list[m.cursor].Number
-          m.loadAyah(n)
-          m.st = stateSurah
-          m.ayOff = 0
-        }
-      }
     case stateSurah:
-      switch msg.String() {
-      case "b", "esc":
-        m.st = stateList
-      case "up", "k":
-        if m.ayOff > 0 { m.ayOff-- }
-      case "down", "j":
-        m.ayOff++
-      case "/":
-        // simple focus: no input mode; leave for future
+      return m.updateSurah(msg)
+    case stateHifz:
+      return m.updateHifz(msg)
+    }
+    switch {
+    case key.Matches(msg, m.keys.Up):
+      m.moveList(-1)
+    case key.Matches(msg, m.keys.Down):
+      m.moveList(1)
+    case key.Matches(msg, m.keys.Open):
+      if len(m.list) > 0 {
+        n := m.list[m.cursor].Number
+        m.loadAyah(n)
+        m.st = stateSurah
       }
+    case key.Matches(msg, m.keys.Search):
+      return m.openSearch()
+    case key.Matches(msg, m.keys.Goto):
+      return m.openGoto()
+    case key.Matches(msg, m.keys.Hifz):
+      return m.openHifz()
     }
+  case tea.MouseMsg:
+    if msg.Action != tea.MouseActionPress { break }
+    delta := 0
+    switch msg.Button {
+    case tea.MouseButtonWheelUp:
+      delta = -1
+    case tea.MouseButtonWheelDown:
+      delta = 1
+    }
+    if delta != 0 { m.scroll(delta) }
+  case searchMsg:
+    return m.gotResults(msg), nil
   case tea.WindowSizeMsg:
     m.w, m.h = msg.Width, msg.Height
+    m.follow()
   }
   return m, nil
 }
 
+// scroll moves the selection of the current view, for the mouse wheel.
+func (m *model) scroll(delta int) {
+  switch m.st {
+  case stateList:
+    m.moveList(delta)
+  case stateSurah:
+    m.ayCur = clamp(m.ayCur+delta, 0, max(0, len(m.ayat)-1))
+    m.follow()
+  case stateSearch:
+    m.moveHit(delta)
+  }
+}
+
+func (m *model) moveList(delta int) {
+  m.cursor = clamp(m.cursor+delta, 0, max(0, len(m.list)-1))
+  if m.cursor < m.listOff { m.listOff = m.cursor }
+  if m.h > 0 {
+    maxVis := m.h - 4
+    if m.cursor >= m.listOff+maxVis { m.listOff = m.cursor - maxVis + 1 }
+  }
+}
+
 func (m model) View() string {
-  if m.st == stateList { return m.viewList() }
-  return m.viewSurah()
+  var v string
+  switch m.st {
+  case stateList:
+    v = m.viewList()
+  case stateSearch:
+    v = m.viewSearch()
+  case stateGoto:
+    if m.prev == stateList { v = m.viewList() + m.gotoIn.View() } else { v = m.viewSurah() }
+  case stateHifz:
+    v = m.viewHifz()
+  default:
+    v = m.viewSurah()
+  }
+  if !m.help { return v }
+  box := lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(m.theme.Border.GetForeground()).Padding(0, 1).Render(m.keys.helpView(m.theme))
+  if m.w <= 0 || m.h <= 0 { return box }
+  return lipgloss.Place(m.w, m.h, lipgloss.Center, lipgloss.Center, box)
+}
+
+// header is a view's title line with the help key, and a rule.
+func (m model) header(title string) string {
+  help := m.theme.Muted.Render(fmt.Sprintf("(%s keys)", m.keys.Help.Help().Key))
+  return m.theme.Title.Render(title) + " " + help + "\n" + m.theme.Border.Render(strings.Repeat("-", max(10, m.w))) + "\n"
+}
+
+// statusLine shows the last error, else the status.
+func (m model) statusLine() string {
+  if m.lastErr != "" { return m.theme.Error.Render("Err: " + m.lastErr) }
+  return m.status
 }
 
 func (m model) viewList() string {
   b := &strings.Builder{}
-  fmt.Fprintln(b, "Quran TUI — Surah list (↑/↓, Enter, q)")
-  fmt.Fprintln(b, strings.Repeat("-", max(10, m.w)))
-  if m.lastErr != "" { fmt.Fprintln(b, "Err:", m.lastErr) }
-  if m.status != "" { fmt.Fprintln(b, m.status) }
+  fmt.Fprint(b, m.header("Quran TUI — Surah list"))
+  if l := m.statusLine(); l != "" { fmt.Fprintln(b, l) }
   if len(m.list) == 0 {
     fmt.Fprintln(b, "No surah found. Ensure quran.db exists or run 'make seed'.")
     return b.String()
@@ -149,43 +250,25 @@ func (m model) viewList() string {
   if vv := start + maxVis; vv < end { end = vv }
   for i := start; i < end; i++ {
     s := m.list[i]
-    cur := "  "
-    if i == m.cursor { cur = "> " }
-    fmt.Fprintf(b, "%s[%3d] %-32s (%d)\n", cur, s.Number, s.NameAr, s.Verses)
+    line := fmt.Sprintf("[%3d] %-32s (%d)", s.Number, s.NameAr, s.Verses)
+    if i == m.cursor { line = m.theme.Cursor.Render("> " + line) } else { line = "  " + line }
+    fmt.Fprintln(b, line)
   }
   return b.String()
 }
 
-func (m model) viewSurah() string {
-  b := &strings.Builder{}
-  fmt.Fprintf(b, "Surah %d — (b to back, q to quit)\n", m.curSurah)
-  fmt.Fprintln(b, strings.Repeat("-", max(10, m.w)))
-  // build lines once per view
-  lines := make([]string, 0, len(m.ayat)*2)
-  for _, a := range m.ayat {
-    lines = append(lines, fmt.Sprintf("%d:%d  %s", m.curSurah, a.Number, a.Arabic))
-    if strings.TrimSpace(a.Trans) != "" {
-      lines = append(lines, "    "+a.Trans)
-    }
-  }
-  start := clamp(m.ayOff, 0, max(0, len(lines)-1))
-  end := len(lines)
-  if m.h > 0 { if vv := start + (m.h - 4); vv < end { end = vv } }
-  for i := start; i < end; i++ { fmt.Fprintln(b, lines[i]) }
-  return b.String()
-}
-
 func clamp(v, lo, hi int) int { if v < lo { return lo }; if v > hi { return hi }; return v }
 func max(a, b int) int { if a > b { return a }; return b }
 
 func main() {
   ctx := context.Background()
-  path := os.Getenv("QURAN_DB_PATH")
-  if path == "" { path = "quran.db" }
-  d, err := db.Open(path); must(err)
-  must(db.Migrate(ctx, d))
+  st, err := db.OpenStore(ctx, db.ConfigFromEnv()); must(err)
+  d := st.DB()
+
+  keys, th, err := loadConfig(configPath())
+  if err != nil { fmt.Fprintln(os.Stderr, "config:", err); os.Exit(2) }
 
-  p := tea.NewProgram(initialModel(d))
+  p := tea.NewProgram(initialModel(d, keys, th), tea.WithMouseCellMotion())
   if _, err := p.Run(); err != nil { fmt.Println("error:", err) }
 }
 
@@ -0,0 +1,261 @@
+package main
+
+import (
+  "context"
+  "fmt"
+  "strings"
+
+  "github.com/charmbracelet/bubbles/key"
+  "github.com/charmbracelet/bubbles/textinput"
+  tea "github.com/charmbracelet/bubbletea"
+  "github.com/charmbracelet/lipgloss"
+  "github.com/foozio/quran-go/internal/concord"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// paneKind is what the detail pane shows for the selected ayah.
+type paneKind int
+
+const (
+  paneTrans paneKind = iota
+  paneWords
+)
+
+// splitMin is the narrowest terminal that gets the side-by-side layout;
+// below it translations are shown under each ayah.
+const splitMin = 80
+
+// paneStyle frames the detail pane; its border takes the theme colour.
+var paneStyle = lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderLeft(true).PaddingLeft(1)
+
+type transRow struct {
+  Number int    `db:"number"`
+  Lang   string `db:"lang"`
+  Text   string `db:"text"`
+}
+
+func newGotoInput() textinput.Model {
+  in := textinput.New()
+  in.Prompt = ": "
+  in.Placeholder = "2:255, 18, juz:30"
+  in.CharLimit = 20
+  return in
+}
+
+func (m model) openGoto() (model, tea.Cmd) {
+  m.prev = m.st
+  m.st = stateGoto
+  m.gotoIn.SetValue("")
+  return m, m.gotoIn.Focus()
+}
+
+func (m model) updateGoto(msg tea.KeyMsg) (model, tea.Cmd) {
+  switch msg.String() {
+  case "esc":
+    m.gotoIn.Blur()
+    m.st = m.prev
+    return m, nil
+  case "enter":
+    m.gotoIn.Blur()
+    m.st = m.prev
+    ref, err := parseGoto(m.gotoIn.Value())
+    if err != nil { m.lastErr = err.Error(); return m, nil }
+    m.jump(ref)
+    return m, nil
+  }
+  var cmd tea.Cmd
+  m.gotoIn, cmd = m.gotoIn.Update(msg)
+  return m, cmd
+}
+
+// parseGoto accepts anything quran.ParseRange does ("2", "2:255",
+// "juz:30", "2 255") and returns where the selection starts.
+func parseGoto(s string) (quran.Ref, error) {
+  s = strings.Join(strings.Fields(s), " ")
+  if !strings.Contains(s, ":") { s = strings.Replace(s, " ", ":", 1) }
+  rng, err := quran.ParseRange(s)
+  if err != nil { return quran.Ref{}, err }
+  return rng.From, nil
+}
+
+// jump opens the surah of ref with its ayah selected.
+func (m *model) jump(ref quran.Ref) {
+  if m.st != stateSurah || m.curSurah != ref.Surah { m.loadAyah(ref.Surah) }
+  m.st = stateSurah
+  m.ayCur = 0
+  for i, a := range m.ayat {
+    if a.Number == ref.Ayah { m.ayCur = i }
+  }
+  m.ayOff = m.ayCur
+  m.follow()
+}
+
+func (m model) updateSurah(msg tea.KeyMsg) (model, tea.Cmd) {
+  last := max(0, len(m.ayat)-1)
+  switch {
+  case key.Matches(msg, m.keys.Back):
+    m.st = stateList
+  case key.Matches(msg, m.keys.Up):
+    m.ayCur = max(0, m.ayCur-1)
+  case key.Matches(msg, m.keys.Down):
+    m.ayCur = min(last, m.ayCur+1)
+  case key.Matches(msg, m.keys.PageUp):
+    m.ayCur = max(0, m.ayCur-5)
+  case key.Matches(msg, m.keys.PageDown):
+    m.ayCur = min(last, m.ayCur+5)
+  case key.Matches(msg, m.keys.Top):
+    m.ayCur = 0
+  case key.Matches(msg, m.keys.Bottom):
+    m.ayCur = last
+  case key.Matches(msg, m.keys.Pane):
+    m.pane = (m.pane + 1) % 2
+    if m.pane == paneWords && m.words == nil {
+      ix, err := concord.Load(context.Background(), m.db)
+      if err != nil { m.lastErr = err.Error() } else { m.words = ix }
+    }
+  case key.Matches(msg, m.keys.Search):
+    return m.openSearch()
+  case key.Matches(msg, m.keys.Goto):
+    return m.openGoto()
+  case key.Matches(msg, m.keys.Hifz):
+    return m.openHifz()
+  case key.Matches(msg, m.keys.Learn):
+    return m.learn()
+  }
+  m.follow()
+  return m, nil
+}
+
+// layout returns the width of the ayah column and of the detail pane (0 when
+// the terminal is too narrow to split).
+func (m model) layout() (left, right int) {
+  w := m.w
+  if w <= 0 { w = splitMin }
+  if w < splitMin { return w, 0 }
+  left = w * 3 / 5
+  return left, w - left - paneStyle.GetHorizontalFrameSize()
+}
+
+// bodyHeight is the number of lines between the header and the status line.
+func (m model) bodyHeight() int {
+  if m.h <= 0 { return 1 << 20 }
+  return max(1, m.h-3)
+}
+
+// follow scrolls so the selected ayah is on screen.
+func (m *model) follow() {
+  if m.ayCur < m.ayOff { m.ayOff = m.ayCur; return }
+  left, right := m.layout()
+  for m.ayOff < m.ayCur {
+    n := 0
+    for i := m.ayOff; i <= m.ayCur; i++ { n += len(m.ayahBlock(i, left, right == 0)) }
+    if n <= m.bodyHeight() { return }
+    m.ayOff++
+  }
+}
+
+// ayahBlock renders ayah i: its reference, then the Arabic wrapped and
+// aligned right, then (inline) the translation.
+func (m model) ayahBlock(i, width int, inline bool) []string {
+  a := m.ayat[i]
+  ref := fmt.Sprintf("%d:%d", m.curSurah, a.Number)
+  if i == m.ayCur { ref = m.theme.Cursor.Render("▶ " + ref) } else { ref = m.theme.Muted.Render("  " + ref) }
+  lines := []string{ref}
+  lines = append(lines, alignRight(wrap(a.Arabic, width), width)...)
+  if inline && strings.TrimSpace(a.Trans) != "" {
+    for _, l := range wrap(a.Trans, width-4) { lines = append(lines, "    "+l) }
+  }
+  return lines
+}
+
+func (m model) viewSurah() string {
+  b := &strings.Builder{}
+  fmt.Fprint(b, m.header(fmt.Sprintf("Surah %d", m.curSurah)))
+  left, right := m.layout()
+  height := m.bodyHeight()
+  var lines []string
+  for i := m.ayOff; i < len(m.ayat) && len(lines) < height; i++ {
+    lines = append(lines, m.ayahBlock(i, left, right == 0)...)
+  }
+  if len(lines) > height { lines = lines[:height] }
+  body := strings.Join(lines, "\n")
+  if right > 0 && len(m.ayat) > 0 {
+    col := lipgloss.NewStyle().Width(left).Render(body)
+    pane := paneStyle.BorderForeground(m.theme.Border.GetForeground()).Width(right + paneStyle.GetHorizontalPadding()).Height(len(lines)).MaxHeight(len(lines)).Render(strings.Join(m.detail(right, max(1, len(lines))), "\n"))
+    body = lipgloss.JoinHorizontal(lipgloss.Top, col, pane)
+  }
+  fmt.Fprintln(b, body)
+  if m.st == stateGoto { fmt.Fprint(b, m.gotoIn.View()) } else { fmt.Fprint(b, m.statusLine()) }
+  return b.String()
+}
+
+// detail renders the pane for the selected ayah: its translations, or its
+// words with how often each occurs in the Quran.
+func (m model) detail(width, height int) []string {
+  if m.ayCur >= len(m.ayat) { return nil }
+  a := m.ayat[m.ayCur]
+  ref := fmt.Sprintf("%d:%d", m.curSurah, a.Number)
+  var lines []string
+  switch m.pane {
+  case paneTrans:
+    lines = append(lines, m.theme.Title.Render(ref+" · Translation")+m.theme.Muted.Render("  "+m.keys.Pane.Help().Key+": words"))
+    if strings.TrimSpace(a.Trans) != "" { lines = append(lines, wrap(a.Trans, width)...) }
+    for _, t := range m.trans[a.Number] {
+      lines = append(lines, "", m.theme.Muted.Render("["+t.Lang+"]"))
+      lines = append(lines, wrap(t.Text, width)...)
+    }
+  case paneWords:
+    lines = append(lines, m.theme.Title.Render(ref+" · Words")+m.theme.Muted.Render("  "+m.keys.Pane.Help().Key+": translation"))
+    n := 0
+    for _, w := range strings.Fields(a.Arabic) {
+      form := concord.Form(w)
+      if form == "" { continue }
+      n++
+      count := ""
+      if m.words != nil {
+        if f, err := m.words.Freq(concord.ColArabic, w, concord.Scope{}); err == nil { count = fmt.Sprintf("×%d", f.Count) }
+      }
+      lines = append(lines, fmt.Sprintf("%3d  %s  %s %s", n, w, m.theme.Muted.Render(form), count))
+    }
+  }
+  if len(lines) > height { lines = lines[:height] }
+  return lines
+}
+
+// wrap breaks s into lines of at most width cells at spaces, splitting
+// words only when a single word is wider than a line. Widths are measured
+// in terminal cells, so Arabic vowel marks take no room.
+func wrap(s string, width int) []string {
+  width = max(width, 8)
+  var lines []string
+  line := ""
+  for _, w := range strings.Fields(s) {
+    for lipgloss.Width(w) > width {
+      if line != "" { lines = append(lines, line); line = "" }
+      rs := []rune(w)
+      cut := len(rs)
+      for cut > 1 && lipgloss.Width(string(rs[:cut])) > width { cut-- }
+      lines = append(lines, string(rs[:cut]))
+      w = string(rs[cut:])
+    }
+    switch {
+    case line == "":
+      line = w
+    case lipgloss.Width(line)+1+lipgloss.Width(w) <= width:
+      line += " " + w
+    default:
+      lines = append(lines, line)
+      line = w
+    }
+  }
+  if line != "" { lines = append(lines, line) }
+  return lines
+}
+
+// alignRight pads right-to-left lines so they end at the right edge.
+func alignRight(lines []string, width int) []string {
+  for i, l := range lines {
+    if pad := width - lipgloss.Width(l); pad > 0 { lines[i] = strings.Repeat(" ", pad) + l }
+  }
+  return lines
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+  "context"
+  "fmt"
+  "strings"
+
+  "github.com/charmbracelet/bubbles/textinput"
+  tea "github.com/charmbracelet/bubbletea"
+  "github.com/charmbracelet/lipgloss"
+  "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/internal/search"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// searchMsg carries the results of one query; results for a query the
+// user has since edited are dropped.
+type searchMsg struct {
+  q    string
+  hits []db.Hit
+  err  error
+}
+
+func newSearchInput() textinput.Model {
+  in := textinput.New()
+  in.Prompt = "/ "
+  in.Placeholder = "Search Arabic or translation"
+  in.CharLimit = 100
+  return in
+}
+
+// openSearch switches to the search view, remembering where to go back to.
+func (m model) openSearch() (model, tea.Cmd) {
+  m.prev = m.st
+  m.st = stateSearch
+  return m, m.input.Focus()
+}
+
+func (m model) search(q string) tea.Cmd {
+  d := m.db
+  return func() tea.Msg {
+    hits, err := db.SearchAyah(context.Background(), d, q, 50)
+    return searchMsg{q: q, hits: hits, err: err}
+  }
+}
+
+func (m model) updateSearch(msg tea.KeyMsg) (model, tea.Cmd) {
+  switch msg.String() {
+  case "esc":
+    m.input.Blur()
+    m.st = m.prev
+    return m, nil
+  case "up", "ctrl+p":
+    m.moveHit(-1)
+    return m, nil
+  case "down", "ctrl+n":
+    m.moveHit(1)
+    return m, nil
+  case "enter":
+    if len(m.hits) == 0 { return m, nil }
+    h := m.hits[m.hitCur]
+    m.input.Blur()
+    m.st = m.prev
+    m.jump(quran.Ref{Surah: h.Surah, Ayah: h.Number})
+    return m, nil
+  }
+  before := m.input.Value()
+  var cmd tea.Cmd
+  m.input, cmd = m.input.Update(msg)
+  q := strings.TrimSpace(m.input.Value())
+  if m.input.Value() == before { return m, cmd }
+  if q == "" {
+    m.hits, m.hitCur, m.hitOff, m.status, m.lastErr = nil, 0, 0, "", ""
+    return m, cmd
+  }
+  return m, tea.Batch(cmd, m.search(q))
+}
+
+func (m *model) moveHit(delta int) {
+  m.hitCur = clamp(m.hitCur+delta, 0, max(0, len(m.hits)-1))
+  if m.hitCur < m.hitOff { m.hitOff = m.hitCur }
+  if vis := m.searchRows(); m.hitCur >= m.hitOff+vis { m.hitOff = m.hitCur - vis + 1 }
+}
+
+func (m model) gotResults(msg searchMsg) model {
+  if msg.q != strings.TrimSpace(m.input.Value()) { return m }
+  m.hitCur, m.hitOff = 0, 0
+  if msg.err != nil {
+    m.hits, m.lastErr, m.status = nil, msg.err.Error(), ""
+    return m
+  }
+  m.hits, m.lastErr = msg.hits, ""
+  m.status = fmt.Sprintf("%d results", len(msg.hits))
+  return m
+}
+
+// searchRows is how many results fit under the header, input and status.
+func (m model) searchRows() int {
+  if m.h <= 6 { return max(1, len(m.hits)) }
+  return m.h - 6
+}
+
+func (m model) viewSearch() string {
+  b := &strings.Builder{}
+  fmt.Fprintln(b, m.theme.Title.Render("Search"), m.theme.Muted.Render("(↑/↓ select, Enter open, Esc back)"))
+  fmt.Fprintln(b, m.theme.Border.Render(strings.Repeat("-", max(10, m.w))))
+  fmt.Fprintln(b, m.input.View())
+  fmt.Fprintln(b, m.statusLine())
+  width := m.w
+  if width <= 0 { width = 80 }
+  end := min(len(m.hits), m.hitOff+m.searchRows())
+  for i := m.hitOff; i < end; i++ {
+    h := m.hits[i]
+    ref := fmt.Sprintf("%d:%d", h.Surah, h.Number)
+    if i == m.hitCur { ref = m.theme.Cursor.Render("> " + ref) } else { ref = "  " + ref }
+    text, spans := excerpt(h, width-lipgloss.Width(ref)-2)
+    line := &strings.Builder{}
+    for _, seg := range search.Segments(text, spans) {
+      if seg.Match { line.WriteString(m.theme.Match.Render(seg.Text)) } else { line.WriteString(seg.Text) }
+    }
+    fmt.Fprintf(b, "%s  %s\n", ref, line)
+  }
+  return b.String()
+}
+
+// excerpt cuts a hit's text to width runes around its first match, shifting
+// the match ranges to the cut text.
+func excerpt(h db.Hit, width int) (string, []search.Span) {
+  // one rune for one, so the match offsets still line up
+  rs := []rune(strings.NewReplacer("\n", " ", "\r", " ", "\t", " ").Replace(h.Text))
+  if width < 10 { width = 10 }
+  if len(rs) <= width { return string(rs), h.Matches }
+  start := 0
+  if len(h.Matches) > 0 { start = max(0, h.Matches[0].Start-width/3) }
+  end := min(len(rs), start+width-2)
+  start = max(0, min(start, end-(width-2)))
+  text := string(rs[start:end])
+  shift := 0
+  if start > 0 { text = "…" + text; shift = 1 }
+  if end < len(rs) { text += "…" }
+  var spans []search.Span
+  for _, s := range h.Matches {
+    if s.End <= start || s.Start >= end { continue }
+    spans = append(spans, search.Span{Start: max(s.Start, start) - start + shift, End: min(s.End, end) - start + shift})
+  }
+  return text, spans
+}
@@ -2,46 +2,112 @@ package main
 
 import (
   "context"
+  "encoding/json"
+  "flag"
   "fmt"
   "log"
   "os"
+  "strings"
 
-  "github.com/jmoiron/sqlx"
-  _ "modernc.org/sqlite"
+  qdb "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/internal/verify"
 )
 
 func main(){
   ctx := context.Background()
-  path := os.Getenv("QURAN_DB_PATH")
-  if path == "" { path = "quran.db" }
-  db, err := sqlx.Open("sqlite", path)
+  manifest := flag.String("manifest", "", "reference manifest: file path, bundled name ("+strings.Join(verify.Bundled(), ", ")+") or none; default "+verify.DefaultManifest+" unless -ref is given")
+  refPath := flag.String("ref", "", "reference text in Tanzil sura|aya|text format (enables character diffs)")
+  norm := flag.String("norm", verify.NormNFC, "normalization when building from -ref: exact, nfc or simple")
+  corpus := flag.String("corpus", "reference", "corpus name recorded by -write-manifest")
+  writeManifest := flag.String("write-manifest", "", "write a manifest built from -ref (or the database) to this path and exit")
+  asJSON := flag.Bool("json", false, "print the full report as JSON")
+  flag.Parse()
+
+  cfg := qdb.ConfigFromEnv()
+  st, err := qdb.OpenStore(ctx, cfg)
   if err != nil { log.Fatalf("open db: %v", err) }
-  defer db.Close()
+  defer st.Close()
+  db := st.DB()
 
-  var surahTotal, ayahTotal, tail int
-  must(db.GetContext(ctx, &surahTotal, `SELECT COUNT(*) FROM surah`))
-  must(db.GetContext(ctx, &ayahTotal, `SELECT COUNT(*) FROM ayah`))
-  must(db.GetContext(ctx, &tail, `SELECT COUNT(*) FROM ayah WHERE surah BETWEEN 94 AND 114`))
+  var ref verify.Text
+  if *refPath != "" {
+    f, err := os.Open(*refPath)
+    if err != nil { log.Fatalf("open reference: %v", err) }
+    ref, err = verify.ReadTanzil(f)
+    f.Close()
+    if err != nil { log.Fatalf("read reference: %v", err) }
+  }
 
-  type row struct{
-    Surah int `db:"Surah"`
-    Verses int `db:"Verses"`
-    Ayah int `db:"Ayah"`
+  if *writeManifest != "" {
+    src, source := ref, *refPath
+    if src == nil {
+      src, err = verify.LoadText(ctx, db)
+      must(err)
+      source = cfg.DSN
+      if cfg.Driver != qdb.DriverSQLite { source = cfg.Driver } // keep credentials out of the manifest
+    }
+    m, err := verify.BuildManifest(*corpus, source, *norm, src)
+    if err != nil { log.Fatalf("build manifest: %v", err) }
+    f, err := os.Create(*writeManifest)
+    must(err)
+    must(verify.WriteManifest(f, m))
+    must(f.Close())
+    fmt.Printf("wrote %s (%s, %s, %s)\n", *writeManifest, m.Corpus, m.Normalization, m.Hash)
+    return
   }
-  rows := []row{}
-  must(db.SelectContext(ctx, &rows, `
-    SELECT s.number AS Surah, s.verses_count AS Verses,
-      (SELECT COUNT(*) FROM ayah a WHERE a.surah = s.number) AS Ayah
-    FROM surah s ORDER BY s.number`))
 
-  bad := 0
-  for _, r := range rows { if r.Verses != r.Ayah { fmt.Printf("mismatch: surah %d verses=%d ayah=%d\n", r.Surah, r.Verses, r.Ayah); bad++ } }
+  counts, err := verify.Counts(ctx, db)
+  must(err)
+  report := verify.Report{OK: counts.OK, Counts: counts}
+
+  name := *manifest
+  if name == "" && ref == nil {
+    if name = verify.Default(); name == "" { log.Print("no bundled manifest; checking row counts only (see internal/verify/manifests/README.md)") }
+  }
+  var m *verify.Manifest
+  switch {
+  case name == "none":
+  case name != "":
+    m, err = verify.LoadManifest(name)
+  case ref != nil:
+    m, err = verify.BuildManifest(*corpus, *refPath, *norm, ref)
+  }
+  if err != nil { log.Fatalf("manifest: %v", err) }
+  if m != nil {
+    got, err := verify.LoadText(ctx, db)
+    must(err)
+    report.Text, err = verify.CheckText(m, got, ref)
+    must(err)
+    report.OK = report.OK && report.Text.OK
+  }
 
-  if surahTotal != 114 || bad > 0 || tail == 0 {
-    log.Fatalf("verify failed: surah=%d mismatches=%d tail_ayah=%d", surahTotal, bad, tail)
+  if *asJSON {
+    enc := json.NewEncoder(os.Stdout)
+    enc.SetIndent("", "  ")
+    enc.SetEscapeHTML(false)
+    must(enc.Encode(report))
+    if !report.OK { os.Exit(1) }
+    return
+  }
+
+  for _, r := range counts.Mismatches { fmt.Printf("mismatch: surah %d verses=%d ayah=%d\n", r.Surah, r.Verses, r.Ayah) }
+  if t := report.Text; t != nil {
+    for _, is := range t.Issues {
+      fmt.Printf("text %s: %s\n", is.Kind, is.Ref)
+      for _, d := range is.Diffs {
+        fmt.Printf("  at %d: expected %q %v, got %q %v\n", d.Offset, d.Expected, d.ExpectedCodes, d.Actual, d.ActualCodes)
+      }
+    }
+  }
+  if !counts.OK {
+    log.Fatalf("verify failed: surah=%d mismatches=%d tail_ayah=%d", counts.SurahTotal, len(counts.Mismatches), counts.Tail)
+  }
+  if t := report.Text; t != nil {
+    if !t.OK { log.Fatalf("verify failed: %d ayah differ from %s (%s)", len(t.Issues), t.Corpus, t.Normalization) }
+    fmt.Printf("verify: OK (114 surah; counts consistent; %d ayah match %s)\n", t.AyahChecked, t.Corpus)
+    return
   }
   fmt.Println("verify: OK (114 surah; counts consistent)")
 }
 
 func must(err error){ if err != nil { log.Fatal(err) } }
-
@@ -0,0 +1,231 @@
+// Package api implements the JSON HTTP API shared by quran-api and quran-all.
+package api
+
+import (
+  "bytes"
+  "database/sql"
+  "errors"
+  "fmt"
+  "net/http"
+  "os"
+  "strconv"
+  "strings"
+
+  "github.com/gin-gonic/gin"
+  "github.com/jmoiron/sqlx"
+  "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/internal/bundle"
+  "github.com/foozio/quran-go/internal/concord"
+  "github.com/foozio/quran-go/internal/data"
+  "github.com/foozio/quran-go/internal/export"
+  "github.com/foozio/quran-go/internal/httpx"
+  "github.com/foozio/quran-go/internal/search"
+  "github.com/foozio/quran-go/internal/similar"
+  "github.com/foozio/quran-go/internal/verify"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// Limits for EPUB and PDF exports: the largest range (juz 30 has 564 ayah)
+// and how many render at once.
+const (
+  maxExportAyah = 600
+  exportSlots   = 2
+)
+
+// New returns the JSON API served by quran-api and quran-all, with
+// compression, CORS and per-IP rate limiting. s serves reads; w is a writable
+// handle for reading plans, and nil disables them.
+func New(s db.Store, w *sqlx.DB) http.Handler {
+  r := gin.New()
+  r.Use(gin.Recovery())
+  r.GET("/healthz", func(c *gin.Context) { c.JSON(200, gin.H{"ok":true}) })
+  r.GET("/surah", func(c *gin.Context) {
+    rows, err := s.Surahs(c.Request.Context())
+    if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+    c.JSON(200, rows)
+  })
+  r.GET("/surah/:n", func(c *gin.Context) {
+    nStr := c.Param("n")
+    n, err := strconv.Atoi(nStr)
+    if err != nil || n < 1 || n > 114 {
+      c.JSON(http.StatusBadRequest, gin.H{"error": "invalid surah number"}); return
+    }
+    format := httpx.Negotiate(c.Request)
+    if format == "" {
+      c.JSON(http.StatusNotAcceptable, gin.H{"error": "unsupported format"}); return
+    }
+    out, err := s.Ayat(c.Request.Context(), n)
+    if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+    renderSurah(c, format, n, out)
+  })
+  r.GET("/search", func(c *gin.Context) {
+    q := strings.TrimSpace(c.Query("q"))
+    if len(q) > 100 { c.JSON(http.StatusBadRequest, gin.H{"error": "query too long"}); return }
+    res, err := s.Search(c.Request.Context(), q, 50)
+    if errors.Is(err, search.ErrSyntax) { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+    if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+    out := gin.H{"q": q, "hits": res.Hits}
+    if res.Suggestion != "" { out["suggestion"] = res.Suggestion }
+    c.JSON(200, out)
+  })
+
+  var sim *similar.Finder
+  if s != nil { sim = similar.NewFinder(s.DB()) }
+  r.GET("/ayah/:surah/:n/similar", func(c *gin.Context) {
+    ref, err := quran.ParseRef(c.Param("surah") + ":" + c.Param("n"))
+    if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+    limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+    if limit < 1 || limit > 50 { limit = 10 }
+    ms, err := sim.Similar(c.Request.Context(), ref, limit)
+    switch {
+    case errors.Is(err, similar.ErrNoVectors):
+      c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()}); return
+    case errors.Is(err, similar.ErrUnknownAyah):
+      c.JSON(http.StatusNotFound, gin.H{"error": err.Error()}); return
+    case err != nil:
+      c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return
+    }
+    c.JSON(200, gin.H{"ayah": ref, "similar": ms})
+  })
+
+  // EPUB and PDF (a headless browser) are costly to render, so they are
+  // capped in size and run at most exportSlots at a time; PDF is off unless
+  // QURAN_EXPORT_PDF=1.
+  pdf := os.Getenv("QURAN_EXPORT_PDF") == "1"
+  heavy := make(chan struct{}, exportSlots)
+  r.GET("/export", func(c *gin.Context) {
+    rng, err := quran.ParseRange(c.Query("ref"))
+    if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+    format := c.DefaultQuery("format", export.FormatMarkdown)
+    if _, ok := export.ContentTypes[format]; !ok {
+      c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format"}); return
+    }
+    if format == export.FormatPDF && !pdf {
+      c.JSON(http.StatusNotImplemented, gin.H{"error": "pdf export is disabled on this server (QURAN_EXPORT_PDF=1 enables it)"}); return
+    }
+    if format == export.FormatEPUB || format == export.FormatPDF {
+      if rng.Len() > maxExportAyah {
+        c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("%s export is limited to %d ayah", format, maxExportAyah)}); return
+      }
+      select {
+      case heavy <- struct{}{}:
+        defer func() { <-heavy }()
+      default:
+        c.Header("Retry-After", "5")
+        c.JSON(http.StatusServiceUnavailable, gin.H{"error": "too many exports in progress"}); return
+      }
+    }
+    opt := export.Options{Title: c.Query("title"), Numbers: c.DefaultQuery("numbers", "1") != "0"}
+    opt.SetTrans(c.Query("trans"))
+    doc, err := export.Load(c.Request.Context(), s.DB(), rng, opt)
+    if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+    buf := &bytes.Buffer{}
+    if err := export.Write(c.Request.Context(), buf, format, doc); err != nil {
+      status := http.StatusInternalServerError
+      if errors.Is(err, export.ErrNoPDFEngine) { status = http.StatusNotImplemented }
+      c.JSON(status, gin.H{"error": err.Error()}); return
+    }
+    if format != export.FormatHTML {
+      c.Header("Content-Disposition", `attachment; filename="`+export.Filename(doc, format)+`"`)
+    }
+    c.Data(http.StatusOK, export.ContentTypes[format], buf.Bytes())
+  })
+
+  // The whole text with one translation, for apps that read offline. The
+  // ETag is the bundle version, so clients re-check with If-None-Match;
+  // built bundles are kept until the data changes, so a revalidation
+  // costs no rebuild.
+  var bundles *bundle.Cache
+  if s != nil { bundles = bundle.NewCache(s.DB()) }
+  r.GET("/bundle", func(c *gin.Context) {
+    b, err := bundles.Get(c.Request.Context(), strings.TrimSpace(c.Query("lang")))
+    if errors.Is(err, bundle.ErrUnknownLang) { c.JSON(http.StatusNotFound, gin.H{"error": err.Error()}); return }
+    if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+    etag := `"` + b.Version + `"`
+    c.Header("ETag", etag)
+    c.Header("Cache-Control", "no-cache")
+    if c.GetHeader("If-None-Match") == etag { c.Status(http.StatusNotModified); return }
+    c.JSON(200, b)
+  })
+  r.GET("/bundle/langs", func(c *gin.Context) {
+    primary, langs, err := bundle.Langs(c.Request.Context(), s.DB())
+    if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+    c.JSON(200, gin.H{"primary": primary, "langs": langs})
+  })
+
+  // Stats endpoint: verifies content consistency at runtime
+  r.GET("/stats", func(c *gin.Context) {
+    rep, err := verify.Counts(c.Request.Context(), s.DB())
+    if err != nil { c.JSON(500, gin.H{"error": err.Error()}); return }
+    c.JSON(200, rep)
+  })
+
+  // Word frequencies and concordance (KWIC) over the Arabic text and translation
+  var conc *concord.Cache
+  if s != nil { conc = concord.NewCache(s.DB()) }
+  r.GET("/stats/words", func(c *gin.Context) {
+    q := strings.TrimSpace(c.Query("q"))
+    if len(q) > 100 { c.JSON(http.StatusBadRequest, gin.H{"error": "query too long"}); return }
+    var sc concord.Scope
+    var err error
+    if v := c.Query("surah"); v != "" {
+      if sc.Surah, err = strconv.Atoi(v); err != nil || sc.Surah < 1 || sc.Surah > 114 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid surah number"}); return
+      }
+    }
+    if v := c.Query("juz"); v != "" {
+      if sc.Juz, err = strconv.Atoi(v); err != nil || sc.Juz < 1 || sc.Juz > 30 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid juz number"}); return
+      }
+    }
+    col := c.Query("col")
+    if col == "" { col = concord.Column(q) }
+    ix, err := conc.Index(c.Request.Context())
+    if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+    if q == "" {
+      limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+      if limit < 1 || limit > 500 { limit = 50 }
+      words, err := ix.Top(col, sc, limit)
+      if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+      c.JSON(200, gin.H{"column": col, "scope": sc, "words": words})
+      return
+    }
+    limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+    if limit < 1 || limit > 200 { limit = 20 }
+    width, _ := strconv.Atoi(c.DefaultQuery("context", "5"))
+    if width < 0 || width > 20 { width = 5 }
+    f, err := ix.Freq(col, q, sc)
+    if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+    lines, _ := ix.KWIC(col, q, sc, width, limit)
+    c.JSON(200, gin.H{"q": q, "scope": sc, "frequency": f, "kwic": lines})
+  })
+
+  // Data changelog recorded by incremental updates (seed -update)
+  r.GET("/meta/versions", func(c *gin.Context) {
+    limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+    vs, err := data.Versions(c.Request.Context(), s.DB(), limit)
+    if err != nil { c.JSON(500, gin.H{"error": err.Error()}); return }
+    c.JSON(200, gin.H{"versions": vs})
+  })
+  r.GET("/meta/versions/:id", func(c *gin.Context) {
+    id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+    if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version id"}); return }
+    v, changes, err := data.VersionChanges(c.Request.Context(), s.DB(), id)
+    if errors.Is(err, sql.ErrNoRows) { c.JSON(http.StatusNotFound, gin.H{"error": "version not found"}); return }
+    if err != nil { c.JSON(500, gin.H{"error": err.Error()}); return }
+    c.JSON(200, gin.H{"version": v, "changes": changes})
+  })
+
+  // plan writes need the QURAN_PLANS_TOKEN bearer token and are off without one
+  token := os.Getenv("QURAN_PLANS_TOKEN")
+  planRoutes(r, w, token)
+
+  h := httpx.Compress(r)
+  if w != nil && token != "" {
+    h = httpx.CORS(h, http.MethodPost, http.MethodPut, http.MethodDelete)
+  } else {
+    h = httpx.CORS(h)
+  }
+  h = httpx.RateLimit(h)
+  return h
+}
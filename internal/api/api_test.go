@@ -0,0 +1,312 @@
+package api
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+  "net/http/httptest"
+  "net/url"
+  "slices"
+  "strings"
+  "testing"
+
+  "github.com/jmoiron/sqlx"
+  "github.com/foozio/quran-go/internal/data"
+  "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/internal/similar"
+)
+
+func TestAPI_InvalidSurahNumber(t *testing.T) {
+  h := New(nil, nil)
+  // below 1
+  req := httptest.NewRequest(http.MethodGet, "/surah/0", nil)
+  w := httptest.NewRecorder()
+  h.ServeHTTP(w, req)
+  if w.Code != http.StatusBadRequest {
+    t.Fatalf("expected 400, got %d", w.Code)
+  }
+  // above 114
+  req = httptest.NewRequest(http.MethodGet, "/surah/115", nil)
+  w = httptest.NewRecorder()
+  h.ServeHTTP(w, req)
+  if w.Code != http.StatusBadRequest {
+    t.Fatalf("expected 400, got %d", w.Code)
+  }
+}
+
+func TestAPI_SearchTooLong(t *testing.T) {
+  h := New(nil, nil)
+  longQ := strings.Repeat("a", 101)
+  req := httptest.NewRequest(http.MethodGet, "/search?q="+longQ, nil)
+  w := httptest.NewRecorder()
+  h.ServeHTTP(w, req)
+  if w.Code != http.StatusBadRequest {
+    t.Fatalf("expected 400, got %d", w.Code)
+  }
+}
+
+func TestAPI_SearchSuggestion(t *testing.T) {
+  h := seededRouter(t)
+  w := httptest.NewRecorder()
+  h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search?q=pengasi", nil))
+  if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+  body := w.Body.String()
+  if !strings.Contains(body, `"suggestion":"pengasih"`) || !strings.Contains(body, `"number":1`) {
+    t.Fatalf("expected fuzzy hit and suggestion, got %s", body)
+  }
+}
+
+func TestAPI_SearchSyntaxError(t *testing.T) {
+  h := seededRouter(t)
+  for _, q := range []string{`"unterminated`, "nama)", "-Allah", "x:y", "AND"} {
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search?q="+url.QueryEscape(q), nil))
+    if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid query") {
+      t.Fatalf("%s: expected 400, got %d %s", q, w.Code, w.Body.String())
+    }
+  }
+}
+
+func TestAPI_Similar(t *testing.T) {
+  d := seededDB(t)
+  d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,tajweed,trans,audio_url) VALUES(1,2,1,'ٱلْحَمْدُ لِلَّهِ','', 'Segala puji bagi Allah', '')`)
+  h := New(newStore(t, d), d)
+  get := func(url string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+    return w
+  }
+  if w := get("/ayah/1/1/similar"); w.Code != http.StatusNotImplemented { t.Fatalf("without vectors: %d %s", w.Code, w.Body.String()) }
+
+  vecs := `{"surah":1,"ayah":1,"vector":[1,0]}` + "\n" + `{"surah":1,"ayah":2,"vector":[1,1]}`
+  if _, err := similar.Import(context.Background(), d, strings.NewReader(vecs), "test"); err != nil { t.Fatal(err) }
+  w := get("/ayah/1/1/similar?limit=5")
+  if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"similar":[{"surah":1,"number":2,"score":0.7071,"arabic":"ٱلْحَمْدُ لِلَّهِ"`) {
+    t.Fatalf("similar: %d %s", w.Code, w.Body.String())
+  }
+  if w := get("/ayah/1/8/similar"); w.Code != http.StatusBadRequest { t.Fatalf("invalid ref: %d", w.Code) }
+  if w := get("/ayah/2/255/similar"); w.Code != http.StatusNotFound { t.Fatalf("unknown ayah: %d", w.Code) }
+}
+
+func TestAPI_StatsWords(t *testing.T) {
+  d := seededDB(t)
+  d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,tajweed,trans,audio_url) VALUES(1,2,1,'ٱلْحَمْدُ لِلَّهِ','', 'Segala puji bagi Allah', '')`)
+  h := New(newStore(t, d), d)
+  get := func(url string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+    return w
+  }
+  w := get("/stats/words?q=allah&context=1")
+  if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"word":"allah","count":2,"ayat":2,"column":"trans","by_surah":[{"number":1,"count":2}]`) ||
+    !strings.Contains(w.Body.String(), `{"surah":1,"number":1,"left":"nama","match":"Allah","right":", \"Pengasih"}`) {
+    t.Fatalf("frequency: %d %s", w.Code, w.Body.String())
+  }
+  w = get("/stats/words?q=" + url.QueryEscape("الله"))
+  if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"column":"arabic"`) || !strings.Contains(w.Body.String(), `"count":1`) {
+    t.Fatalf("arabic: %d %s", w.Code, w.Body.String())
+  }
+  w = get("/stats/words?limit=1&surah=1")
+  if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"words":[{"word":"allah","count":2,"ayat":2}]`) { t.Fatalf("top: %d %s", w.Code, w.Body.String()) }
+  // limit=0 is not "every line": it falls back to the default of 20
+  d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,tajweed,trans,audio_url) VALUES(1,3,1,'x','', ?, '')`, strings.Repeat("Allah ", 30))
+  h = New(newStore(t, d), d) // a fresh concordance cache
+  if w := get("/stats/words?q=allah&limit=0"); strings.Count(w.Body.String(), `"match":`) != 20 { t.Fatalf("limit=0: %d lines", strings.Count(w.Body.String(), `"match":`)) }
+  for _, u := range []string{"/stats/words?q=two+words", "/stats/words?juz=31", "/stats/words?surah=x", "/stats/words?col=tafsir"} {
+    if w := get(u); w.Code != http.StatusBadRequest { t.Fatalf("%s: expected 400, got %d", u, w.Code) }
+  }
+}
+
+func TestAPI_Healthz(t *testing.T) {
+  h := New(nil, nil)
+  req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+  w := httptest.NewRecorder()
+  h.ServeHTTP(w, req)
+  if w.Code != http.StatusOK {
+    t.Fatalf("expected 200, got %d", w.Code)
+  }
+}
+
+
+func seededRouter(t *testing.T) http.Handler {
+  t.Helper()
+  d := seededDB(t)
+  return New(newStore(t, d), d)
+}
+
+func newStore(t *testing.T, d *sqlx.DB) db.Store {
+  t.Helper()
+  st, err := db.NewStore(context.Background(), d)
+  if err != nil { t.Fatal(err) }
+  t.Cleanup(func() { st.Close() })
+  return st
+}
+
+func seededDB(t *testing.T) *sqlx.DB {
+  t.Helper()
+  d, err := sqlx.Open("sqlite", ":memory:")
+  if err != nil { t.Fatal(err) }
+  d.SetMaxOpenConns(1)
+  if err := db.Migrate(context.Background(), d); err != nil { t.Fatal(err) }
+  d.MustExec(`INSERT INTO surah(number,name_ar,verses_count) VALUES(1,'الفاتحة',7)`)
+  d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,tajweed,trans,audio_url) VALUES(1,1,1,'بِسْمِ ٱللَّهِ','', 'Dengan nama Allah, "Pengasih"', '')`)
+  return d
+}
+
+func TestAPI_SurahFormats(t *testing.T) {
+  h := seededRouter(t)
+  cases := []struct{ url, accept, ctype, contains string }{
+    {"/surah/1", "", "application/json", `"arabic":"بِسْمِ ٱللَّهِ"`},
+    {"/surah/1?format=text", "", "text/plain", "1:1\nبِسْمِ ٱللَّهِ"},
+    {"/surah/1", "text/csv", "text/csv", `1,1,بِسْمِ ٱللَّهِ,,"Dengan nama Allah, ""Pengasih""",`},
+    {"/surah/1", "application/msgpack", "application/msgpack", "audio_url"},
+  }
+  for _, tc := range cases {
+    req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+    if tc.accept != "" { req.Header.Set("Accept", tc.accept) }
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, req)
+    if w.Code != http.StatusOK { t.Fatalf("%s %s: expected 200, got %d", tc.url, tc.accept, w.Code) }
+    if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, tc.ctype) {
+      t.Fatalf("%s %s: content-type %q", tc.url, tc.accept, ct)
+    }
+    if !strings.Contains(w.Body.String(), tc.contains) {
+      t.Fatalf("%s %s: body %q missing %q", tc.url, tc.accept, w.Body.String(), tc.contains)
+    }
+    if vary := w.Header().Values("Vary"); !slices.Contains(vary, "Accept") || !slices.Contains(vary, "Accept-Encoding") {
+      t.Fatalf("%s %s: Vary %q", tc.url, tc.accept, vary)
+    }
+  }
+
+  req := httptest.NewRequest(http.MethodGet, "/surah/1?format=yaml", nil)
+  w := httptest.NewRecorder()
+  h.ServeHTTP(w, req)
+  if w.Code != http.StatusNotAcceptable { t.Fatalf("expected 406, got %d", w.Code) }
+}
+
+func TestAPI_MetaVersions(t *testing.T) {
+  d := seededDB(t)
+  ds := &data.Dataset{Source: "fix", Translations: []data.Translation{{Surah: 1, Number: 1, Lang: "id", Text: "Dengan nama Allah"}}}
+  v, err := data.Update(context.Background(), d, ds, data.UpdateOptions{Note: "typo"})
+  if err != nil { t.Fatal(err) }
+  h := New(newStore(t, d), d)
+
+  get := func(url string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+    return w
+  }
+  w := get("/meta/versions")
+  if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"note":"typo"`) {
+    t.Fatalf("versions: %d %s", w.Code, w.Body.String())
+  }
+  w = get(fmt.Sprintf("/meta/versions/%d", v.ID))
+  if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"kind":"translation"`) {
+    t.Fatalf("version detail: %d %s", w.Code, w.Body.String())
+  }
+  if w = get("/meta/versions/99"); w.Code != http.StatusNotFound { t.Fatalf("expected 404, got %d", w.Code) }
+  if w = get("/meta/versions/x"); w.Code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", w.Code) }
+}
+
+func TestAPI_Plans(t *testing.T) {
+  d := seededDB(t)
+  t.Setenv("QURAN_PLANS_TOKEN", "s3cret")
+  h := New(newStore(t, d), d)
+  do := func(method, url, body string) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(method, url, strings.NewReader(body))
+    if method != http.MethodGet { req.Header.Set("Authorization", "Bearer s3cret") }
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, req)
+    return w
+  }
+  w := do(http.MethodPost, "/plans", `{"name":"Ramadan","parts":30,"start":"2026-02-18"}`)
+  if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"id":1,"name":"Ramadan","unit":"juz","mode":"days","parts":30`) {
+    t.Fatalf("create: %d %s", w.Code, w.Body.String())
+  }
+  if w := do(http.MethodPut, "/plans/1/parts/1", `{"done":true}`); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"done":1,`) {
+    t.Fatalf("done: %d %s", w.Code, w.Body.String())
+  }
+  w = do(http.MethodGet, "/plans/1/today?date=2026-02-20", "")
+  if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"today":[{"part":3,"day":"2026-02-20","from":{"surah":2,"ayah":253},"to":{"surah":3,"ayah":92}`) ||
+    !strings.Contains(w.Body.String(), `"overdue":[{"part":2,`) {
+    t.Fatalf("today: %d %s", w.Code, w.Body.String())
+  }
+  if w := do(http.MethodGet, "/plans", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"plans":[{"id":1,`) { t.Fatalf("list: %d %s", w.Code, w.Body.String()) }
+
+  for _, tc := range []struct{ method, url, body string; code int }{
+    {http.MethodPost, "/plans", `{"unit":"page","parts":605}`, http.StatusBadRequest},
+    {http.MethodPost, "/plans", `{"parts":31}`, http.StatusBadRequest},
+    {http.MethodPost, "/plans", `not json`, http.StatusBadRequest},
+    {http.MethodPut, "/plans/1/parts/31", `{"done":true}`, http.StatusBadRequest},
+    {http.MethodPut, "/plans/1/parts/2", `{}`, http.StatusBadRequest},
+    {http.MethodGet, "/plans/1/today?date=tomorrow", "", http.StatusBadRequest},
+    {http.MethodGet, "/plans/x", "", http.StatusBadRequest},
+    {http.MethodGet, "/plans/2", "", http.StatusNotFound},
+    {http.MethodDelete, "/plans/1", "", http.StatusNoContent},
+    {http.MethodDelete, "/plans/1", "", http.StatusNotFound},
+  } {
+    if w := do(tc.method, tc.url, tc.body); w.Code != tc.code { t.Errorf("%s %s: %d %s, want %d", tc.method, tc.url, w.Code, w.Body.String(), tc.code) }
+  }
+
+  w = httptest.NewRecorder()
+  New(nil, nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/plans", nil))
+  if w.Code != http.StatusServiceUnavailable { t.Fatalf("without a writable handle: %d", w.Code) }
+}
+
+func TestAPI_PlanWritesNeedToken(t *testing.T) {
+  d := seededDB(t)
+  send := func(h http.Handler, method, url, auth string) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(method, url, strings.NewReader(`{"parts":30}`))
+    if auth != "" { req.Header.Set("Authorization", auth) }
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, req)
+    return w
+  }
+  open := New(newStore(t, d), d)
+  if w := send(open, http.MethodPost, "/plans", "Bearer x"); w.Code != http.StatusForbidden { t.Fatalf("without QURAN_PLANS_TOKEN: %d %s", w.Code, w.Body.String()) }
+  if w := send(open, http.MethodGet, "/plans", ""); w.Code != http.StatusOK { t.Fatalf("reads stay public: %d", w.Code) }
+  if m := send(open, http.MethodOptions, "/plans", "").Header().Get("Access-Control-Allow-Methods"); m != "GET,OPTIONS" { t.Fatalf("preflight without writes: %q", m) }
+
+  t.Setenv("QURAN_PLANS_TOKEN", "s3cret")
+  h := New(newStore(t, d), d)
+  for _, auth := range []string{"", "Bearer wrong", "s3cret"} {
+    if w := send(h, http.MethodDelete, "/plans/1", auth); w.Code != http.StatusUnauthorized { t.Errorf("auth %q: %d", auth, w.Code) }
+  }
+  if w := send(h, http.MethodPost, "/plans", "Bearer s3cret"); w.Code != http.StatusCreated { t.Fatalf("with token: %d %s", w.Code, w.Body.String()) }
+  if m := send(h, http.MethodOptions, "/plans", "").Header().Get("Access-Control-Allow-Methods"); m != "GET,OPTIONS,POST,PUT,DELETE" { t.Fatalf("preflight with writes: %q", m) }
+}
+
+func TestAPI_Bundle(t *testing.T) {
+  h := seededRouter(t)
+  get := func(url, etag string) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(http.MethodGet, url, nil)
+    if etag != "" { req.Header.Set("If-None-Match", etag) }
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, req)
+    return w
+  }
+  w := get("/bundle", "")
+  etag := w.Header().Get("ETag")
+  if w.Code != http.StatusOK || len(etag) != 18 || !strings.Contains(w.Body.String(), `"version":`+etag) ||
+    !strings.Contains(w.Body.String(), `"ayah":[{"surah":1,"ayah":1,"arabic":"بِسْمِ ٱللَّهِ","trans":"Dengan nama Allah, \"Pengasih\""}]`) {
+    t.Fatalf("bundle: %d %q %s", w.Code, etag, w.Body.String())
+  }
+  if w := get("/bundle", etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 { t.Fatalf("revalidate: %d %s", w.Code, w.Body.String()) }
+  if w := get("/bundle", `"stale"`); w.Code != http.StatusOK { t.Fatalf("stale etag: %d", w.Code) }
+  if w := get("/bundle?lang=xx", ""); w.Code != http.StatusNotFound { t.Fatalf("unknown lang: %d %s", w.Code, w.Body.String()) }
+  if w := get("/bundle/langs", ""); w.Code != http.StatusOK || w.Body.String() != `{"langs":[],"primary":""}` { t.Fatalf("langs: %d %s", w.Code, w.Body.String()) }
+}
+
+func TestAPI_ExportLimits(t *testing.T) {
+  h := seededRouter(t)
+  for url, code := range map[string]int{
+    "/export?ref=1:1&format=epub":  http.StatusOK,
+    "/export?ref=1:1&format=pdf":   http.StatusNotImplemented,
+    "/export?ref=1-114&format=epub": http.StatusRequestEntityTooLarge,
+    "/export?ref=1-114&format=md":  http.StatusOK,
+  } {
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+    if w.Code != code { t.Errorf("%s: %d %s, want %d", url, w.Code, w.Body.String(), code) }
+  }
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+  "crypto/subtle"
+  "errors"
+  "net/http"
+  "strconv"
+  "strings"
+  "time"
+
+  "github.com/gin-gonic/gin"
+  "github.com/jmoiron/sqlx"
+  "github.com/foozio/quran-go/internal/plan"
+)
+
+// planRoutes serves reading plans from w, the servers' only writable
+// handle; without one the endpoints answer 503. Reads are public; writes
+// need "Authorization: Bearer <token>" and are refused when token is empty.
+func planRoutes(r *gin.Engine, w *sqlx.DB, token string) {
+  g := r.Group("/plans")
+  g.Use(func(c *gin.Context) {
+    if w == nil { c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "reading plans are not available"}); return }
+    if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+      if token == "" { c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "plan changes are disabled on this server (set QURAN_PLANS_TOKEN)"}); return }
+      got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+      if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+        c.Header("WWW-Authenticate", "Bearer")
+        c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "plan changes need a valid bearer token"}); return
+      }
+    }
+    if c.Param("id") != "" {
+      id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+      if err != nil { c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid plan id"}); return }
+      c.Set("id", id)
+    }
+  })
+  g.GET("", func(c *gin.Context) {
+    ps, err := plan.List(c.Request.Context(), w)
+    if err != nil { planError(c, err); return }
+    c.JSON(200, gin.H{"plans": ps})
+  })
+  g.POST("", func(c *gin.Context) {
+    var s plan.Spec
+    if err := c.ShouldBindJSON(&s); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+    p, err := plan.Create(c.Request.Context(), w, s, time.Now())
+    if err != nil { planError(c, err); return }
+    c.JSON(http.StatusCreated, p)
+  })
+  g.GET("/:id", func(c *gin.Context) {
+    p, err := plan.Get(c.Request.Context(), w, c.GetInt64("id"))
+    if err != nil { planError(c, err); return }
+    c.JSON(200, p)
+  })
+  g.DELETE("/:id", func(c *gin.Context) {
+    if err := plan.Delete(c.Request.Context(), w, c.GetInt64("id")); err != nil { planError(c, err); return }
+    c.Status(http.StatusNoContent)
+  })
+  g.GET("/:id/today", func(c *gin.Context) {
+    day := time.Now()
+    if v := c.Query("date"); v != "" {
+      var err error
+      if day, err = time.Parse(plan.DateLayout, v); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"}); return }
+    }
+    p, err := plan.Get(c.Request.Context(), w, c.GetInt64("id"))
+    if err != nil { planError(c, err); return }
+    today, overdue := p.Today(day, c.Query("participant"))
+    c.JSON(200, gin.H{"plan": p.ID, "name": p.Name, "date": day.Format(plan.DateLayout), "today": today, "overdue": overdue})
+  })
+  g.PUT("/:id/parts/:part", func(c *gin.Context) {
+    part, err := strconv.Atoi(c.Param("part"))
+    if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid part"}); return }
+    var body struct {
+      Done *bool `json:"done"`
+    }
+    if err := c.ShouldBindJSON(&body); err != nil || body.Done == nil { c.JSON(http.StatusBadRequest, gin.H{"error": `body must be {"done": true|false}`}); return }
+    id := c.GetInt64("id")
+    if err := plan.SetDone(c.Request.Context(), w, id, part, *body.Done, time.Now()); err != nil { planError(c, err); return }
+    p, err := plan.Get(c.Request.Context(), w, id)
+    if err != nil { planError(c, err); return }
+    c.JSON(200, p)
+  })
+}
+
+func planError(c *gin.Context, err error) {
+  switch {
+  case errors.Is(err, plan.ErrNotFound):
+    c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+  case errors.Is(err, plan.ErrInvalid):
+    c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+  default:
+    c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+  }
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+  "bytes"
+  "encoding/csv"
+  "fmt"
+  "net/http"
+  "strconv"
+  "strings"
+
+  "github.com/gin-gonic/gin"
+  "github.com/gin-gonic/gin/render"
+  "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/internal/httpx"
+)
+
+// renderSurah writes a surah's ayah in the negotiated format.
+func renderSurah(c *gin.Context, format string, n int, rows []db.Ayah) {
+  c.Writer.Header().Add("Vary", "Accept")
+  switch format {
+  case httpx.FormatText:
+    b := &strings.Builder{}
+    for _, a := range rows {
+      fmt.Fprintf(b, "%d:%d\n%s\n", n, a.Ayah, a.Arabic)
+      if strings.TrimSpace(a.Trans) != "" { fmt.Fprintf(b, "  %s\n", a.Trans) }
+    }
+    c.Data(http.StatusOK, httpx.ContentTypes[format], []byte(b.String()))
+  case httpx.FormatCSV:
+    buf := &bytes.Buffer{}
+    cw := csv.NewWriter(buf)
+    _ = cw.Write([]string{"surah", "ayah", "arabic", "tajweed", "trans", "audio_url"})
+    for _, a := range rows {
+      _ = cw.Write([]string{strconv.Itoa(n), strconv.Itoa(a.Ayah), a.Arabic, a.Tajweed, a.Trans, a.AudioURL})
+    }
+    cw.Flush()
+    c.Data(http.StatusOK, httpx.ContentTypes[format], buf.Bytes())
+  case httpx.FormatMsgPack:
+    c.Render(http.StatusOK, render.MsgPack{Data: gin.H{"surah": n, "ayah": rows}})
+  default:
+    c.JSON(http.StatusOK, gin.H{"surah": n, "ayah": rows})
+  }
+}
@@ -0,0 +1,95 @@
+// Package assets embeds the stylesheet and script of the server-rendered web
+// UI and serves them under content-hashed names, so pages work without any
+// network beyond the server and browsers may cache the files for good.
+package assets
+
+import (
+  "crypto/sha512"
+  "embed"
+  "encoding/base64"
+  "encoding/hex"
+  "fmt"
+  "html/template"
+  "io/fs"
+  "mime"
+  "net/http"
+  "path"
+  "strings"
+)
+
+//go:embed static
+var files embed.FS
+
+// Prefix is the URL path the assets are served under.
+const Prefix = "/static/"
+
+type asset struct {
+  url   string // Prefix + name with the hash before the extension
+  sri   string // Subresource Integrity value
+  etag  string
+  ctype string
+  body  []byte
+}
+
+var (
+  byName = map[string]*asset{}
+  byURL  = map[string]*asset{}
+)
+
+func init() {
+  err := fs.WalkDir(files, "static", func(p string, d fs.DirEntry, err error) error {
+    if err != nil || d.IsDir() { return err }
+    body, err := files.ReadFile(p)
+    if err != nil { return err }
+    sum := sha512.Sum384(body)
+    name := strings.TrimPrefix(p, "static/")
+    ext := path.Ext(name)
+    hash := hex.EncodeToString(sum[:])[:12]
+    a := &asset{
+      url:   Prefix + strings.TrimSuffix(name, ext) + "." + hash + ext,
+      sri:   "sha384-" + base64.StdEncoding.EncodeToString(sum[:]),
+      etag:  `"` + hash + `"`,
+      ctype: mime.TypeByExtension(ext),
+      body:  body,
+    }
+    byName[name], byURL[a.url] = a, a
+    return nil
+  })
+  if err != nil { panic(err) }
+}
+
+// Path returns the hashed URL of an embedded file, e.g.
+// /static/app.3f9a1c2b7d4e.css.
+func Path(name string) (string, error) {
+  a, ok := byName[name]
+  if !ok { return "", fmt.Errorf("assets: no file %q", name) }
+  return a.url, nil
+}
+
+// Integrity returns the Subresource Integrity value of an embedded file.
+func Integrity(name string) (string, error) {
+  a, ok := byName[name]
+  if !ok { return "", fmt.Errorf("assets: no file %q", name) }
+  return a.sri, nil
+}
+
+// Funcs are template functions: {{asset "app.css"}} is the hashed URL and
+// {{sri "app.css"}} its integrity value.
+var Funcs = template.FuncMap{"asset": Path, "sri": Integrity}
+
+// Handler serves the files at their hashed URLs. Those never change content,
+// so responses are cacheable for a year and marked immutable; any other
+// name under Prefix, including an unhashed or stale one, is 404.
+func Handler() http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    a, ok := byURL[r.URL.Path]
+    if !ok { http.NotFound(w, r); return }
+    h := w.Header()
+    h.Set("Content-Type", a.ctype)
+    h.Set("Cache-Control", "public, max-age=31536000, immutable")
+    h.Set("ETag", a.etag)
+    if r.Header.Get("If-None-Match") == a.etag { w.WriteHeader(http.StatusNotModified); return }
+    if r.Method == http.MethodHead { return }
+    _, _ = w.Write(a.body)
+  })
+}
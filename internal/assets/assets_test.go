@@ -0,0 +1,47 @@
+package assets
+
+import (
+  "crypto/sha512"
+  "encoding/base64"
+  "net/http"
+  "net/http/httptest"
+  "regexp"
+  "strings"
+  "testing"
+)
+
+func TestPathAndIntegrity(t *testing.T) {
+  for _, name := range []string{"app.css", "app.js"} {
+    p, err := Path(name)
+    if err != nil { t.Fatal(err) }
+    if !regexp.MustCompile(`^/static/app\.[0-9a-f]{12}\.(css|js)$`).MatchString(p) { t.Errorf("%s: path %s", name, p) }
+    body, _ := files.ReadFile("static/" + name)
+    sum := sha512.Sum384(body)
+    if sri, _ := Integrity(name); sri != "sha384-"+base64.StdEncoding.EncodeToString(sum[:]) { t.Errorf("%s: integrity %s", name, sri) }
+  }
+  if _, err := Path("htmx.js"); err == nil { t.Error("unknown file: no error") }
+  if _, err := Integrity("htmx.js"); err == nil { t.Error("unknown file: no error") }
+}
+
+func TestHandler(t *testing.T) {
+  h := Handler()
+  p, _ := Path("app.css")
+  get := func(url, etag string) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(http.MethodGet, url, nil)
+    if etag != "" { req.Header.Set("If-None-Match", etag) }
+    w := httptest.NewRecorder()
+    h.ServeHTTP(w, req)
+    return w
+  }
+
+  w := get(p, "")
+  if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/css") || !strings.Contains(w.Body.String(), "--accent") {
+    t.Fatalf("%s: %d %v", p, w.Code, w.Header())
+  }
+  if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=31536000, immutable" { t.Errorf("Cache-Control %q", cc) }
+  if w := get(p, w.Header().Get("ETag")); w.Code != http.StatusNotModified || w.Body.Len() != 0 { t.Errorf("If-None-Match: %d", w.Code) }
+
+  for _, url := range []string{"/static/app.css", "/static/app.000000000000.css", "/static/"} {
+    if w := get(url, ""); w.Code != http.StatusNotFound { t.Errorf("%s: %d", url, w.Code) }
+  }
+}
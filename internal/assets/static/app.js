@@ -0,0 +1,112 @@
+// Partial page loads, bookmarks and notes for the quran-web and quran-all
+// pages. Served from the binary, so the UI needs no network beyond the server.
+(() => {
+  const LS_BOOK = 'quran_bookmarks';
+  const LS_NOTES = 'quran_notes';
+
+  // load fetches url as a fragment (the server sees X-Partial) and puts it in
+  // target. Error bodies are plain text and are inserted as text.
+  async function load(url, target, push) {
+    const el = document.querySelector(target);
+    if (!el) return;
+    try {
+      const res = await fetch(url, { headers: { 'X-Partial': '1' } });
+      const body = await res.text();
+      if ((res.headers.get('Content-Type') || '').startsWith('text/html')) el.innerHTML = body;
+      else el.textContent = body;
+      if (push && res.ok) history.pushState(null, '', url);
+    } catch (err) {
+      el.textContent = 'The server could not be reached.';
+    }
+  }
+
+  // links with data-get load into data-target; data-push also updates the URL
+  document.addEventListener('click', (e) => {
+    const a = e.target.closest('a[data-get]');
+    if (a && !e.ctrlKey && !e.metaKey && !e.shiftKey) {
+      e.preventDefault();
+      load(a.dataset.get, a.dataset.target, a.hasAttribute('data-push'));
+      return;
+    }
+    const btn = e.target.closest('[data-ref]');
+    if (btn) toggleBookmark(btn.dataset.ref);
+  });
+  window.addEventListener('popstate', () => location.reload());
+
+  // inputs with data-get search as you type, after a pause
+  document.querySelectorAll('input[data-get]').forEach((input) => {
+    let timer, last = input.value;
+    input.addEventListener('input', () => {
+      clearTimeout(timer);
+      timer = setTimeout(() => {
+        if (input.value === last) return;
+        last = input.value;
+        load(input.dataset.get + '?q=' + encodeURIComponent(input.value), input.dataset.target, false);
+      }, 400);
+    });
+  });
+
+  // mushaf reader: pages turn right to left, so ← is the next page
+  if (document.body.hasAttribute('data-mushaf')) {
+    const keys = { ArrowLeft: 'next', n: 'next', ArrowRight: 'prev', p: 'prev' };
+    document.addEventListener('keydown', (e) => {
+      if (e.altKey || e.ctrlKey || e.metaKey || e.target.closest('input, textarea')) return;
+      const a = keys[e.key] && document.querySelector('a[data-key="' + keys[e.key] + '"]');
+      if (a) { e.preventDefault(); location.href = a.href; }
+    });
+  }
+
+  // the rest drives the bookmark and notes panel of the main page
+  if (!document.getElementById('notes')) return;
+
+  // everything from storage is inserted as text, never as HTML
+  function loadBookmarks() {
+    const list = JSON.parse(localStorage.getItem(LS_BOOK) || '[]');
+    const el = document.getElementById('bookmarks');
+    el.replaceChildren();
+    if (!list.length) {
+      const em = document.createElement('em');
+      em.className = 'muted';
+      em.textContent = 'No bookmarks yet';
+      el.appendChild(em);
+    }
+    list.forEach((ref) => {
+      const a = document.createElement('a');
+      a.href = '/s/' + encodeURIComponent(String(ref).split(':')[0]);
+      a.textContent = ref;
+      el.appendChild(a);
+    });
+  }
+  function toggleBookmark(ref) {
+    const list = new Set(JSON.parse(localStorage.getItem(LS_BOOK) || '[]'));
+    if (list.has(ref)) list.delete(ref); else list.add(ref);
+    localStorage.setItem(LS_BOOK, JSON.stringify(Array.from(list)));
+    loadBookmarks();
+  }
+  function loadNotes() {
+    const notes = JSON.parse(localStorage.getItem(LS_NOTES) || '{}');
+    const el = document.getElementById('notes');
+    el.replaceChildren();
+    Object.keys(notes).sort().forEach((ref) => {
+      const div = document.createElement('div');
+      div.className = 'ayah';
+      const b = document.createElement('b');
+      b.textContent = ref;
+      div.append(b, document.createElement('br'), notes[ref]);
+      el.appendChild(div);
+    });
+  }
+  function saveNote() {
+    const ref = document.getElementById('note-ref').value.trim();
+    const text = document.getElementById('note-text').value.trim();
+    if (!ref || !text) return;
+    const notes = JSON.parse(localStorage.getItem(LS_NOTES) || '{}');
+    notes[ref] = text;
+    localStorage.setItem(LS_NOTES, JSON.stringify(notes));
+    document.getElementById('note-text').value = '';
+    loadNotes();
+  }
+  document.getElementById('note-save').addEventListener('click', saveNote);
+
+  loadBookmarks(); loadNotes();
+})();
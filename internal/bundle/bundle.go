@@ -0,0 +1,135 @@
+// Package bundle builds the whole Quran with one translation as a single
+// versioned document, which apps download once to read offline.
+package bundle
+
+import (
+  "context"
+  "crypto/sha256"
+  "encoding/hex"
+  "errors"
+  "fmt"
+  "slices"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+
+  "github.com/jmoiron/sqlx"
+  "github.com/foozio/quran-go/internal/db"
+)
+
+// ErrUnknownLang is returned for a language with no translation rows.
+var ErrUnknownLang = errors.New("no translation in that language")
+
+// Ayah is one verse of the bundle; Trans is in the bundle's language.
+type Ayah struct {
+  Surah  int    `db:"surah" json:"surah"`
+  Ayah   int    `db:"number" json:"ayah"`
+  Arabic string `db:"arabic" json:"arabic"`
+  Trans  string `db:"trans" json:"trans,omitempty"`
+}
+
+// Bundle is the dataset as apps cache it. Version is a hash of the content,
+// so it changes exactly when a reseed or update changes the text, the
+// translation or the surah list.
+type Bundle struct {
+  Version string     `json:"version"`
+  Lang    string     `json:"lang"`
+  Surahs  []db.Surah `json:"surahs"`
+  Ayat    []Ayah     `json:"ayah"`
+}
+
+// Build reads every surah and ayah with the translation in lang; empty lang
+// is the primary translation (ayah.trans).
+func Build(ctx context.Context, d *sqlx.DB, lang string) (*Bundle, error) {
+  primary := ""
+  _ = d.GetContext(ctx, &primary, `SELECT value FROM meta WHERE key='primary_lang'`)
+  if lang == "" { lang = primary }
+  b := &Bundle{Lang: lang}
+  if err := d.SelectContext(ctx, &b.Surahs, `SELECT number, name_ar, name_latin, revelation, verses_count FROM surah ORDER BY number`); err != nil { return nil, err }
+
+  q, args := `SELECT surah, number, arabic, COALESCE(trans,'') AS trans FROM ayah ORDER BY surah, number`, []any{}
+  if lang != primary {
+    var n int
+    if err := d.GetContext(ctx, &n, d.Rebind(`SELECT COUNT(*) FROM translation WHERE lang=?`), lang); err != nil { return nil, err }
+    if n == 0 { return nil, fmt.Errorf("%w: %q", ErrUnknownLang, lang) }
+    q = d.Rebind(`SELECT a.surah, a.number, a.arabic, COALESCE(t.text,'') AS trans
+      FROM ayah a LEFT JOIN translation t ON t.surah = a.surah AND t.number = a.number AND t.lang = ?
+      ORDER BY a.surah, a.number`)
+    args = append(args, lang)
+  }
+  if err := d.SelectContext(ctx, &b.Ayat, q, args...); err != nil { return nil, err }
+  b.Version = b.hash()
+  return b, nil
+}
+
+// Langs returns the primary translation language and every language that
+// can be bundled, primary included.
+func Langs(ctx context.Context, d *sqlx.DB) (primary string, langs []string, err error) {
+  _ = d.GetContext(ctx, &primary, `SELECT value FROM meta WHERE key='primary_lang'`)
+  langs = []string{}
+  if err := d.SelectContext(ctx, &langs, `SELECT DISTINCT lang FROM translation ORDER BY lang`); err != nil { return "", nil, err }
+  if primary != "" && !slices.Contains(langs, primary) {
+    langs = append([]string{primary}, langs...)
+  }
+  return primary, langs, nil
+}
+
+// Cache keeps built bundles per language. They are dropped when the data
+// stamp (the latest data_version and the source and primary_lang meta)
+// changes, and after TTL for reseeds that record no version.
+type Cache struct {
+  d   *sqlx.DB
+  TTL time.Duration
+
+  mu    sync.Mutex
+  stamp string
+  at    time.Time
+  by    map[string]*Bundle
+}
+
+// NewCache returns a Cache that also rebuilds every five minutes.
+func NewCache(d *sqlx.DB) *Cache { return &Cache{d: d, TTL: 5 * time.Minute} }
+
+// Get returns the bundle for lang, building it only when the data changed.
+func (c *Cache) Get(ctx context.Context, lang string) (*Bundle, error) {
+  st, err := stamp(ctx, c.d)
+  if err != nil { return nil, err }
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  if st != c.stamp || time.Since(c.at) >= c.TTL { c.stamp, c.at, c.by = st, time.Now(), map[string]*Bundle{} }
+  if b := c.by[lang]; b != nil { return b, nil }
+  b, err := Build(ctx, c.d, lang)
+  if err != nil { return nil, err }
+  c.by[lang] = b
+  return b, nil
+}
+
+func stamp(ctx context.Context, d *sqlx.DB) (string, error) {
+  var id int64
+  if err := d.GetContext(ctx, &id, `SELECT COALESCE(MAX(id),0) FROM data_version`); err != nil { return "", err }
+  var meta []string
+  if err := d.SelectContext(ctx, &meta, `SELECT key || '=' || value FROM meta WHERE key IN ('source','primary_lang') ORDER BY key`); err != nil { return "", err }
+  return strconv.FormatInt(id, 10) + ";" + strings.Join(meta, ";"), nil
+}
+
+func (b *Bundle) hash() string {
+  h := sha256.New()
+  // length-prefixed, so no two field lists hash alike
+  write := func(fields ...string) {
+    for _, f := range fields { fmt.Fprintf(h, "%d:%s", len(f), f) }
+  }
+  write(b.Lang)
+  for _, s := range b.Surahs {
+    write(strconv.Itoa(s.Number), s.NameAr, deref(s.NameLatin), deref(s.Revelation), strconv.Itoa(s.Verses))
+  }
+  for _, a := range b.Ayat {
+    write(strconv.Itoa(a.Surah), strconv.Itoa(a.Ayah), a.Arabic, a.Trans)
+  }
+  return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func deref(s *string) string {
+  if s == nil { return "" }
+  return *s
+}
@@ -0,0 +1,75 @@
+package bundle
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "testing"
+  "time"
+
+  "github.com/jmoiron/sqlx"
+  _ "modernc.org/sqlite"
+
+  "github.com/foozio/quran-go/internal/db"
+)
+
+func TestBuild(t *testing.T) {
+  ctx := context.Background()
+  d, err := sqlx.Open("sqlite", ":memory:")
+  if err != nil { t.Fatal(err) }
+  d.SetMaxOpenConns(1)
+  defer d.Close()
+  if err := db.Migrate(ctx, d); err != nil { t.Fatal(err) }
+  d.MustExec(`INSERT INTO surah(number,name_ar,name_latin,verses_count) VALUES(1,'الفاتحة','Al-Fatihah',7)`)
+  d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,trans) VALUES(1,1,1,'بِسْمِ','Dengan nama'),(1,2,1,'ٱلْحَمْدُ','Segala puji')`)
+  d.MustExec(`INSERT INTO translation(surah,number,lang,text) VALUES(1,1,'en','In the name')`)
+  d.MustExec(`INSERT INTO meta(key,value) VALUES('primary_lang','id')`)
+
+  b, err := Build(ctx, d, "")
+  if err != nil { t.Fatal(err) }
+  if b.Lang != "id" || len(b.Surahs) != 1 || len(b.Ayat) != 2 || b.Ayat[1] != (Ayah{1, 2, "ٱلْحَمْدُ", "Segala puji"}) || len(b.Version) != 16 {
+    t.Fatalf("primary: %+v", b)
+  }
+  if again, _ := Build(ctx, d, "id"); again.Version != b.Version { t.Errorf("version not stable: %s %s", again.Version, b.Version) }
+
+  en, err := Build(ctx, d, "en")
+  if err != nil { t.Fatal(err) }
+  if en.Lang != "en" || en.Ayat[0].Trans != "In the name" || en.Ayat[1].Trans != "" || en.Version == b.Version { t.Fatalf("en: %+v", en) }
+  if _, err := Build(ctx, d, "fr"); !errors.Is(err, ErrUnknownLang) { t.Fatalf("fr: %v", err) }
+
+  d.MustExec(`UPDATE ayah SET arabic='بِسْمِ ٱللَّهِ' WHERE surah=1 AND number=1`)
+  if changed, _ := Build(ctx, d, ""); changed.Version == b.Version { t.Error("version did not change with the text") }
+
+  primary, langs, err := Langs(ctx, d)
+  if err != nil || primary != "id" || fmt.Sprint(langs) != "[id en]" { t.Fatalf("langs: %q %v %v", primary, langs, err) }
+}
+
+func TestCache(t *testing.T) {
+  ctx := context.Background()
+  d, err := sqlx.Open("sqlite", ":memory:")
+  if err != nil { t.Fatal(err) }
+  d.SetMaxOpenConns(1)
+  defer d.Close()
+  if err := db.Migrate(ctx, d); err != nil { t.Fatal(err) }
+  d.MustExec(`INSERT INTO surah(number,name_ar,name_latin,verses_count) VALUES(1,'الفاتحة','Al-Fatihah',7)`)
+  d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,trans) VALUES(1,1,1,'بِسْمِ','Dengan nama')`)
+
+  c := NewCache(d)
+  b, err := c.Get(ctx, "")
+  if err != nil { t.Fatal(err) }
+  if again, _ := c.Get(ctx, ""); again != b { t.Fatal("not cached") }
+  if _, err := c.Get(ctx, "fr"); !errors.Is(err, ErrUnknownLang) { t.Fatalf("fr: %v", err) }
+
+  d.MustExec(`UPDATE ayah SET arabic='بِسْمِ ٱللَّهِ'`)
+  if same, _ := c.Get(ctx, ""); same != b { t.Fatal("rebuilt without a new data version") }
+  d.MustExec(`INSERT INTO data_version(corpus_hash) VALUES('x')`)
+  fresh, err := c.Get(ctx, "")
+  if err != nil || fresh == b || fresh.Ayat[0].Arabic != "بِسْمِ ٱللَّهِ" { t.Fatalf("after update: %+v %v", fresh, err) }
+
+  d.MustExec(`UPDATE ayah SET arabic='x'`)
+  c.TTL = 0
+  if expired, _ := c.Get(ctx, ""); expired.Ayat[0].Arabic != "x" { t.Fatalf("after TTL: %+v", expired) }
+  c.TTL = time.Hour
+  d.MustExec(`INSERT INTO meta(key,value) VALUES('primary_lang','id')`)
+  if relang, _ := c.Get(ctx, ""); relang.Lang != "id" { t.Fatalf("after primary_lang: %+v", relang) }
+}
@@ -0,0 +1,291 @@
+// Package concord counts word occurrences in the Arabic text and the
+// primary translation, overall or within a surah or juz, and lists them in
+// context (keyword in context, KWIC).
+package concord
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "sort"
+  "strings"
+  "sync"
+  "time"
+  "unicode"
+
+  "github.com/jmoiron/sqlx"
+
+  "github.com/foozio/quran-go/internal/search"
+  "github.com/foozio/quran-go/internal/verify"
+)
+
+// Columns that can be counted.
+const (
+  ColArabic = "arabic"
+  ColTrans  = "trans"
+)
+
+// ErrEmpty means the database has no ayah.
+var ErrEmpty = errors.New("no ayah to count")
+
+// Ayah is an ayah as loaded for counting.
+type Ayah struct {
+  Surah  int    `db:"surah"`
+  Number int    `db:"number"`
+  Juz    int    `db:"juz"`
+  Arabic string `db:"arabic"`
+  Trans  string `db:"trans"`
+}
+
+// Scope restricts counts to a surah or a juz; the zero Scope is the whole
+// Quran.
+type Scope struct {
+  Surah int `json:"surah,omitempty"`
+  Juz   int `json:"juz,omitempty"`
+}
+
+func (s Scope) has(a *Ayah) bool {
+  return (s.Surah == 0 || a.Surah == s.Surah) && (s.Juz == 0 || a.Juz == s.Juz)
+}
+
+// Word is a word form with its number of occurrences and of ayah holding it.
+type Word struct {
+  Word  string `json:"word"`
+  Count int    `json:"count"`
+  Ayat  int    `json:"ayat"`
+}
+
+// Part is the count within one surah or juz.
+type Part struct {
+  Number int `json:"number"`
+  Count  int `json:"count"`
+}
+
+// Freq is the frequency of one word form and its distribution.
+type Freq struct {
+  Word
+  Column  string `json:"column"`
+  BySurah []Part `json:"by_surah"`
+  ByJuz   []Part `json:"by_juz"`
+}
+
+// Line is one occurrence in context: Left and Right are up to the requested
+// number of words around Match, as they appear in the text.
+type Line struct {
+  Surah  int    `json:"surah"`
+  Number int    `json:"number"`
+  Left   string `json:"left"`
+  Match  string `json:"match"`
+  Right  string `json:"right"`
+}
+
+// Index holds every ayah split into words, with the occurrences of each
+// word form per column.
+type Index struct {
+  ayat  []Ayah
+  words [2][][]token        // per column, per ayah
+  occ   [2]map[string][]occ // per column, by form
+}
+
+type token struct {
+  form       string
+  start, end int // byte offsets in the original text
+}
+
+type occ struct{ ayah, word int }
+
+// Load reads every ayah from d and indexes it.
+func Load(ctx context.Context, d *sqlx.DB) (*Index, error) {
+  var ayat []Ayah
+  err := d.SelectContext(ctx, &ayat, `SELECT surah, number, juz, arabic, COALESCE(trans,'') AS trans FROM ayah ORDER BY surah, number`)
+  if err != nil { return nil, err }
+  if len(ayat) == 0 { return nil, ErrEmpty }
+  return New(ayat), nil
+}
+
+// New indexes ayat, which should be in mushaf order.
+func New(ayat []Ayah) *Index {
+  ix := &Index{ayat: ayat}
+  for c := range ix.occ {
+    ix.occ[c] = map[string][]occ{}
+    ix.words[c] = make([][]token, len(ayat))
+  }
+  for i, a := range ayat {
+    for c, text := range []string{a.Arabic, a.Trans} {
+      ws := tokenize(text)
+      ix.words[c][i] = ws
+      for j, w := range ws { ix.occ[c][w.form] = append(ix.occ[c][w.form], occ{i, j}) }
+    }
+  }
+  return ix
+}
+
+// Column picks the column a word is counted in: Arabic when it has Arabic
+// letters, else the translation.
+func Column(word string) string {
+  for _, r := range word {
+    if unicode.Is(unicode.Arabic, r) && unicode.IsLetter(r) { return ColArabic }
+  }
+  return ColTrans
+}
+
+// Form normalizes word the way the text is counted: Arabic without
+// diacritics or Quranic marks, translations lowercased without Latin
+// accents. It returns "" unless word is exactly one word.
+func Form(word string) string {
+  ws := tokenize(word)
+  if len(ws) != 1 { return "" }
+  return ws[0].form
+}
+
+func col(name string) (int, error) {
+  switch name {
+  case ColArabic:
+    return 0, nil
+  case ColTrans:
+    return 1, nil
+  }
+  return 0, fmt.Errorf("unknown column %q (use %s or %s)", name, ColArabic, ColTrans)
+}
+
+// Top returns the n most frequent word forms of column within s, most
+// frequent first; n <= 0 returns all of them.
+func (ix *Index) Top(column string, s Scope, n int) ([]Word, error) {
+  c, err := col(column)
+  if err != nil { return nil, err }
+  out := []Word{}
+  for form, occs := range ix.occ[c] {
+    w := ix.count(form, occs, s)
+    if w.Count > 0 { out = append(out, w) }
+  }
+  sort.Slice(out, func(i, j int) bool {
+    if out[i].Count != out[j].Count { return out[i].Count > out[j].Count }
+    return out[i].Word < out[j].Word
+  })
+  if n > 0 && n < len(out) { out = out[:n] }
+  return out, nil
+}
+
+func (ix *Index) count(form string, occs []occ, s Scope) Word {
+  w := Word{Word: form}
+  last := -1
+  for _, o := range occs {
+    if !s.has(&ix.ayat[o.ayah]) { continue }
+    w.Count++
+    if o.ayah != last { w.Ayat++; last = o.ayah }
+  }
+  return w
+}
+
+// Freq counts word (see Form) in column within s, with its distribution
+// over surahs and juz.
+func (ix *Index) Freq(column, word string, s Scope) (Freq, error) {
+  c, err := col(column)
+  if err != nil { return Freq{}, err }
+  form := Form(word)
+  if form == "" { return Freq{}, fmt.Errorf("%q is not a single word", word) }
+  occs := ix.occ[c][form]
+  f := Freq{Word: ix.count(form, occs, s), Column: column, BySurah: []Part{}, ByJuz: []Part{}}
+  for _, o := range occs {
+    a := &ix.ayat[o.ayah]
+    if !s.has(a) { continue }
+    f.BySurah = add(f.BySurah, a.Surah)
+    if a.Juz > 0 { f.ByJuz = add(f.ByJuz, a.Juz) }
+  }
+  return f, nil
+}
+
+// add counts one occurrence in part n; occurrences come in mushaf order, so
+// surah and juz numbers never decrease.
+func add(ps []Part, n int) []Part {
+  if len(ps) > 0 && ps[len(ps)-1].Number == n { ps[len(ps)-1].Count++; return ps }
+  return append(ps, Part{Number: n, Count: 1})
+}
+
+// KWIC lists up to limit occurrences of word in column within s, each with
+// width words of context on either side.
+func (ix *Index) KWIC(column, word string, s Scope, width, limit int) ([]Line, error) {
+  c, err := col(column)
+  if err != nil { return nil, err }
+  form := Form(word)
+  if form == "" { return nil, fmt.Errorf("%q is not a single word", word) }
+  out := []Line{}
+  for _, o := range ix.occ[c][form] {
+    if limit > 0 && len(out) >= limit { break }
+    a := &ix.ayat[o.ayah]
+    if !s.has(a) { continue }
+    text := a.Arabic
+    if c == 1 { text = a.Trans }
+    ws := ix.words[c][o.ayah]
+    m := ws[o.word]
+    from, to := ws[max(o.word-width, 0)].start, ws[min(o.word+width, len(ws)-1)].end
+    if width <= 0 { from, to = m.start, m.end }
+    out = append(out, Line{
+      Surah:  a.Surah,
+      Number: a.Number,
+      Left:   strings.TrimSpace(text[from:m.start]),
+      Match:  text[m.start:m.end],
+      Right:  strings.TrimSpace(text[m.end:to]),
+    })
+  }
+  return out, nil
+}
+
+// tokenize splits s into words: runs of letters and digits together with
+// their combining marks, so voweled Arabic stays whole.
+func tokenize(s string) []token {
+  var out []token
+  start := -1
+  flush := func(end int) {
+    if start < 0 { return }
+    if f := form(s[start:end]); f != "" { out = append(out, token{f, start, end}) }
+    start = -1
+  }
+  for i, r := range s {
+    if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc) {
+      if start < 0 { start = i }
+    } else {
+      flush(i)
+    }
+  }
+  flush(len(s))
+  return out
+}
+
+func form(w string) string {
+  if Column(w) == ColArabic {
+    w, _ = verify.Normalize(w, verify.NormSimple)
+    return strings.Map(func(r rune) rune {
+      if unicode.IsLetter(r) || unicode.IsDigit(r) { return r }
+      return -1
+    }, w)
+  }
+  w = search.Normalize(w)
+  if !strings.ContainsFunc(w, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) { return "" }
+  return w
+}
+
+// Cache serves an Index, loading it on first use and again after TTL so
+// data updates are picked up.
+type Cache struct {
+  d   *sqlx.DB
+  TTL time.Duration
+
+  mu sync.Mutex
+  ix *Index
+  at time.Time
+}
+
+// NewCache returns a Cache reloading every five minutes.
+func NewCache(d *sqlx.DB) *Cache { return &Cache{d: d, TTL: 5 * time.Minute} }
+
+// Index returns the current index.
+func (c *Cache) Index(ctx context.Context) (*Index, error) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  if c.ix != nil && time.Since(c.at) < c.TTL { return c.ix, nil }
+  ix, err := Load(ctx, c.d)
+  if err != nil { return nil, err }
+  c.ix, c.at = ix, time.Now()
+  return ix, nil
+}
@@ -0,0 +1,80 @@
+package concord
+
+import (
+  "context"
+  "errors"
+  "testing"
+
+  "github.com/jmoiron/sqlx"
+  _ "modernc.org/sqlite"
+
+  "github.com/foozio/quran-go/internal/db"
+)
+
+var ayat = []Ayah{
+  {1, 1, 1, "بِسْمِ ٱللَّهِ ٱلرَّحْمَٰنِ ٱلرَّحِيمِ", "In the name of Allah, the Merciful, the Compassionate."},
+  {1, 2, 1, "ٱلْحَمْدُ لِلَّهِ رَبِّ ٱلْعَٰلَمِينَ", "Praise be to Allah, Lord of the worlds."},
+  {1, 3, 1, "ٱلرَّحْمَٰنِ ٱلرَّحِيمِ", "The Merciful, the Compassionate."},
+  {2, 255, 3, "ٱللَّهُ لَآ إِلَٰهَ إِلَّا هُوَ", "Allah! There is no god but He."},
+  {112, 1, 30, "قُلْ هُوَ ٱللَّهُ أَحَدٌ", "Say: He is Allah, the One."},
+}
+
+func TestFreq(t *testing.T) {
+  ix := New(ayat)
+  f, err := ix.Freq(ColArabic, "الرحمن", Scope{})
+  if err != nil { t.Fatal(err) }
+  if f.Count != 2 || f.Ayat != 2 || len(f.BySurah) != 1 || f.BySurah[0] != (Part{1, 2}) { t.Fatalf("الرحمن: %+v", f) }
+
+  f, _ = ix.Freq(ColTrans, "ALLAH", Scope{})
+  if f.Word.Word != "allah" || f.Count != 4 || len(f.BySurah) != 3 || len(f.ByJuz) != 3 || f.ByJuz[2] != (Part{30, 1}) { t.Fatalf("allah: %+v", f) }
+  f, _ = ix.Freq(ColTrans, "allah", Scope{Surah: 1})
+  if f.Count != 2 || len(f.BySurah) != 1 { t.Fatalf("allah in surah 1: %+v", f) }
+  f, _ = ix.Freq(ColTrans, "allah", Scope{Juz: 30})
+  if f.Count != 1 || f.BySurah[0].Number != 112 { t.Fatalf("allah in juz 30: %+v", f) }
+
+  if _, err := ix.Freq(ColTrans, "two words", Scope{}); err == nil { t.Fatal("expected an error for two words") }
+  if _, err := ix.Freq("tafsir", "x", Scope{}); err == nil { t.Fatal("expected an error for an unknown column") }
+  if Column("ٱللَّهِ") != ColArabic || Column("Allah") != ColTrans { t.Fatal("column detection") }
+}
+
+func TestTop(t *testing.T) {
+  ix := New(ayat)
+  top, err := ix.Top(ColTrans, Scope{}, 3)
+  if err != nil { t.Fatal(err) }
+  if len(top) != 3 || top[0] != (Word{"the", 7, 4}) || top[1] != (Word{"allah", 4, 4}) { t.Fatalf("top: %+v", top) }
+  top, _ = ix.Top(ColArabic, Scope{Surah: 1}, 0)
+  if top[0].Word != "الرحمن" && top[0].Word != "الرحيم" || top[0].Count != 2 { t.Fatalf("top arabic: %+v", top) }
+}
+
+func TestKWIC(t *testing.T) {
+  ix := New(ayat)
+  lines, err := ix.KWIC(ColTrans, "merciful", Scope{}, 2, 0)
+  if err != nil { t.Fatal(err) }
+  want := []Line{
+    {1, 1, "Allah, the", "Merciful", ", the Compassionate"},
+    {1, 3, "The", "Merciful", ", the Compassionate"},
+  }
+  if len(lines) != len(want) { t.Fatalf("kwic: %+v", lines) }
+  for i := range want {
+    if lines[i] != want[i] { t.Errorf("line %d: %+v, want %+v", i, lines[i], want[i]) }
+  }
+  lines, _ = ix.KWIC(ColArabic, "الله", Scope{}, 1, 1)
+  if len(lines) != 1 || lines[0].Match != "ٱللَّهِ" || lines[0].Left != "بِسْمِ" || lines[0].Right != "ٱلرَّحْمَٰنِ" { t.Fatalf("arabic kwic: %+v", lines) }
+}
+
+func TestCache(t *testing.T) {
+  ctx := context.Background()
+  d, err := sqlx.Open("sqlite", ":memory:")
+  if err != nil { t.Fatal(err) }
+  d.SetMaxOpenConns(1)
+  if err := db.Migrate(ctx, d); err != nil { t.Fatal(err) }
+  c := NewCache(d)
+  if _, err := c.Index(ctx); !errors.Is(err, ErrEmpty) { t.Fatalf("empty database: %v", err) }
+  for _, a := range ayat {
+    d.MustExec(`INSERT OR IGNORE INTO surah(number,name_ar,verses_count) VALUES(?,?,7)`, a.Surah, "s")
+    d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,trans) VALUES(?,?,?,?,?)`, a.Surah, a.Number, a.Juz, a.Arabic, a.Trans)
+  }
+  ix, err := c.Index(ctx)
+  if err != nil { t.Fatal(err) }
+  if f, _ := ix.Freq(ColTrans, "lord", Scope{}); f.Count != 1 { t.Fatalf("lord: %+v", f) }
+}
@@ -0,0 +1,104 @@
+package data_test
+
+import (
+  "context"
+  "fmt"
+  "strings"
+  "testing"
+
+  "github.com/jmoiron/sqlx"
+  _ "modernc.org/sqlite"
+
+  "github.com/foozio/quran-go/internal/data"
+  "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+func setupDB(t *testing.T) *sqlx.DB {
+  t.Helper()
+  d, err := sqlx.Open("sqlite", ":memory:")
+  if err != nil { t.Fatal(err) }
+  d.SetMaxOpenConns(1)
+  if err := db.Migrate(context.Background(), d); err != nil { t.Fatal(err) }
+  return d
+}
+
+func ingest(t *testing.T, d *sqlx.DB, a data.Adapter) *data.Dataset {
+  t.Helper()
+  ds, err := a.Load(context.Background())
+  if err != nil { t.Fatal(err) }
+  if err := data.Ingest(context.Background(), d, ds, data.IngestOptions{}); err != nil { t.Fatal(err) }
+  return ds
+}
+
+func TestAdapters_NormalizeIntoSchema(t *testing.T) {
+  d := setupDB(t)
+  ingest(t, d, data.TanzilXML{Path: "testdata/quran-simple.xml"})
+  ingest(t, d, data.TanzilText{Path: "testdata/en.sahih.txt", Lang: "en"})
+  ingest(t, d, data.TranslationCSV{Path: "testdata/trans.csv", Lang: "id"})
+
+  var name string
+  var verses int
+  if err := d.Get(&name, `SELECT name_ar FROM surah WHERE number=2`); err != nil || name != "البقرة" {
+    t.Fatalf("surah name: %q %v", name, err)
+  }
+  if err := d.Get(&verses, `SELECT verses_count FROM surah WHERE number=1`); err != nil || verses != 2 {
+    t.Fatalf("verses_count: %d %v", verses, err)
+  }
+  var n int
+  if err := d.Get(&n, `SELECT COUNT(*) FROM translation`); err != nil || n != 4 {
+    t.Fatalf("translations: %d %v", n, err)
+  }
+  // the first translation ingested becomes primary and searchable
+  hits, err := db.SearchAyah(context.Background(), d, "Merciful", 10)
+  if err != nil || len(hits) != 1 { t.Fatalf("search primary translation: %v %v", hits, err) }
+
+  // quran.com data updates text and metadata without duplicating FTS rows
+  ds := ingest(t, d, data.QuranCom{Paths: []string{"testdata/qurancom.json"}})
+  if len(ds.Translations) != 1 || ds.Translations[0].Lang != "english" || ds.Translations[0].Text != "In the name of Allah, the Merciful" {
+    t.Fatalf("quran.com translation: %+v", ds.Translations)
+  }
+  var latin, place string
+  if err := d.QueryRow(`SELECT name_latin, revelation FROM surah WHERE number=1`).Scan(&latin, &place); err != nil || latin != "Al-Fatihah" || place != "Mecca" {
+    t.Fatalf("surah metadata: %q %q %v", latin, place, err)
+  }
+  if err := d.Get(&verses, `SELECT verses_count FROM surah WHERE number=1`); err != nil || verses != 7 {
+    t.Fatalf("verses_count from chapters: %d %v", verses, err)
+  }
+  var page, surah, number int
+  if err := d.QueryRow(`SELECT page, surah, number FROM mushaf_page`).Scan(&page, &surah, &number); err != nil || page != 1 || surah != 1 || number != 1 {
+    t.Fatalf("mushaf_page from page_number: %d %d:%d %v", page, surah, number, err)
+  }
+  hits, err = db.SearchAyah(context.Background(), d, "الرحمن", 10)
+  if err != nil { t.Fatal(err) }
+  for _, h := range hits {
+    if h.Surah == 1 && h.Number == 1 { t.Fatalf("stale FTS row for replaced text: %+v", hits) }
+  }
+}
+
+func TestIngest_PageStartsOnlyMoveEarlier(t *testing.T) {
+  ctx := context.Background()
+  d := setupDB(t)
+  ayah := func(n, page int) quran.Ayah { return quran.Ayah{Surah: 2, Number: n, Arabic: "x", Page: page} }
+  for _, ayat := range [][]quran.Ayah{
+    {ayah(7, 3), ayah(8, 3)},
+    {ayah(6, 3), ayah(5, 2)},
+    {ayah(9, 3), ayah(10, 0)},
+  } {
+    if err := data.Ingest(ctx, d, &data.Dataset{Ayat: ayat}, data.IngestOptions{}); err != nil { t.Fatal(err) }
+  }
+  var starts []string
+  if err := d.Select(&starts, `SELECT page || '=' || surah || ':' || number FROM mushaf_page ORDER BY page`); err != nil { t.Fatal(err) }
+  if fmt.Sprint(starts) != "[2=2:5 3=2:6]" { t.Fatalf("page starts: %v", starts) }
+}
+
+func TestTranslationCSV_RequiresLang(t *testing.T) {
+  if _, err := (data.TranslationCSV{Path: "testdata/trans.csv"}).Load(context.Background()); err == nil {
+    t.Fatalf("expected error without language")
+  }
+}
+
+func TestTranslationCSV_MissingRef(t *testing.T) {
+  _, err := (data.TranslationCSV{Path: "testdata/ref-only.csv", Lang: "id"}).Load(context.Background())
+  if err == nil || !strings.Contains(err.Error(), "ref-only.csv:3: missing reference") { t.Fatalf("expected a line 3 error, got %v", err) }
+}
@@ -0,0 +1,67 @@
+package data
+
+import (
+  "context"
+  "encoding/csv"
+  "fmt"
+  "io"
+  "os"
+  "strconv"
+  "strings"
+
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// TranslationCSV reads a translation from CSV with a header row. Columns are
+// matched by name: surah/sura/chapter, ayah/aya/verse/number (or a single
+// ref column like "2:255") and text/translation.
+type TranslationCSV struct {
+  Path string
+  Lang string
+}
+
+func (t TranslationCSV) Load(ctx context.Context) (*Dataset, error) {
+  if t.Lang == "" { return nil, fmt.Errorf("csv translation needs a language code") }
+  f, err := os.Open(t.Path)
+  if err != nil { return nil, err }
+  defer f.Close()
+  r := csv.NewReader(f)
+  r.FieldsPerRecord = -1
+  header, err := r.Read()
+  if err != nil { return nil, fmt.Errorf("%s: header: %w", t.Path, err) }
+  col := func(names ...string) int {
+    for i, h := range header {
+      h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+      for _, n := range names { if h == n { return i } }
+    }
+    return -1
+  }
+  si, ai := col("surah", "sura", "chapter"), col("ayah", "aya", "verse", "number")
+  ri, ti := col("ref", "verse_key", "key"), col("text", "translation")
+  if ti < 0 || (ri < 0 && (si < 0 || ai < 0)) {
+    return nil, fmt.Errorf("%s: need surah,ayah (or ref) and text columns", t.Path)
+  }
+
+  ds := &Dataset{Source: "csv:" + t.Path}
+  for line := 2; ; line++ {
+    rec, err := r.Read()
+    if err == io.EOF { break }
+    if err != nil { return nil, fmt.Errorf("%s:%d: %w", t.Path, line, err) }
+    var ref quran.Ref
+    if ri >= 0 && ri < len(rec) && rec[ri] != "" {
+      ref, err = quran.ParseRef(rec[ri])
+    } else if si < 0 || ai < 0 {
+      err = fmt.Errorf("missing reference")
+    } else if si < len(rec) && ai < len(rec) {
+      ref.Surah, _ = strconv.Atoi(strings.TrimSpace(rec[si]))
+      ref.Ayah, _ = strconv.Atoi(strings.TrimSpace(rec[ai]))
+      if !ref.Valid() { err = fmt.Errorf("invalid reference %s:%s", rec[si], rec[ai]) }
+    } else {
+      err = fmt.Errorf("short record")
+    }
+    if err != nil { return nil, fmt.Errorf("%s:%d: %w", t.Path, line, err) }
+    if ti >= len(rec) { return nil, fmt.Errorf("%s:%d: missing text", t.Path, line) }
+    ds.Translations = append(ds.Translations, Translation{Surah: ref.Surah, Number: ref.Ayah, Lang: t.Lang, Text: strings.TrimSpace(rec[ti])})
+  }
+  return ds, nil
+}
@@ -0,0 +1,170 @@
+package data
+
+import (
+  "context"
+  "fmt"
+  "sort"
+  "strings"
+
+  "github.com/jmoiron/sqlx"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// Translation is one translated ayah in a given language.
+type Translation struct {
+  Surah  int
+  Number int
+  Lang   string
+  Text   string
+}
+
+// Dataset is the normalized form every ingest adapter produces. Fields an
+// adapter cannot provide are left empty and keep their stored values.
+type Dataset struct {
+  Source       string
+  Surahs       []quran.SurahInfo
+  Ayat         []quran.Ayah
+  Translations []Translation
+}
+
+// Adapter reads a corpus in some external format into a Dataset.
+type Adapter interface {
+  Load(ctx context.Context) (*Dataset, error)
+}
+
+// IngestOptions controls Ingest.
+type IngestOptions struct {
+  // PrimaryLang translations are also written to ayah.trans (the column
+  // indexed for search). Empty uses meta.primary_lang.
+  PrimaryLang string
+}
+
+// Ingest writes ds into the database in one transaction. Rows are upserted
+// so the FTS triggers see proper updates, and empty incoming fields never
+// overwrite stored ones (e.g. a Tanzil text has no surah names).
+func Ingest(ctx context.Context, db *sqlx.DB, ds *Dataset, opt IngestOptions) error {
+  tx, err := db.BeginTxx(ctx, nil)
+  if err != nil { return err }
+  defer tx.Rollback()
+  if err := ingestTx(ctx, tx, ds, opt); err != nil { return err }
+  return tx.Commit()
+}
+
+func ingestTx(ctx context.Context, tx *sqlx.Tx, ds *Dataset, opt IngestOptions) error {
+  primary, err := resolvePrimary(ctx, tx, ds, opt.PrimaryLang)
+  if err != nil { return err }
+
+  for _, s := range surahsOf(ds) {
+    n := s.Number
+    if n < 1 || n > 114 { return fmt.Errorf("invalid surah number %d", n) }
+    if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO surah(number,name_ar,name_latin,revelation,verses_count) VALUES(?,?,?,?,?)
+      ON CONFLICT(number) DO UPDATE SET
+        name_ar=COALESCE(NULLIF(excluded.name_ar,''), name_ar),
+        name_latin=COALESCE(NULLIF(excluded.name_latin,''), name_latin),
+        revelation=COALESCE(NULLIF(excluded.revelation,''), revelation),
+        verses_count=excluded.verses_count`),
+      n, s.NameArabic, s.NameLatin, s.Revelation, s.VersesCount); err != nil {
+      return fmt.Errorf("surah %d: %w", n, err)
+    }
+  }
+
+  for _, a := range ds.Ayat {
+    juz := a.Juz
+    if juz == 0 { juz = quran.JuzOf(quran.Ref{Surah: a.Surah, Ayah: a.Number}) }
+    if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO ayah(surah,number,juz,arabic,tajweed,trans,audio_url) VALUES(?,?,?,?,?,?,?)
+      ON CONFLICT(surah,number) DO UPDATE SET
+        juz=excluded.juz,
+        arabic=excluded.arabic,
+        tajweed=COALESCE(NULLIF(excluded.tajweed,''), tajweed),
+        trans=COALESCE(NULLIF(excluded.trans,''), trans),
+        audio_url=COALESCE(NULLIF(excluded.audio_url,''), audio_url)`),
+      a.Surah, a.Number, juz, strings.TrimSpace(a.Arabic), a.Tajweed, a.Trans, a.Audio); err != nil {
+      return fmt.Errorf("ayah %d:%d: %w", a.Surah, a.Number, err)
+    }
+  }
+
+  if err := writePages(ctx, tx, ds.Ayat); err != nil { return err }
+
+  for _, t := range ds.Translations {
+    if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO translation(surah,number,lang,text) VALUES(?,?,?,?)
+      ON CONFLICT(surah,number,lang) DO UPDATE SET text=excluded.text`), t.Surah, t.Number, t.Lang, t.Text); err != nil {
+      return fmt.Errorf("translation %s %d:%d: %w", t.Lang, t.Surah, t.Number, err)
+    }
+    if t.Lang == primary {
+      if _, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE ayah SET trans=? WHERE surah=? AND number=? AND COALESCE(trans,'') <> ?`),
+        t.Text, t.Surah, t.Number, t.Text); err != nil {
+        return err
+      }
+    }
+  }
+
+  return recordSource(ctx, tx, ds)
+}
+
+// writePages records the first ayah of every mushaf page numbered in ayat.
+// A dataset may hold only part of a page (one surah of a page two share), so
+// a stored start is only ever moved earlier.
+func writePages(ctx context.Context, tx *sqlx.Tx, ayat []quran.Ayah) error {
+  starts := map[int]quran.Ref{}
+  for _, a := range ayat {
+    if a.Page < 1 || a.Page > quran.MushafPages { continue }
+    ref := quran.Ref{Surah: a.Surah, Ayah: a.Number}
+    if s, ok := starts[a.Page]; !ok || ref.Key() < s.Key() { starts[a.Page] = ref }
+  }
+  for page, r := range starts {
+    if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO mushaf_page(page,surah,number) VALUES(?,?,?)
+      ON CONFLICT(page) DO UPDATE SET surah=excluded.surah, number=excluded.number
+      WHERE excluded.surah*1000+excluded.number < mushaf_page.surah*1000+mushaf_page.number`), page, r.Surah, r.Ayah); err != nil {
+      return fmt.Errorf("page %d: %w", page, err)
+    }
+  }
+  return nil
+}
+
+// recordSource notes in meta where the text and each translation came from.
+func recordSource(ctx context.Context, tx *sqlx.Tx, ds *Dataset) error {
+  if ds.Source == "" { return nil }
+  keys := map[string]bool{}
+  if len(ds.Ayat) > 0 { keys["source"] = true }
+  for _, t := range ds.Translations { keys["source_"+t.Lang] = true }
+  for k := range keys {
+    if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO meta(key,value) VALUES(?,?) ON CONFLICT(key) DO UPDATE SET value=excluded.value`), k, ds.Source); err != nil { return err }
+  }
+  return nil
+}
+
+// resolvePrimary returns the language mirrored into ayah.trans: explicit,
+// else meta.primary_lang, else (for a fresh database) the first translation
+// in ds, which is then recorded in meta.
+func resolvePrimary(ctx context.Context, tx *sqlx.Tx, ds *Dataset, primary string) (string, error) {
+  if primary == "" {
+    _ = tx.GetContext(ctx, &primary, `SELECT value FROM meta WHERE key='primary_lang'`)
+  }
+  if primary == "" && len(ds.Translations) > 0 {
+    // first translation into a fresh database becomes the searchable one
+    primary = ds.Translations[0].Lang
+    if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO meta(key,value) VALUES('primary_lang',?) ON CONFLICT(key) DO UPDATE SET value=excluded.value`), primary); err != nil { return "", err }
+  }
+  return primary, nil
+}
+
+// surahsOf lists the surah touched by ds, ordered by number. verses_count is
+// derived from the ayat when the source has no surah metadata.
+func surahsOf(ds *Dataset) []quran.SurahInfo {
+  surahs := map[int]quran.SurahInfo{}
+  for _, s := range ds.Surahs { surahs[s.Number] = s }
+  counts := map[int]int{}
+  for _, a := range ds.Ayat {
+    if a.Number > counts[a.Surah] { counts[a.Surah] = a.Number }
+  }
+  for n, c := range counts {
+    s := surahs[n]
+    s.Number = n
+    if s.VersesCount == 0 { s.VersesCount = c }
+    surahs[n] = s
+  }
+  out := make([]quran.SurahInfo, 0, len(surahs))
+  for _, s := range surahs { out = append(out, s) }
+  sort.Slice(out, func(i, j int) bool { return out[i].Number < out[j].Number })
+  return out
+}
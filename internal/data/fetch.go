@@ -1,50 +1,99 @@
 package data
 
 import (
+  "context"
   "encoding/json"
+  "errors"
   "fmt"
   "io"
+  "math/rand"
   "net/http"
+  "time"
 )
 
-const rawBase = "https://raw.githubusercontent.com/semarketir/quranjson/master/source"
+// BaseURL is the semarketir/quranjson source tree used by IngestAll.
+var BaseURL = "https://raw.githubusercontent.com/semarketir/quranjson/master/source"
 
-func get(path string, v any) error {
-  url := fmt.Sprintf("%s/%s", rawBase, path)
-  resp, err := http.Get(url)
+// Client is used for all upstream requests.
+var Client = &http.Client{Timeout: 30 * time.Second}
+
+// Retry policy for upstream requests: network errors, 429 and 5xx are
+// retried with exponential backoff and jitter; other statuses fail fast.
+var (
+  MaxAttempts = 4
+  BaseBackoff = 500 * time.Millisecond
+)
+
+// HTTPError is a non-2xx upstream response.
+type HTTPError struct {
+  URL    string
+  Status int
+  Body   string
+}
+
+func (e *HTTPError) Error() string {
+  return fmt.Sprintf("GET %s: %d %s (%s)", e.URL, e.Status, http.StatusText(e.Status), e.Body)
+}
+
+func (e *HTTPError) retryable() bool {
+  return e.Status == http.StatusTooManyRequests || e.Status >= 500
+}
+
+func get(ctx context.Context, path string, v any) error {
+  url := fmt.Sprintf("%s/%s", BaseURL, path)
+  var err error
+  for attempt := 1; attempt <= MaxAttempts; attempt++ {
+    if err = getOnce(ctx, url, v); err == nil { return nil }
+    var he *HTTPError
+    if ctx.Err() != nil || (errors.As(err, &he) && !he.retryable()) || attempt == MaxAttempts { break }
+    wait := BaseBackoff << (attempt - 1)
+    wait += time.Duration(rand.Int63n(int64(wait) / 2 + 1))
+    select {
+    case <-ctx.Done():
+      return ctx.Err()
+    case <-time.After(wait):
+    }
+  }
+  return err
+}
+
+func getOnce(ctx context.Context, url string, v any) error {
+  req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+  if err != nil { return err }
+  resp, err := Client.Do(req)
   if err != nil { return err }
   defer resp.Body.Close()
   if resp.StatusCode != 200 {
-    b, _ := io.ReadAll(resp.Body)
-    return fmt.Errorf("GET %s: %s (%s)", url, resp.Status, string(b))
+    b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+    return &HTTPError{URL: url, Status: resp.StatusCode, Body: string(b)}
   }
   return json.NewDecoder(resp.Body).Decode(v)
 }
 
-func FetchSurahIndex() ([]map[string]any, error) {
+func FetchSurahIndex(ctx context.Context) ([]map[string]any, error) {
   var out []map[string]any
-  err := get("surah.json", &out)
+  err := get(ctx, "surah.json", &out)
   return out, err
 }
 
-func FetchArabicSurah(n int) (map[string]any, error) {
+func FetchArabicSurah(ctx context.Context, n int) (map[string]any, error) {
   var out map[string]any
-  err := get(fmt.Sprintf("surah/surah_%d.json", n), &out)
+  err := get(ctx, fmt.Sprintf("surah/surah_%d.json", n), &out)
   return out, err
 }
 
-func FetchTajweed(n int) (map[string]any, error) {
+func FetchTajweed(ctx context.Context, n int) (map[string]any, error) {
   var out map[string]any
-  err := get(fmt.Sprintf("tajweed/surah_%d.json", n), &out)
+  err := get(ctx, fmt.Sprintf("tajweed/surah_%d.json", n), &out)
   return out, err
 }
 
-func FetchTranslation(lang string, n int) (map[string]any, error) {
+func FetchTranslation(ctx context.Context, lang string, n int) (map[string]any, error) {
   var out map[string]any
-  err := get(fmt.Sprintf("translation/%s/%s_translation_%d.json", lang, lang, n), &out)
+  err := get(ctx, fmt.Sprintf("translation/%s/%s_translation_%d.json", lang, lang, n), &out)
   return out, err
 }
 
 func AudioURL(surah, ayah int) string {
-  return fmt.Sprintf("%s/audio/%03d/%03d.mp3", rawBase, surah, ayah)
+  return fmt.Sprintf("%s/audio/%03d/%03d.mp3", BaseURL, surah, ayah)
 }
@@ -5,54 +5,216 @@ import (
   "fmt"
   "strconv"
   "strings"
+  "sync"
 
   "github.com/jmoiron/sqlx"
+  "golang.org/x/sync/errgroup"
+
+  "github.com/foozio/quran-go/pkg/quran"
 )
 
-func IngestAll(ctx context.Context, db *sqlx.DB, lang string) error {
-  idx, err := FetchSurahIndex(); if err != nil { return err }
-  tx := db.MustBegin()
+// IngestConfig tunes IngestAll; the zero value is usable.
+type IngestConfig struct {
+  Workers  int            // concurrent surah downloads (default 4)
+  Force    bool           // ignore checkpoints and ingest everything again
+  Progress func(Progress) // called from a single goroutine after each surah
+}
+
+// Progress reports how far IngestAll has got.
+type Progress struct {
+  Done    int // surah completed, including ones skipped via checkpoint
+  Total   int
+  Surah   int
+  Skipped bool // already ingested by an earlier run
+}
+
+// IngestAll downloads the semarketir/quranjson dataset with lang as the
+// primary translation. Surahs are fetched concurrently with retries and
+// written one transaction per surah together with a checkpoint, so a rerun
+// after a failure or cancellation resumes where the previous one stopped.
+func IngestAll(ctx context.Context, db *sqlx.DB, lang string, cfg IngestConfig) error {
+  workers := cfg.Workers
+  if workers <= 0 { workers = 4 }
+  report := cfg.Progress
+  if report == nil { report = func(Progress) {} }
+
+  if cfg.Force {
+    if _, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM ingest_checkpoint WHERE source=? AND lang=?`), BaseURL, lang); err != nil { return err }
+  }
+  var doneList []int
+  if err := db.SelectContext(ctx, &doneList, db.Rebind(`SELECT surah FROM ingest_checkpoint WHERE source=? AND lang=?`), BaseURL, lang); err != nil { return err }
+  done := map[int]bool{}
+  for _, s := range doneList { done[s] = true }
+
+  if !done[0] {
+    if err := ingestIndex(ctx, db, lang); err != nil { return fmt.Errorf("surah index: %w", err) }
+  }
+
+  p := Progress{Total: 114}
+  var todo []int
+  for s := 1; s <= 114; s++ {
+    if done[s] {
+      p.Done++
+      report(Progress{Done: p.Done, Total: p.Total, Surah: s, Skipped: true})
+      continue
+    }
+    todo = append(todo, s)
+  }
+
+  return fetchSurahs(ctx, lang, todo, workers, func(ctx context.Context, ds *Dataset) error {
+    s := ds.Ayat[0].Surah
+    if err := writeSurah(ctx, db, lang, s, ds); err != nil { return fmt.Errorf("surah %d: %w", s, err) }
+    p.Done++
+    report(Progress{Done: p.Done, Total: p.Total, Surah: s})
+    return nil
+  })
+}
+
+// FetchDataset downloads the whole quranjson dataset into memory without
+// touching the database, for use with Update. Checkpoints do not apply.
+func FetchDataset(ctx context.Context, lang string, cfg IngestConfig) (*Dataset, error) {
+  workers := cfg.Workers
+  if workers <= 0 { workers = 4 }
+  report := cfg.Progress
+  if report == nil { report = func(Progress) {} }
+
+  ds, err := fetchIndex(ctx)
+  if err != nil { return nil, fmt.Errorf("surah index: %w", err) }
+  ds.Source = BaseURL
+  todo := make([]int, 114)
+  for i := range todo { todo[i] = i + 1 }
+  done := 0
+  err = fetchSurahs(ctx, lang, todo, workers, func(_ context.Context, s *Dataset) error {
+    ds.Ayat = append(ds.Ayat, s.Ayat...)
+    ds.Translations = append(ds.Translations, s.Translations...)
+    done++
+    report(Progress{Done: done, Total: len(todo), Surah: s.Ayat[0].Surah})
+    return nil
+  })
+  if err != nil { return nil, err }
+  return ds, nil
+}
+
+// fetchSurahs downloads the todo surah with a pool of workers and hands each
+// result to sink. SQLite has a single writer, so sink always runs on one
+// goroutine.
+func fetchSurahs(ctx context.Context, lang string, todo []int, workers int, sink func(context.Context, *Dataset) error) error {
+  g, gctx := errgroup.WithContext(ctx)
+  jobs := make(chan int)
+  results := make(chan *Dataset)
+  g.Go(func() error {
+    defer close(jobs)
+    for _, s := range todo {
+      select {
+      case jobs <- s:
+      case <-gctx.Done():
+        return gctx.Err()
+      }
+    }
+    return nil
+  })
+  var wg sync.WaitGroup
+  for i := 0; i < workers; i++ {
+    wg.Add(1)
+    g.Go(func() error {
+      defer wg.Done()
+      for s := range jobs {
+        ds, err := fetchSurah(gctx, lang, s)
+        if err != nil { return fmt.Errorf("surah %d: %w", s, err) }
+        select {
+        case results <- ds:
+        case <-gctx.Done():
+          return gctx.Err()
+        }
+      }
+      return nil
+    })
+  }
+  go func() { wg.Wait(); close(results) }()
+  g.Go(func() error {
+    for ds := range results {
+      if err := sink(gctx, ds); err != nil { return err }
+    }
+    return nil
+  })
+  return g.Wait()
+}
+
+func ingestIndex(ctx context.Context, db *sqlx.DB, lang string) error {
+  ds, err := fetchIndex(ctx)
+  if err != nil { return err }
+
+  tx, err := db.BeginTxx(ctx, nil)
+  if err != nil { return err }
+  defer tx.Rollback()
+  if err := ingestTx(ctx, tx, ds, IngestOptions{PrimaryLang: lang}); err != nil { return err }
+  if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO meta(key,value) VALUES('source',?),('primary_lang',?) ON CONFLICT(key) DO UPDATE SET value=excluded.value`), BaseURL, lang); err != nil { return err }
+  if err := checkpoint(ctx, tx, lang, 0); err != nil { return err }
+  return tx.Commit()
+}
+
+// fetchIndex downloads the surah list as a Dataset with only Surahs set.
+func fetchIndex(ctx context.Context) (*Dataset, error) {
+  idx, err := FetchSurahIndex(ctx)
+  if err != nil { return nil, err }
+  ds := &Dataset{}
   for _, s := range idx {
     // semarketir/quranjson structure
     // index: "001" (string), titleAr: arabic name, title: latin, place: Mecca/Medina, count: number of verses
     idxStr, _ := s["index"].(string)
-    if idxStr == "" { continue }
-    n64, _ := strconv.ParseInt(strings.TrimLeft(idxStr, "0"), 10, 32)
-    if n64 == 0 { n64 = 0 } // keep as 0 if failed (should not)
+    n, err := strconv.Atoi(strings.TrimLeft(idxStr, "0"))
+    if err != nil { return nil, fmt.Errorf("invalid surah index %q", idxStr) }
     nameAr, _ := s["titleAr"].(string)
     if nameAr == "" { nameAr, _ = s["title"].(string) }
-    nameAr = strings.TrimSpace(nameAr)
     nameLa, _ := s["title"].(string)
     place, _ := s["place"].(string)
     cnt := 0
     if v, ok := s["count"].(float64); ok { cnt = int(v) }
-    tx.MustExec(`INSERT OR REPLACE INTO surah(number,name_ar,name_latin,revelation,verses_count) VALUES(?,?,?,?,?)`,
-      int(n64), nameAr, nameLa, place, cnt)
+    ds.Surahs = append(ds.Surahs, quran.SurahInfo{Number: n, NameArabic: strings.TrimSpace(nameAr), NameLatin: nameLa, Revelation: place, VersesCount: cnt})
   }
-  if err := tx.Commit(); err != nil { return err }
+  if len(ds.Surahs) != 114 { return nil, fmt.Errorf("expected 114 surah in index, got %d", len(ds.Surahs)) }
+  return ds, nil
+}
 
-  for surah := 1; surah <= 114; surah++ {
-    ar, err := FetchArabicSurah(surah); if err != nil { return fmt.Errorf("surah %d: %w", surah, err) }
-    // tajweed currently ignored (format differs)
-    tr, _ := FetchTranslation(lang, surah)
+// fetchSurah downloads the Arabic text and translation of surah n.
+func fetchSurah(ctx context.Context, lang string, n int) (*Dataset, error) {
+  ar, err := FetchArabicSurah(ctx, n)
+  if err != nil { return nil, err }
+  // tajweed currently ignored (format differs)
+  tr, err := FetchTranslation(ctx, lang, n)
+  if err != nil { return nil, fmt.Errorf("translation %s: %w", lang, err) }
 
-    // Arabic verses live under object: verse: { verse_1: "text", ... }
-    verseAr, _ := ar["verse"].(map[string]any)
-    verseTr := map[string]any{}
-    if v, ok := tr["verse"].(map[string]any); ok { verseTr = v }
-    cnt := 0
-    if v, ok := ar["count"].(float64); ok { cnt = int(v) }
-
-    tx := db.MustBegin()
-    for i := 1; i <= cnt; i++ {
-      key := fmt.Sprintf("verse_%d", i)
-      arabic, _ := verseAr[key].(string)
-      trn, _ := verseTr[key].(string)
-      au := AudioURL(surah, i)
-      tx.MustExec(`INSERT OR REPLACE INTO ayah(surah,number,juz,arabic,tajweed,trans,audio_url)
-        VALUES(?,?,?,?,?,?,?)`, surah, i, 1, strings.TrimSpace(arabic), "", trn, au)
+  // Arabic verses live under object: verse: { verse_1: "text", ... }
+  verseAr, _ := ar["verse"].(map[string]any)
+  verseTr, _ := tr["verse"].(map[string]any)
+  cnt := 0
+  if v, ok := ar["count"].(float64); ok { cnt = int(v) }
+  if cnt == 0 { return nil, fmt.Errorf("no verses in upstream data") }
+
+  ds := &Dataset{}
+  for i := 1; i <= cnt; i++ {
+    key := fmt.Sprintf("verse_%d", i)
+    arabic, _ := verseAr[key].(string)
+    if strings.TrimSpace(arabic) == "" { return nil, fmt.Errorf("%s missing", key) }
+    trn, _ := verseTr[key].(string)
+    ds.Ayat = append(ds.Ayat, quran.Ayah{Surah: n, Number: i, Arabic: arabic, Trans: trn, Audio: AudioURL(n, i)})
+    if trn != "" {
+      ds.Translations = append(ds.Translations, Translation{Surah: n, Number: i, Lang: lang, Text: trn})
     }
-    if err := tx.Commit(); err != nil { return err }
   }
-  return nil
+  return ds, nil
+}
+
+func writeSurah(ctx context.Context, db *sqlx.DB, lang string, n int, ds *Dataset) error {
+  tx, err := db.BeginTxx(ctx, nil)
+  if err != nil { return err }
+  defer tx.Rollback()
+  if err := ingestTx(ctx, tx, ds, IngestOptions{PrimaryLang: lang}); err != nil { return err }
+  if err := checkpoint(ctx, tx, lang, n); err != nil { return err }
+  return tx.Commit()
+}
+
+func checkpoint(ctx context.Context, tx *sqlx.Tx, lang string, surah int) error {
+  _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO ingest_checkpoint(source,lang,surah) VALUES(?,?,?) ON CONFLICT(source,lang,surah) DO UPDATE SET done_at=excluded.done_at`), BaseURL, lang, surah)
+  return err
 }
@@ -0,0 +1,141 @@
+package data_test
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "net/http"
+  "net/http/httptest"
+  "regexp"
+  "strconv"
+  "sync"
+  "testing"
+  "time"
+
+  "github.com/foozio/quran-go/internal/data"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// fakeQuranJSON serves a tiny semarketir/quranjson tree. fail decides the
+// HTTP status for a surah file (0 means serve normally).
+type fakeQuranJSON struct {
+  mu    sync.Mutex
+  hits  map[string]int
+  fail  func(surah, hit int) int
+}
+
+var surahPath = regexp.MustCompile(`/(surah/surah|translation/id/id_translation)_(\d+)\.json$`)
+
+func (f *fakeQuranJSON) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  f.mu.Lock()
+  f.hits[r.URL.Path]++
+  hit := f.hits[r.URL.Path]
+  f.mu.Unlock()
+  if r.URL.Path == "/surah.json" {
+    var idx []map[string]any
+    for n := 1; n <= 114; n++ {
+      idx = append(idx, map[string]any{"index": fmt.Sprintf("%03d", n), "title": fmt.Sprintf("Surah %d", n), "titleAr": "سورة", "place": "Mecca", "count": 2})
+    }
+    _ = json.NewEncoder(w).Encode(idx)
+    return
+  }
+  m := surahPath.FindStringSubmatch(r.URL.Path)
+  if m == nil { http.NotFound(w, r); return }
+  n, _ := strconv.Atoi(m[2])
+  if f.fail != nil && m[1] == "surah/surah" {
+    if code := f.fail(n, hit); code != 0 { w.WriteHeader(code); return }
+  }
+  _ = json.NewEncoder(w).Encode(map[string]any{
+    "count": 2,
+    "verse": map[string]any{"verse_1": fmt.Sprintf("نص %d", n), "verse_2": "نص"},
+  })
+}
+
+func (f *fakeQuranJSON) count(path string) int {
+  f.mu.Lock(); defer f.mu.Unlock()
+  return f.hits[path]
+}
+
+func withServer(t *testing.T, h http.Handler) {
+  t.Helper()
+  srv := httptest.NewServer(h)
+  t.Cleanup(srv.Close)
+  base, backoff := data.BaseURL, data.BaseBackoff
+  data.BaseURL, data.BaseBackoff = srv.URL, time.Millisecond
+  t.Cleanup(func() { data.BaseURL, data.BaseBackoff = base, backoff })
+}
+
+func TestIngestAll_RetriesAndResumes(t *testing.T) {
+  broken := true
+  f := &fakeQuranJSON{hits: map[string]int{}, fail: func(surah, hit int) int {
+    if surah == 5 && hit == 1 { return http.StatusBadGateway } // transient, retried
+    if surah == 50 && broken { return http.StatusNotFound }      // permanent for this run
+    return 0
+  }}
+  withServer(t, f)
+  d := setupDB(t)
+  ctx := context.Background()
+
+  err := data.IngestAll(ctx, d, "id", data.IngestConfig{Workers: 3})
+  if err == nil { t.Fatalf("expected failure for surah 50") }
+  if f.count("/surah/surah_50.json") != 1 { t.Fatalf("404 must not be retried, got %d hits", f.count("/surah/surah_50.json")) }
+  if f.count("/surah/surah_5.json") != 2 { t.Fatalf("502 should be retried once, got %d hits", f.count("/surah/surah_5.json")) }
+
+  var doneList []int
+  if err := d.Select(&doneList, `SELECT surah FROM ingest_checkpoint WHERE surah > 0`); err != nil { t.Fatal(err) }
+  done := len(doneList)
+  if done == 0 || done >= 114 { t.Fatalf("expected partial checkpoints, got %d", done) }
+  before := map[int]int{}
+  for _, s := range doneList { before[s] = f.count(fmt.Sprintf("/surah/surah_%d.json", s)) }
+
+  broken = false
+  var last data.Progress
+  skipped := 0
+  err = data.IngestAll(ctx, d, "id", data.IngestConfig{Workers: 3, Progress: func(p data.Progress) {
+    last = p
+    if p.Skipped { skipped++ }
+  }})
+  if err != nil { t.Fatal(err) }
+  if skipped != done { t.Fatalf("expected %d resumed surah, got %d", done, skipped) }
+  if last.Done != 114 { t.Fatalf("progress did not reach 114: %+v", last) }
+  for s, n := range before {
+    if got := f.count(fmt.Sprintf("/surah/surah_%d.json", s)); got != n { t.Fatalf("surah %d re-downloaded after checkpoint", s) }
+  }
+  if f.count("/surah.json") != 1 { t.Fatalf("index should be fetched once, got %d", f.count("/surah.json")) }
+
+  var ayah, trans int
+  if err := d.Get(&ayah, `SELECT COUNT(*) FROM ayah`); err != nil || ayah != 228 { t.Fatalf("ayah rows: %d %v", ayah, err) }
+  if err := d.Get(&trans, `SELECT COUNT(*) FROM translation WHERE lang='id'`); err != nil || trans != 228 { t.Fatalf("translations: %d %v", trans, err) }
+  var juz int
+  if err := d.Get(&juz, `SELECT juz FROM ayah WHERE surah=78 AND number=1`); err != nil || juz != quran.JuzOf(quran.Ref{Surah: 78, Ayah: 1}) {
+    t.Fatalf("juz: %d %v", juz, err)
+  }
+}
+
+func TestIngestAll_Cancelled(t *testing.T) {
+  f := &fakeQuranJSON{hits: map[string]int{}}
+  withServer(t, f)
+  d := setupDB(t)
+  ctx, cancel := context.WithCancel(context.Background())
+  err := data.IngestAll(ctx, d, "id", data.IngestConfig{Workers: 2, Progress: func(p data.Progress) {
+    if p.Done == 10 { cancel() }
+  }})
+  if err == nil { t.Fatalf("expected cancellation error") }
+  var done int
+  if err := d.Get(&done, `SELECT COUNT(*) FROM ingest_checkpoint WHERE surah > 0`); err != nil { t.Fatal(err) }
+  if done < 10 || done == 114 { t.Fatalf("unexpected checkpoint count after cancel: %d", done) }
+}
+
+func TestFetchDataset_UpdateAfterIngestIsNoop(t *testing.T) {
+  withServer(t, &fakeQuranJSON{hits: map[string]int{}, fail: func(int, int) int { return 0 }})
+  d := setupDB(t)
+  ctx := context.Background()
+  if err := data.IngestAll(ctx, d, "id", data.IngestConfig{}); err != nil { t.Fatal(err) }
+
+  ds, err := data.FetchDataset(ctx, "id", data.IngestConfig{Workers: 2})
+  if err != nil { t.Fatal(err) }
+  if len(ds.Surahs) != 114 || len(ds.Ayat) != 228 { t.Fatalf("dataset: %d surah, %d ayah", len(ds.Surahs), len(ds.Ayat)) }
+  v, err := data.Update(ctx, d, ds, data.UpdateOptions{PrimaryLang: "id"})
+  if err != nil { t.Fatal(err) }
+  if !v.Empty() { t.Fatalf("unchanged upstream produced a version: %+v", v) }
+}
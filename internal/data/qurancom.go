@@ -0,0 +1,96 @@
+package data
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "os"
+  "regexp"
+  "strconv"
+  "strings"
+
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// QuranCom reads response bodies saved from the quran.com v4 API, e.g.
+// /chapters and /verses/by_chapter/N?fields=text_uthmani&translations=33.
+// Verses carry their mushaf page_number, which fills mushaf_page.
+// Each file may carry "chapters", "chapter" and/or "verses". Translations
+// are stored under Lang, or the API's language_name when Lang is empty.
+type QuranCom struct {
+  Paths []string
+  Lang  string
+}
+
+type qcChapter struct {
+  ID              int    `json:"id"`
+  RevelationPlace string `json:"revelation_place"`
+  NameSimple      string `json:"name_simple"`
+  NameArabic      string `json:"name_arabic"`
+  VersesCount     int    `json:"verses_count"`
+}
+
+type qcVerse struct {
+  VerseKey          string `json:"verse_key"`
+  JuzNumber         int    `json:"juz_number"`
+  PageNumber        int    `json:"page_number"`
+  TextUthmani       string `json:"text_uthmani"`
+  TextUthmaniSimple string `json:"text_uthmani_simple"`
+  TextImlaei        string `json:"text_imlaei"`
+  TextIndopak       string `json:"text_indopak"`
+  Translations      []struct{
+    ResourceID   int    `json:"resource_id"`
+    LanguageName string `json:"language_name"`
+    Text         string `json:"text"`
+  } `json:"translations"`
+  Audio *struct{ URL string `json:"url"` } `json:"audio"`
+}
+
+type qcFile struct {
+  Chapters []qcChapter `json:"chapters"`
+  Chapter  *qcChapter  `json:"chapter"`
+  Verses   []qcVerse   `json:"verses"`
+}
+
+// footnotes in quran.com translations look like <sup foot_note=123>1</sup>
+var qcFootnote = regexp.MustCompile(`<sup[^>]*>.*?</sup>|<[^>]+>`)
+
+var revelation = map[string]string{"makkah": "Mecca", "madinah": "Medina"}
+
+func (q QuranCom) Load(ctx context.Context) (*Dataset, error) {
+  ds := &Dataset{Source: "quran.com"}
+  for _, p := range q.Paths {
+    b, err := os.ReadFile(p)
+    if err != nil { return nil, err }
+    var f qcFile
+    if err := json.Unmarshal(b, &f); err != nil { return nil, fmt.Errorf("%s: %w", p, err) }
+    if f.Chapter != nil { f.Chapters = append(f.Chapters, *f.Chapter) }
+    for _, c := range f.Chapters {
+      ds.Surahs = append(ds.Surahs, quran.SurahInfo{
+        Number: c.ID, NameArabic: c.NameArabic, NameLatin: c.NameSimple,
+        VersesCount: c.VersesCount, Revelation: revelation[strings.ToLower(c.RevelationPlace)],
+      })
+    }
+    for _, v := range f.Verses {
+      ref, err := quran.ParseRef(v.VerseKey)
+      if err != nil { return nil, fmt.Errorf("%s: %w", p, err) }
+      text := firstNonEmpty(v.TextUthmani, v.TextImlaei, v.TextIndopak, v.TextUthmaniSimple)
+      if text != "" {
+        a := quran.Ayah{Surah: ref.Surah, Number: ref.Ayah, Arabic: text, Juz: v.JuzNumber, Page: v.PageNumber}
+        if v.Audio != nil { a.Audio = v.Audio.URL }
+        ds.Ayat = append(ds.Ayat, a)
+      }
+      for _, t := range v.Translations {
+        lang := firstNonEmpty(q.Lang, strings.ToLower(t.LanguageName), "qc"+strconv.Itoa(t.ResourceID))
+        txt := strings.TrimSpace(qcFootnote.ReplaceAllString(t.Text, ""))
+        ds.Translations = append(ds.Translations, Translation{Surah: ref.Surah, Number: ref.Ayah, Lang: lang, Text: txt})
+      }
+    }
+  }
+  return ds, nil
+}
+
+func firstNonEmpty(ss ...string) string {
+  for _, s := range ss { if s != "" { return s } }
+  return ""
+}
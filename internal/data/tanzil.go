@@ -0,0 +1,100 @@
+package data
+
+import (
+  "bufio"
+  "context"
+  "encoding/xml"
+  "fmt"
+  "io"
+  "os"
+  "strconv"
+  "strings"
+
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// TanzilXML reads a Tanzil XML file (quran-uthmani.xml or a translation such
+// as en.sahih.xml). With Lang set, the text is treated as a translation.
+type TanzilXML struct {
+  Path string
+  Lang string
+}
+
+type tanzilDoc struct {
+  Suras []struct{
+    Index int    `xml:"index,attr"`
+    Name  string `xml:"name,attr"`
+    Ayas  []struct{
+      Index int    `xml:"index,attr"`
+      Text  string `xml:"text,attr"`
+    } `xml:"aya"`
+  } `xml:"sura"`
+}
+
+func (t TanzilXML) Load(ctx context.Context) (*Dataset, error) {
+  f, err := os.Open(t.Path)
+  if err != nil { return nil, err }
+  defer f.Close()
+  var doc tanzilDoc
+  if err := xml.NewDecoder(f).Decode(&doc); err != nil { return nil, fmt.Errorf("%s: %w", t.Path, err) }
+  var ayat []quran.Ayah
+  var surahs []quran.SurahInfo
+  for _, s := range doc.Suras {
+    if s.Name != "" && t.Lang == "" {
+      surahs = append(surahs, quran.SurahInfo{Number: s.Index, NameArabic: s.Name, VersesCount: len(s.Ayas)})
+    }
+    for _, a := range s.Ayas { ayat = append(ayat, quran.Ayah{Surah: s.Index, Number: a.Index, Arabic: a.Text}) }
+  }
+  ds := fromAyat("tanzil:"+t.Path, t.Lang, ayat)
+  ds.Surahs = surahs
+  return ds, nil
+}
+
+// TanzilText reads Tanzil's pipe-delimited "sura|aya|text" format.
+type TanzilText struct {
+  Path string
+  Lang string
+}
+
+func (t TanzilText) Load(ctx context.Context) (*Dataset, error) {
+  f, err := os.Open(t.Path)
+  if err != nil { return nil, err }
+  defer f.Close()
+  ayat, err := ReadTanzilText(f)
+  if err != nil { return nil, fmt.Errorf("%s: %w", t.Path, err) }
+  return fromAyat("tanzil:"+t.Path, t.Lang, ayat), nil
+}
+
+// ReadTanzilText parses "sura|aya|text" lines, skipping blanks and '#' comments.
+func ReadTanzilText(r io.Reader) ([]quran.Ayah, error) {
+  var out []quran.Ayah
+  sc := bufio.NewScanner(r)
+  sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+  line := 0
+  for sc.Scan() {
+    line++
+    l := strings.TrimSpace(strings.TrimPrefix(sc.Text(), "\ufeff"))
+    if l == "" || strings.HasPrefix(l, "#") { continue }
+    parts := strings.SplitN(l, "|", 3)
+    if len(parts) != 3 { return nil, fmt.Errorf("line %d: expected sura|aya|text", line) }
+    s, err1 := strconv.Atoi(parts[0])
+    a, err2 := strconv.Atoi(parts[1])
+    if err1 != nil || err2 != nil { return nil, fmt.Errorf("line %d: invalid reference", line) }
+    out = append(out, quran.Ayah{Surah: s, Number: a, Arabic: parts[2]})
+  }
+  return out, sc.Err()
+}
+
+// fromAyat builds a Dataset from parsed lines: Arabic text when lang is
+// empty, otherwise translations in lang.
+func fromAyat(source, lang string, ayat []quran.Ayah) *Dataset {
+  ds := &Dataset{Source: source}
+  if lang == "" {
+    ds.Ayat = ayat
+    return ds
+  }
+  for _, a := range ayat {
+    ds.Translations = append(ds.Translations, Translation{Surah: a.Surah, Number: a.Number, Lang: lang, Text: strings.TrimSpace(a.Arabic)})
+  }
+  return ds
+}
@@ -0,0 +1,296 @@
+package data
+
+import (
+  "context"
+  "crypto/sha256"
+  "encoding/hex"
+  "fmt"
+  "strconv"
+  "strings"
+
+  "github.com/jmoiron/sqlx"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// Change kinds recorded in data_change.
+const (
+  ChangeSurah       = "surah"
+  ChangeAyah        = "ayah"
+  ChangeTranslation = "translation"
+)
+
+// Version is one entry of the data changelog.
+type Version struct {
+  ID           int64  `db:"id" json:"id"`
+  AppliedAt    string `db:"applied_at" json:"applied_at"`
+  Source       string `db:"source" json:"source"`
+  Note         string `db:"note" json:"note,omitempty"`
+  SurahChanged int    `db:"surah_changed" json:"surah_changed"`
+  AyahAdded    int    `db:"ayah_added" json:"ayah_added"`
+  AyahChanged  int    `db:"ayah_changed" json:"ayah_changed"`
+  TransAdded   int    `db:"trans_added" json:"trans_added"`
+  TransChanged int    `db:"trans_changed" json:"trans_changed"`
+  CorpusHash   string `db:"corpus_hash" json:"corpus_hash"`
+}
+
+// Empty reports whether the update changed nothing.
+func (v *Version) Empty() bool {
+  return v.SurahChanged+v.AyahAdded+v.AyahChanged+v.TransAdded+v.TransChanged == 0
+}
+
+// Change is a single row touched by an update. OldHash is empty for added rows.
+type Change struct {
+  Kind    string `db:"kind" json:"kind"`
+  Surah   int    `db:"surah" json:"surah"`
+  Number  int    `db:"number" json:"number,omitempty"`
+  Lang    string `db:"lang" json:"lang,omitempty"`
+  OldHash string `db:"old_hash" json:"old_hash,omitempty"`
+  NewHash string `db:"new_hash" json:"new_hash"`
+}
+
+// UpdateOptions controls Update.
+type UpdateOptions struct {
+  PrimaryLang string // as in IngestOptions
+  Note        string // free text stored with the version
+}
+
+// Update applies ds as a diff: incoming rows are merged with the stored ones
+// (empty fields keep their stored values, as with Ingest) and only rows whose
+// content hash differs are written, so the FTS index is touched for changed
+// ayah only. Everything happens in one transaction; when anything changed a
+// data_version row and its data_change rows are recorded. The returned
+// version has ID 0 and is Empty when the database was already up to date.
+func Update(ctx context.Context, db *sqlx.DB, ds *Dataset, opt UpdateOptions) (*Version, error) {
+  tx, err := db.BeginTxx(ctx, nil)
+  if err != nil { return nil, err }
+  defer tx.Rollback()
+
+  primary, err := resolvePrimary(ctx, tx, ds, opt.PrimaryLang)
+  if err != nil { return nil, err }
+  u := &updater{tx: tx, v: &Version{Source: ds.Source, Note: opt.Note}}
+  if err := u.surahs(ctx, ds); err != nil { return nil, err }
+  if err := u.ayat(ctx, ds, primary); err != nil { return nil, err }
+  if err := u.translations(ctx, ds.Translations); err != nil { return nil, err }
+  // page starts are layout, not text: they are kept but make no version
+  if err := writePages(ctx, tx, ds.Ayat); err != nil { return nil, err }
+  if u.v.Empty() { return u.v, tx.Commit() }
+
+  if err := recordSource(ctx, tx, ds); err != nil { return nil, err }
+  if u.v.CorpusHash, err = corpusHash(ctx, tx); err != nil { return nil, err }
+  if err := tx.QueryRowxContext(ctx, tx.Rebind(`INSERT INTO data_version(source,note,surah_changed,ayah_added,ayah_changed,trans_added,trans_changed,corpus_hash)
+    VALUES(?,?,?,?,?,?,?,?) RETURNING id, applied_at`), u.v.Source, u.v.Note, u.v.SurahChanged, u.v.AyahAdded, u.v.AyahChanged,
+    u.v.TransAdded, u.v.TransChanged, u.v.CorpusHash).Scan(&u.v.ID, &u.v.AppliedAt); err != nil {
+    return nil, err
+  }
+  for _, c := range u.changes {
+    if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO data_change(version_id,kind,surah,number,lang,old_hash,new_hash) VALUES(?,?,?,?,?,?,?)`),
+      u.v.ID, c.Kind, c.Surah, c.Number, c.Lang, c.OldHash, c.NewHash); err != nil {
+      return nil, err
+    }
+  }
+  return u.v, tx.Commit()
+}
+
+type updater struct {
+  tx      *sqlx.Tx
+  v       *Version
+  changes []Change
+}
+
+func (u *updater) record(c Change) { u.changes = append(u.changes, c) }
+
+func (u *updater) surahs(ctx context.Context, ds *Dataset) error {
+  var stored []struct {
+    Number      int    `db:"number"`
+    NameArabic  string `db:"name_ar"`
+    NameLatin   string `db:"name_latin"`
+    Revelation  string `db:"revelation"`
+    VersesCount int    `db:"verses_count"`
+  }
+  if err := u.tx.SelectContext(ctx, &stored, `SELECT number, name_ar, COALESCE(name_latin,'') AS name_latin,
+    COALESCE(revelation,'') AS revelation, verses_count FROM surah`); err != nil { return err }
+  old := map[int]quran.SurahInfo{}
+  for _, s := range stored {
+    old[s.Number] = quran.SurahInfo{Number: s.Number, NameArabic: s.NameArabic, NameLatin: s.NameLatin, Revelation: s.Revelation, VersesCount: s.VersesCount}
+  }
+
+  explicit := map[int]bool{}
+  for _, s := range ds.Surahs { explicit[s.Number] = s.VersesCount > 0 }
+
+  for _, s := range surahsOf(ds) {
+    if s.Number < 1 || s.Number > 114 { return fmt.Errorf("invalid surah number %d", s.Number) }
+    prev, exists := old[s.Number]
+    if exists {
+      // a count derived from a partial set of ayah must not shrink the surah
+      if !explicit[s.Number] && s.VersesCount < prev.VersesCount { s.VersesCount = prev.VersesCount }
+      s.NameArabic = keep(s.NameArabic, prev.NameArabic)
+      s.NameLatin = keep(s.NameLatin, prev.NameLatin)
+      s.Revelation = keep(s.Revelation, prev.Revelation)
+    }
+    h := hashFields(s.NameArabic, s.NameLatin, s.Revelation, strconv.Itoa(s.VersesCount))
+    c := Change{Kind: ChangeSurah, Surah: s.Number, NewHash: h}
+    if exists {
+      c.OldHash = hashFields(prev.NameArabic, prev.NameLatin, prev.Revelation, strconv.Itoa(prev.VersesCount))
+      if c.OldHash == h { continue }
+    }
+    if _, err := u.tx.ExecContext(ctx, u.tx.Rebind(`INSERT INTO surah(number,name_ar,name_latin,revelation,verses_count) VALUES(?,?,?,?,?)
+      ON CONFLICT(number) DO UPDATE SET name_ar=excluded.name_ar, name_latin=excluded.name_latin,
+        revelation=excluded.revelation, verses_count=excluded.verses_count`),
+      s.Number, s.NameArabic, s.NameLatin, s.Revelation, s.VersesCount); err != nil {
+      return fmt.Errorf("surah %d: %w", s.Number, err)
+    }
+    u.v.SurahChanged++
+    u.record(c)
+  }
+  return nil
+}
+
+type ayahRow struct {
+  Surah   int    `db:"surah"`
+  Number  int    `db:"number"`
+  Juz     int    `db:"juz"`
+  Arabic  string `db:"arabic"`
+  Tajweed string `db:"tajweed"`
+  Trans   string `db:"trans"`
+  Audio   string `db:"audio_url"`
+}
+
+func (a ayahRow) hash() string {
+  return hashFields(strconv.Itoa(a.Juz), a.Arabic, a.Tajweed, a.Trans, a.Audio)
+}
+
+// ayat diffs the incoming ayah. Primary-language translations are folded
+// into ayah.trans here so each ayah is written (and re-indexed) at most once.
+func (u *updater) ayat(ctx context.Context, ds *Dataset, primary string) error {
+  var stored []ayahRow
+  if err := u.tx.SelectContext(ctx, &stored, `SELECT surah, number, juz, arabic, COALESCE(tajweed,'') AS tajweed,
+    COALESCE(trans,'') AS trans, COALESCE(audio_url,'') AS audio_url FROM ayah`); err != nil { return err }
+  old := map[quran.Ref]ayahRow{}
+  for _, a := range stored { old[quran.Ref{Surah: a.Surah, Ayah: a.Number}] = a }
+
+  // merged rows in input order: incoming ayah first, then stored ayah that
+  // only gain a new primary translation
+  var order []quran.Ref
+  rows := map[quran.Ref]ayahRow{}
+  for _, a := range ds.Ayat {
+    ref := quran.Ref{Surah: a.Surah, Ayah: a.Number}
+    r := ayahRow{a.Surah, a.Number, a.Juz, strings.TrimSpace(a.Arabic), a.Tajweed, a.Trans, a.Audio}
+    if r.Juz == 0 { r.Juz = quran.JuzOf(ref) }
+    if prev, ok := old[ref]; ok {
+      r.Tajweed = keep(r.Tajweed, prev.Tajweed)
+      r.Trans = keep(r.Trans, prev.Trans)
+      r.Audio = keep(r.Audio, prev.Audio)
+    }
+    if _, seen := rows[ref]; !seen { order = append(order, ref) }
+    rows[ref] = r
+  }
+  for _, t := range ds.Translations {
+    if t.Lang != primary { continue }
+    ref := quran.Ref{Surah: t.Surah, Ayah: t.Number}
+    r, ok := rows[ref]
+    if !ok {
+      if r, ok = old[ref]; !ok { continue } // translation of a missing ayah fails below on the foreign key
+      order = append(order, ref)
+    }
+    r.Trans = t.Text
+    rows[ref] = r
+  }
+
+  for _, ref := range order {
+    r := rows[ref]
+    c := Change{Kind: ChangeAyah, Surah: r.Surah, Number: r.Number, NewHash: r.hash()}
+    prev, exists := old[ref]
+    if exists {
+      c.OldHash = prev.hash()
+      if c.OldHash == c.NewHash { continue }
+    }
+    if _, err := u.tx.ExecContext(ctx, u.tx.Rebind(`INSERT INTO ayah(surah,number,juz,arabic,tajweed,trans,audio_url) VALUES(?,?,?,?,?,?,?)
+      ON CONFLICT(surah,number) DO UPDATE SET juz=excluded.juz, arabic=excluded.arabic, tajweed=excluded.tajweed,
+        trans=excluded.trans, audio_url=excluded.audio_url`),
+      r.Surah, r.Number, r.Juz, r.Arabic, r.Tajweed, r.Trans, r.Audio); err != nil {
+      return fmt.Errorf("ayah %s: %w", ref, err)
+    }
+    if exists { u.v.AyahChanged++ } else { u.v.AyahAdded++ }
+    u.record(c)
+  }
+  return nil
+}
+
+func (u *updater) translations(ctx context.Context, in []Translation) error {
+  old := map[string]map[quran.Ref]string{}
+  for _, t := range in {
+    if _, ok := old[t.Lang]; ok { continue }
+    var stored []Translation
+    if err := u.tx.SelectContext(ctx, &stored, u.tx.Rebind(`SELECT surah, number, lang, text FROM translation WHERE lang=?`), t.Lang); err != nil { return err }
+    m := map[quran.Ref]string{}
+    for _, s := range stored { m[quran.Ref{Surah: s.Surah, Ayah: s.Number}] = hashFields(s.Text) }
+    old[t.Lang] = m
+  }
+
+  for _, t := range in {
+    ref := quran.Ref{Surah: t.Surah, Ayah: t.Number}
+    c := Change{Kind: ChangeTranslation, Surah: t.Surah, Number: t.Number, Lang: t.Lang, NewHash: hashFields(t.Text)}
+    prev, exists := old[t.Lang][ref]
+    if exists {
+      c.OldHash = prev
+      if prev == c.NewHash { continue }
+    }
+    if _, err := u.tx.ExecContext(ctx, u.tx.Rebind(`INSERT INTO translation(surah,number,lang,text) VALUES(?,?,?,?)
+      ON CONFLICT(surah,number,lang) DO UPDATE SET text=excluded.text`), t.Surah, t.Number, t.Lang, t.Text); err != nil {
+      return fmt.Errorf("translation %s %s: %w", t.Lang, ref, err)
+    }
+    old[t.Lang][ref] = c.NewHash // duplicates within ds count once
+    if exists { u.v.TransChanged++ } else { u.v.TransAdded++ }
+    u.record(c)
+  }
+  return nil
+}
+
+// keep returns in unless it is empty, in which case the stored value stays.
+func keep(in, stored string) string {
+  if in == "" { return stored }
+  return in
+}
+
+// hashFields is a short sha256 over the fields, separated so that moving
+// text between fields changes the hash.
+func hashFields(fields ...string) string {
+  h := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+  return hex.EncodeToString(h[:16])
+}
+
+// corpusHash fingerprints the Arabic text of the whole database, letting two
+// installations compare what they hold.
+func corpusHash(ctx context.Context, tx *sqlx.Tx) (string, error) {
+  rows, err := tx.QueryContext(ctx, `SELECT arabic FROM ayah ORDER BY surah, number`)
+  if err != nil { return "", err }
+  defer rows.Close()
+  h := sha256.New()
+  for rows.Next() {
+    var s string
+    if err := rows.Scan(&s); err != nil { return "", err }
+    h.Write([]byte(s))
+    h.Write([]byte{'\n'})
+  }
+  if err := rows.Err(); err != nil { return "", err }
+  return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Versions lists the data changelog, newest first.
+func Versions(ctx context.Context, db *sqlx.DB, limit int) ([]Version, error) {
+  if limit <= 0 { limit = 50 }
+  out := []Version{}
+  err := db.SelectContext(ctx, &out, db.Rebind(`SELECT * FROM data_version ORDER BY id DESC LIMIT ?`), limit)
+  return out, err
+}
+
+// VersionChanges returns one version with the rows it touched. The error
+// wraps sql.ErrNoRows when id does not exist.
+func VersionChanges(ctx context.Context, db *sqlx.DB, id int64) (*Version, []Change, error) {
+  var v Version
+  if err := db.GetContext(ctx, &v, db.Rebind(`SELECT * FROM data_version WHERE id=?`), id); err != nil { return nil, nil, fmt.Errorf("version %d: %w", id, err) }
+  changes := []Change{}
+  err := db.SelectContext(ctx, &changes, db.Rebind(`SELECT kind, surah, number, lang, old_hash, new_hash FROM data_change
+    WHERE version_id=? ORDER BY CASE kind WHEN 'surah' THEN 0 WHEN 'ayah' THEN 1 ELSE 2 END, surah, number, lang`), id)
+  return &v, changes, err
+}
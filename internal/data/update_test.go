@@ -0,0 +1,82 @@
+package data_test
+
+import (
+  "context"
+  "testing"
+
+  "github.com/foozio/quran-go/internal/data"
+  "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+func TestUpdate_AppliesOnlyChanges(t *testing.T) {
+  ctx := context.Background()
+  d := setupDB(t)
+  ds := &data.Dataset{
+    Source: "test",
+    Surahs: []quran.SurahInfo{{Number: 1, NameArabic: "الفاتحة", NameLatin: "Al-Fatihah", Revelation: "Mecca", VersesCount: 7}},
+    Ayat: []quran.Ayah{
+      {Surah: 1, Number: 1, Arabic: "بسم الله الرحمن الرحيم"},
+      {Surah: 1, Number: 2, Arabic: "الحمد لله رب العالمين"},
+    },
+    Translations: []data.Translation{
+      {Surah: 1, Number: 1, Lang: "en", Text: "In the name of God"},
+      {Surah: 1, Number: 2, Lang: "en", Text: "Praise be to God"},
+    },
+  }
+  v1, err := data.Update(ctx, d, ds, data.UpdateOptions{PrimaryLang: "en", Note: "initial"})
+  if err != nil { t.Fatal(err) }
+  if v1.ID == 0 || v1.SurahChanged != 1 || v1.AyahAdded != 2 || v1.TransAdded != 2 || v1.CorpusHash == "" {
+    t.Fatalf("first update: %+v", v1)
+  }
+
+  // same data again is a no-op and records nothing
+  v, err := data.Update(ctx, d, ds, data.UpdateOptions{})
+  if err != nil { t.Fatal(err) }
+  if v.ID != 0 || !v.Empty() { t.Fatalf("expected no-op, got %+v", v) }
+
+  // one corrected translation touches one translation and one ayah (trans)
+  ds2 := &data.Dataset{Source: "test-2", Translations: []data.Translation{
+    {Surah: 1, Number: 1, Lang: "en", Text: "In the name of God"},
+    {Surah: 1, Number: 2, Lang: "en", Text: "All praise is for God"},
+  }}
+  v2, err := data.Update(ctx, d, ds2, data.UpdateOptions{Note: "fix 1:2"})
+  if err != nil { t.Fatal(err) }
+  if v2.AyahChanged != 1 || v2.TransChanged != 1 || v2.TransAdded != 0 || v2.SurahChanged != 0 {
+    t.Fatalf("second update: %+v", v2)
+  }
+  if v2.CorpusHash != v1.CorpusHash { t.Fatalf("arabic unchanged but corpus hash moved") }
+  hits, err := db.SearchAyah(ctx, d, "praise", 10)
+  if err != nil || len(hits) != 1 || hits[0].Number != 2 { t.Fatalf("search after update: %v %v", hits, err) }
+
+  vs, err := data.Versions(ctx, d, 0)
+  if err != nil { t.Fatal(err) }
+  if len(vs) != 2 || vs[0].ID != v2.ID || vs[0].Note != "fix 1:2" { t.Fatalf("versions: %+v", vs) }
+  got, changes, err := data.VersionChanges(ctx, d, v2.ID)
+  if err != nil { t.Fatal(err) }
+  if got.Source != "test-2" || len(changes) != 2 { t.Fatalf("changes: %+v %+v", got, changes) }
+  for _, c := range changes {
+    if c.Surah != 1 || c.Number != 2 || c.OldHash == "" || c.OldHash == c.NewHash { t.Fatalf("change: %+v", c) }
+  }
+  if _, _, err := data.VersionChanges(ctx, d, 99); err == nil { t.Fatalf("expected error for unknown version") }
+}
+
+func TestUpdate_KeepsStoredFields(t *testing.T) {
+  ctx := context.Background()
+  d := setupDB(t)
+  ingest(t, d, data.QuranCom{Paths: []string{"testdata/qurancom.json"}})
+
+  // a Tanzil text carries no names, audio or tajweed; with unchanged Arabic
+  // nothing must be written
+  var arabic string
+  if err := d.Get(&arabic, `SELECT arabic FROM ayah WHERE surah=1 AND number=1`); err != nil { t.Fatal(err) }
+  ds := &data.Dataset{Source: "tanzil", Ayat: []quran.Ayah{{Surah: 1, Number: 1, Arabic: arabic}}}
+  v, err := data.Update(ctx, d, ds, data.UpdateOptions{})
+  if err != nil { t.Fatal(err) }
+  if !v.Empty() { t.Fatalf("unexpected change: %+v", v) }
+  var latin string
+  var verses int
+  if err := d.QueryRow(`SELECT name_latin, verses_count FROM surah WHERE number=1`).Scan(&latin, &verses); err != nil || latin != "Al-Fatihah" || verses != 7 {
+    t.Fatalf("surah metadata lost: %q %d %v", latin, verses, err)
+  }
+}
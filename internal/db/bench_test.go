@@ -0,0 +1,142 @@
+package db_test
+
+import (
+  "context"
+  "fmt"
+  "path/filepath"
+  "sort"
+  "sync"
+  "testing"
+  "time"
+
+  "github.com/jmoiron/sqlx"
+
+  mydb "github.com/foozio/quran-go/internal/db"
+)
+
+// Benchmarks compare the old per-request ad-hoc queries on a default
+// read-write pool with the read-only pool and prepared statements the
+// servers use. Run with:
+//
+//    go test ./internal/db -run '^$' -bench . -cpu 1,4,16
+//
+// Besides ns/op each reports p50 and p99 latency of a single query.
+
+// benchDB writes a synthetic corpus the size of the mushaf (114 surahs,
+// 6236 ayah) to a file and returns its path.
+func benchDB(b *testing.B) string {
+  b.Helper()
+  path := filepath.Join(b.TempDir(), "bench.db")
+  d, err := mydb.Open(path)
+  if err != nil { b.Fatal(err) }
+  defer d.Close()
+  if err := mydb.Migrate(context.Background(), d); err != nil { b.Fatal(err) }
+  tx := d.MustBegin()
+  n := 0
+  for s := 1; s <= 114; s++ {
+    verses := 54
+    if s <= 80 { verses = 55 }
+    if s == 1 { verses = 7 }
+    tx.MustExec(`INSERT INTO surah(number,name_ar,verses_count) VALUES(?,?,?)`, s, fmt.Sprintf("سورة %d", s), verses)
+    for a := 1; a <= verses; a++ {
+      n++
+      tx.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,tajweed,trans,audio_url) VALUES(?,?,?,?,?,?,?)`,
+        s, a, 1+n/208, fmt.Sprintf("بسم الله الرحمن الرحيم آية %d من سورة %d", a, s), "",
+        fmt.Sprintf("In the name of Allah, verse %d of surah %d", a, s), "")
+    }
+  }
+  if err := tx.Commit(); err != nil { b.Fatal(err) }
+  return path
+}
+
+// load runs op in parallel and reports per-query latency percentiles.
+func load(b *testing.B, op func(ctx context.Context, i int) error) {
+  ctx := context.Background()
+  var mu sync.Mutex
+  var lat []time.Duration
+  b.ResetTimer()
+  b.RunParallel(func(pb *testing.PB) {
+    var mine []time.Duration
+    for i := 0; pb.Next(); i++ {
+      t0 := time.Now()
+      if err := op(ctx, i); err != nil { b.Error(err); return }
+      mine = append(mine, time.Since(t0))
+    }
+    mu.Lock()
+    lat = append(lat, mine...)
+    mu.Unlock()
+  })
+  b.StopTimer()
+  if len(lat) == 0 { return }
+  sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+  b.ReportMetric(float64(lat[len(lat)/2].Microseconds()), "p50-µs")
+  b.ReportMetric(float64(lat[len(lat)*99/100].Microseconds()), "p99-µs")
+}
+
+func adhoc(b *testing.B) *sqlx.DB {
+  d, err := mydb.Open(benchDB(b))
+  if err != nil { b.Fatal(err) }
+  b.Cleanup(func() { d.Close() })
+  return d
+}
+
+func prepared(b *testing.B) mydb.Store {
+  st, err := mydb.OpenStore(context.Background(), mydb.Config{Driver: mydb.DriverSQLite, DSN: benchDB(b), ReadOnly: true})
+  if err != nil { b.Fatal(err) }
+  b.Cleanup(func() { st.Close() })
+  return st
+}
+
+var searchTerms = []string{"Allah", "الرحمن", "verse", "surah"}
+
+func BenchmarkSurah(b *testing.B) {
+  b.Run("adhoc", func(b *testing.B) {
+    d := adhoc(b)
+    load(b, func(ctx context.Context, i int) error {
+      var rows []mydb.Ayah
+      return d.SelectContext(ctx, &rows, `SELECT number AS ayah, arabic, COALESCE(tajweed,'') AS tajweed,
+        COALESCE(trans,'') AS trans, COALESCE(audio_url,'') AS audio_url FROM ayah WHERE surah=? ORDER BY number`, 1+i%114)
+    })
+  })
+  b.Run("prepared", func(b *testing.B) {
+    st := prepared(b)
+    load(b, func(ctx context.Context, i int) error {
+      _, err := st.Ayat(ctx, 1+i%114)
+      return err
+    })
+  })
+}
+
+func BenchmarkSurahList(b *testing.B) {
+  b.Run("adhoc", func(b *testing.B) {
+    d := adhoc(b)
+    load(b, func(ctx context.Context, i int) error {
+      var rows []mydb.Surah
+      return d.SelectContext(ctx, &rows, `SELECT number, name_ar, name_latin, revelation, verses_count FROM surah ORDER BY number`)
+    })
+  })
+  b.Run("prepared", func(b *testing.B) {
+    st := prepared(b)
+    load(b, func(ctx context.Context, i int) error {
+      _, err := st.Surahs(ctx)
+      return err
+    })
+  })
+}
+
+func BenchmarkSearch(b *testing.B) {
+  b.Run("adhoc", func(b *testing.B) {
+    d := adhoc(b)
+    load(b, func(ctx context.Context, i int) error {
+      _, err := mydb.SearchAyah(ctx, d, searchTerms[i%len(searchTerms)], 50)
+      return err
+    })
+  })
+  b.Run("prepared", func(b *testing.B) {
+    st := prepared(b)
+    load(b, func(ctx context.Context, i int) error {
+      _, err := st.Search(ctx, searchTerms[i%len(searchTerms)], 50)
+      return err
+    })
+  })
+}
@@ -7,6 +7,8 @@ import (
 
   "github.com/jmoiron/sqlx"
   _ "modernc.org/sqlite"
+
+  "github.com/foozio/quran-go/internal/search"
 )
 
 //go:embed migrate.sql
@@ -25,31 +27,61 @@ func Migrate(ctx context.Context, db *sqlx.DB) error {
   return err
 }
 
-func SearchAyah(ctx context.Context, db *sqlx.DB, q string, limit int) ([]struct{
-  Surah int `db:"surah" json:"surah"`
-  Number int `db:"number" json:"number"`
-  Snip string `db:"snip" json:"snip"`
-}, error) {
-  q = strings.TrimSpace(q)
-  var rows []struct{
-    Surah int `db:"surah" json:"surah"`
-    Number int `db:"number" json:"number"`
-    Snip string `db:"snip" json:"snip"`
+// OpenReadOnly opens an existing SQLite database for reading only: the file
+// is opened with mode=ro and every connection has query_only set.
+func OpenReadOnly(path string) (*sqlx.DB, error) {
+  return sqlx.Open("sqlite", "file:"+path+"?mode=ro&_pragma=query_only(1)&_pragma=busy_timeout(5000)")
+}
+
+const searchSQL = `
+  SELECT ayah.surah, ayah.number, ayah.arabic, COALESCE(ayah.trans,'') AS trans
+  FROM ayah_fts JOIN ayah ON ayah_fts.rowid = ayah.rowid
+  WHERE ayah_fts MATCH ?
+  LIMIT ?`
+
+const listSQL = `
+  SELECT surah, number, arabic, COALESCE(trans,'') AS trans
+  FROM ayah ORDER BY surah, number
+  LIMIT ?`
+
+// SearchAyah runs an ad-hoc FTS5 query (see search.Query; words match
+// exactly). Servers use Store.Search, which prepares the same statements
+// once and widens words with the index vocabulary.
+func SearchAyah(ctx context.Context, db *sqlx.DB, q string, limit int) ([]Hit, error) {
+  var rows []hitRow
+  if strings.TrimSpace(q) == "" {
+    if err := db.SelectContext(ctx, &rows, listSQL, limit); err != nil { return nil, err }
+    return highlight(rows, nil), nil
   }
-  var err error
-  if q == "" {
-    err = db.SelectContext(ctx, &rows, `
-      SELECT ayah.surah, ayah.number,
-        snippet(ayah_fts, 2, '<b>','</b>','…', 10) AS snip
-      FROM ayah_fts JOIN ayah ON ayah_fts.rowid = ayah.rowid
-      LIMIT ?`, limit)
-  } else {
-    err = db.SelectContext(ctx, &rows, `
-      SELECT ayah.surah, ayah.number,
-        snippet(ayah_fts, 2, '<b>','</b>','…', 10) AS snip
-      FROM ayah_fts JOIN ayah ON ayah_fts.rowid = ayah.rowid
-      WHERE ayah_fts MATCH ?
-      LIMIT ?`, q, limit)
+  pq, err := search.Parse(q)
+  if err != nil { return nil, err }
+  if err := db.SelectContext(ctx, &rows, searchSQL, pq.FTS5(), limit); err != nil { return nil, err }
+  return highlight(rows, pq), nil
+}
+
+// hitRow is a matched ayah before highlighting.
+type hitRow struct {
+  Surah  int    `db:"surah"`
+  Number int    `db:"number"`
+  Arabic string `db:"arabic"`
+  Trans  string `db:"trans"`
+}
+
+// highlight turns rows into hits on the column with more of q's matches,
+// preferring the Arabic text on a tie; q is nil for a plain listing.
+func highlight(rows []hitRow, q *search.Query) []Hit {
+  hits := make([]Hit, 0, len(rows))
+  for _, r := range rows {
+    h := Hit{Surah: r.Surah, Number: r.Number, Column: "arabic", Text: r.Arabic, Matches: []search.Span{}}
+    if q != nil {
+      ar, tr := q.Highlight(search.ColArabic, r.Arabic), q.Highlight(search.ColTrans, r.Trans)
+      if len(ar) > 0 && len(ar) >= len(tr) {
+        h.Matches = ar
+      } else if len(tr) > 0 {
+        h.Column, h.Text, h.Matches = "trans", r.Trans, tr
+      }
+    }
+    hits = append(hits, h)
   }
-  return rows, err
+  return hits
 }
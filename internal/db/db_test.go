@@ -1,7 +1,9 @@
 package db_test
 
 import (
+  "bytes"
   "context"
+  "os"
   "testing"
 
   "github.com/jmoiron/sqlx"
@@ -47,3 +49,33 @@ func TestSearchAyah_EmptyQueryWildcard(t *testing.T) {
   if len(hits) == 0 { t.Fatalf("expected hits for wildcard search") }
 }
 
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+  ctx := context.Background()
+  d := setupDB(t)
+  d.MustExec(`INSERT INTO surah(number,name_ar,verses_count) VALUES(1,'الفاتحة',7)`)
+  d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,tajweed,trans,audio_url) VALUES(1,1,1,'بسم الله','', 'In the name of Allah', '')`)
+  d.MustExec(`INSERT INTO reading_plan(name,unit,mode,parts,start_date) VALUES('mine','juz','days',30,'2026-01-01')`)
+
+  buf := &bytes.Buffer{}
+  must(t, mydb.WriteSnapshot(ctx, d, buf))
+  dir := t.TempDir()
+  snap, err := mydb.OpenSnapshot(buf.Bytes(), dir)
+  must(t, err)
+  defer snap.Close()
+
+  hits, err := mydb.SearchAyah(ctx, snap, "Allah", 10)
+  must(t, err)
+  if len(hits) != 1 { t.Fatalf("expected 1 hit from snapshot, got %d", len(hits)) }
+  if _, err := snap.Exec(`DELETE FROM ayah`); err == nil { t.Fatalf("snapshot should be read-only") }
+  var plans int
+  must(t, snap.Get(&plans, `SELECT COUNT(*) FROM reading_plan`))
+  if plans != 0 { t.Fatalf("snapshot carries %d reading plans", plans) }
+
+  // reopening reuses the extracted file
+  again, err := mydb.OpenSnapshot(buf.Bytes(), dir)
+  must(t, err)
+  again.Close()
+  files, _ := os.ReadDir(dir)
+  if len(files) != 1 { t.Fatalf("expected one extracted file, got %d", len(files)) }
+}
@@ -0,0 +1,10 @@
+//go:build embeddb
+
+package db
+
+import _ "embed"
+
+// snapshot is produced by `make snapshot`.
+//
+//go:embed snapshot/quran.db.zst
+var snapshot []byte
@@ -0,0 +1,6 @@
+//go:build !embeddb
+
+package db
+
+// snapshot is empty unless built with -tags embeddb.
+var snapshot []byte
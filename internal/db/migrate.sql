@@ -20,9 +20,112 @@ CREATE TABLE IF NOT EXISTS ayah (
   FOREIGN KEY (surah) REFERENCES surah(number) ON DELETE CASCADE
 );
 
+-- Additional translations keyed by language code; ayah.trans holds the
+-- primary (seeded) translation that is indexed for search.
+CREATE TABLE IF NOT EXISTS translation (
+  surah INTEGER NOT NULL,
+  number INTEGER NOT NULL,
+  lang TEXT NOT NULL,
+  text TEXT NOT NULL,
+  PRIMARY KEY (surah, number, lang),
+  FOREIGN KEY (surah, number) REFERENCES ayah(surah, number) ON DELETE CASCADE
+);
+
+-- Dataset metadata (source, primary translation language, ...).
+CREATE TABLE IF NOT EXISTS meta (
+  key TEXT PRIMARY KEY,
+  value TEXT NOT NULL
+);
+
+-- Per-surah progress of data.IngestAll so interrupted seeds resume.
+-- Surah 0 marks the surah index.
+CREATE TABLE IF NOT EXISTS ingest_checkpoint (
+  source TEXT NOT NULL,
+  lang TEXT NOT NULL,
+  surah INTEGER NOT NULL,
+  done_at TEXT NOT NULL DEFAULT (datetime('now')),
+  PRIMARY KEY (source, lang, surah)
+);
+
+-- Changelog written by data.Update: one row per applied update that changed
+-- something, and one data_change row per surah/ayah/translation it touched.
+CREATE TABLE IF NOT EXISTS data_version (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  applied_at TEXT NOT NULL DEFAULT (datetime('now')),
+  source TEXT NOT NULL DEFAULT '',
+  note TEXT NOT NULL DEFAULT '',
+  surah_changed INTEGER NOT NULL DEFAULT 0,
+  ayah_added INTEGER NOT NULL DEFAULT 0,
+  ayah_changed INTEGER NOT NULL DEFAULT 0,
+  trans_added INTEGER NOT NULL DEFAULT 0,
+  trans_changed INTEGER NOT NULL DEFAULT 0,
+  corpus_hash TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS data_change (
+  version_id INTEGER NOT NULL,
+  kind TEXT NOT NULL,
+  surah INTEGER NOT NULL,
+  number INTEGER NOT NULL DEFAULT 0,
+  lang TEXT NOT NULL DEFAULT '',
+  old_hash TEXT NOT NULL DEFAULT '',
+  new_hash TEXT NOT NULL,
+  FOREIGN KEY (version_id) REFERENCES data_version(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS data_change_version ON data_change(version_id);
+
+-- Per-ayah vectors for similar-verse search (internal/similar): unit-length
+-- little-endian float32, all of one model.
+CREATE TABLE IF NOT EXISTS ayah_vector (
+  surah INTEGER NOT NULL,
+  number INTEGER NOT NULL,
+  model TEXT NOT NULL,
+  vec BLOB NOT NULL,
+  PRIMARY KEY (surah, number),
+  FOREIGN KEY (surah, number) REFERENCES ayah(surah, number) ON DELETE CASCADE
+);
+
+-- First ayah of each page of the Madani mushaf (1-604), from sources that
+-- number pages (quran.com); empty when the dataset has none.
+CREATE TABLE IF NOT EXISTS mushaf_page (
+  page INTEGER PRIMARY KEY,
+  surah INTEGER NOT NULL,
+  number INTEGER NOT NULL,
+  FOREIGN KEY (surah, number) REFERENCES ayah(surah, number) ON DELETE CASCADE
+);
+
+-- Reading plans (internal/plan): a khatm split into portions by day or
+-- participant; done_at is '' until a portion is read.
+CREATE TABLE IF NOT EXISTS reading_plan (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  unit TEXT NOT NULL,
+  mode TEXT NOT NULL,
+  parts INTEGER NOT NULL,
+  start_date TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+CREATE TABLE IF NOT EXISTS reading_plan_part (
+  plan_id INTEGER NOT NULL,
+  part INTEGER NOT NULL,
+  day TEXT NOT NULL DEFAULT '',
+  participant TEXT NOT NULL DEFAULT '',
+  from_surah INTEGER NOT NULL,
+  from_ayah INTEGER NOT NULL,
+  to_surah INTEGER NOT NULL,
+  to_ayah INTEGER NOT NULL,
+  ayat INTEGER NOT NULL,
+  done_at TEXT NOT NULL DEFAULT '',
+  PRIMARY KEY (plan_id, part),
+  FOREIGN KEY (plan_id) REFERENCES reading_plan(id) ON DELETE CASCADE
+);
+
 CREATE VIRTUAL TABLE IF NOT EXISTS ayah_fts
 USING fts5(surah, number, arabic, trans, content='ayah', content_rowid='rowid');
 
+-- Indexed words per column, for typo correction and stem variants.
+CREATE VIRTUAL TABLE IF NOT EXISTS ayah_vocab USING fts5vocab(ayah_fts, col);
+
 CREATE TRIGGER IF NOT EXISTS ayah_ai AFTER INSERT ON ayah BEGIN
   INSERT INTO ayah_fts(rowid,surah,number,arabic,trans)
   VALUES (new.rowid, new.surah, new.number, new.arabic, new.trans);
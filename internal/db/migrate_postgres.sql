@@ -0,0 +1,110 @@
+-- PostgreSQL schema; mirrors migrate.sql. Search uses the generated tsv
+-- column instead of the SQLite FTS5 table and triggers.
+
+CREATE TABLE IF NOT EXISTS surah (
+  number INTEGER PRIMARY KEY,
+  name_ar TEXT NOT NULL,
+  name_latin TEXT,
+  revelation TEXT,
+  verses_count INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ayah (
+  surah INTEGER NOT NULL,
+  number INTEGER NOT NULL,
+  juz INTEGER NOT NULL,
+  arabic TEXT NOT NULL,
+  tajweed TEXT,
+  trans TEXT,
+  audio_url TEXT,
+  tsv tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', arabic), 'A') || setweight(to_tsvector('simple', COALESCE(trans, '')), 'B')) STORED,
+  PRIMARY KEY (surah, number),
+  FOREIGN KEY (surah) REFERENCES surah(number) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS ayah_tsv ON ayah USING GIN (tsv);
+
+CREATE TABLE IF NOT EXISTS translation (
+  surah INTEGER NOT NULL,
+  number INTEGER NOT NULL,
+  lang TEXT NOT NULL,
+  text TEXT NOT NULL,
+  PRIMARY KEY (surah, number, lang),
+  FOREIGN KEY (surah, number) REFERENCES ayah(surah, number) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS meta (
+  key TEXT PRIMARY KEY,
+  value TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ingest_checkpoint (
+  source TEXT NOT NULL,
+  lang TEXT NOT NULL,
+  surah INTEGER NOT NULL,
+  done_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+  PRIMARY KEY (source, lang, surah)
+);
+
+CREATE TABLE IF NOT EXISTS data_version (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  applied_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+  source TEXT NOT NULL DEFAULT '',
+  note TEXT NOT NULL DEFAULT '',
+  surah_changed INTEGER NOT NULL DEFAULT 0,
+  ayah_added INTEGER NOT NULL DEFAULT 0,
+  ayah_changed INTEGER NOT NULL DEFAULT 0,
+  trans_added INTEGER NOT NULL DEFAULT 0,
+  trans_changed INTEGER NOT NULL DEFAULT 0,
+  corpus_hash TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS data_change (
+  version_id BIGINT NOT NULL REFERENCES data_version(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  surah INTEGER NOT NULL,
+  number INTEGER NOT NULL DEFAULT 0,
+  lang TEXT NOT NULL DEFAULT '',
+  old_hash TEXT NOT NULL DEFAULT '',
+  new_hash TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS data_change_version ON data_change(version_id);
+
+CREATE TABLE IF NOT EXISTS ayah_vector (
+  surah INTEGER NOT NULL,
+  number INTEGER NOT NULL,
+  model TEXT NOT NULL,
+  vec BYTEA NOT NULL,
+  PRIMARY KEY (surah, number),
+  FOREIGN KEY (surah, number) REFERENCES ayah(surah, number) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mushaf_page (
+  page INTEGER PRIMARY KEY,
+  surah INTEGER NOT NULL,
+  number INTEGER NOT NULL,
+  FOREIGN KEY (surah, number) REFERENCES ayah(surah, number) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS reading_plan (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  name TEXT NOT NULL,
+  unit TEXT NOT NULL,
+  mode TEXT NOT NULL,
+  parts INTEGER NOT NULL,
+  start_date TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+);
+CREATE TABLE IF NOT EXISTS reading_plan_part (
+  plan_id BIGINT NOT NULL REFERENCES reading_plan(id) ON DELETE CASCADE,
+  part INTEGER NOT NULL,
+  day TEXT NOT NULL DEFAULT '',
+  participant TEXT NOT NULL DEFAULT '',
+  from_surah INTEGER NOT NULL,
+  from_ayah INTEGER NOT NULL,
+  to_surah INTEGER NOT NULL,
+  to_ayah INTEGER NOT NULL,
+  ayat INTEGER NOT NULL,
+  done_at TEXT NOT NULL DEFAULT '',
+  PRIMARY KEY (plan_id, part)
+);
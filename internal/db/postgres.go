@@ -0,0 +1,55 @@
+package db
+
+import (
+  "context"
+  _ "embed"
+
+  "github.com/jackc/pgx/v5"
+  "github.com/jackc/pgx/v5/stdlib"
+  "github.com/jmoiron/sqlx"
+)
+
+// pgxDriver is the database/sql name registered by pgx; sqlx rebinds ? to $n for it.
+const pgxDriver = "pgx"
+
+//go:embed migrate_postgres.sql
+var postgresSchema string
+
+func openPostgres(ctx context.Context, dsn string, readOnly bool) (*sqlx.DB, error) {
+  cc, err := pgx.ParseConfig(dsn)
+  if err != nil { return nil, err }
+  if readOnly {
+    // migrate first; every later transaction on the pool is read-only
+    rw := sqlx.NewDb(stdlib.OpenDB(*cc), pgxDriver)
+    err := migratePostgres(ctx, rw)
+    rw.Close()
+    if err != nil { return nil, err }
+    cc.RuntimeParams["default_transaction_read_only"] = "on"
+  }
+  d := sqlx.NewDb(stdlib.OpenDB(*cc), pgxDriver)
+  if err := d.PingContext(ctx); err != nil { d.Close(); return nil, err }
+  if !readOnly {
+    if err := migratePostgres(ctx, d); err != nil { d.Close(); return nil, err }
+  }
+  return d, nil
+}
+
+func migratePostgres(ctx context.Context, d *sqlx.DB) error {
+  _, err := d.ExecContext(ctx, postgresSchema)
+  return err
+}
+
+// postgresQueries search a generated tsvector column (simple configuration,
+// Arabic weighted A and the translation B so queries can scope to either)
+// through a GIN index.
+var postgresQueries = queries{
+  surahs: surahsSQL,
+  ayat:   ayatSQL,
+  match: `SELECT surah, number, arabic, COALESCE(trans, '') AS trans
+    FROM ayah, to_tsquery('simple', ?) AS query
+    WHERE tsv @@ query
+    ORDER BY ts_rank(tsv, query) DESC, surah, number
+    LIMIT ?`,
+  list: listSQL,
+  vocab: `SELECT word AS term, ndoc AS docs FROM ts_stat($$SELECT to_tsvector('simple', COALESCE(trans, '')) FROM ayah$$)`,
+}
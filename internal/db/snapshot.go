@@ -0,0 +1,97 @@
+package db
+
+import (
+  "context"
+  "crypto/sha256"
+  "encoding/hex"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+
+  "github.com/jmoiron/sqlx"
+  "github.com/klauspost/compress/zstd"
+)
+
+// HasSnapshot reports whether the binary carries an embedded database
+// (built with -tags embeddb).
+func HasSnapshot() bool { return len(snapshot) > 0 }
+
+// OpenDefault opens and migrates the database at path. When path does not
+// exist and a snapshot is embedded, the snapshot is opened read-only instead,
+// so quran-cli and quran-tui work without a separately seeded quran.db.
+func OpenDefault(ctx context.Context, path string) (*sqlx.DB, error) {
+  if usesSnapshot(path) { return OpenSnapshot(snapshot, "") }
+  d, err := Open(path)
+  if err != nil { return nil, err }
+  if err := Migrate(ctx, d); err != nil { d.Close(); return nil, err }
+  return d, nil
+}
+
+func usesSnapshot(path string) bool {
+  _, err := os.Stat(path)
+  return errors.Is(err, os.ErrNotExist) && HasSnapshot()
+}
+
+// OpenSnapshot extracts a compressed snapshot into dir (default: the user
+// cache dir, or QURAN_CACHE_DIR) and opens it read-only. The file is named
+// after the snapshot's hash, so it is extracted once per build.
+func OpenSnapshot(data []byte, dir string) (*sqlx.DB, error) {
+  if dir == "" { dir = cacheDir() }
+  if err := os.MkdirAll(dir, 0o755); err != nil { return nil, err }
+  sum := sha256.Sum256(data)
+  path := filepath.Join(dir, "quran-"+hex.EncodeToString(sum[:8])+".db")
+  if _, err := os.Stat(path); err != nil {
+    if err := extract(data, path); err != nil { return nil, fmt.Errorf("extract snapshot: %w", err) }
+  }
+  return sqlx.Open("sqlite", "file:"+path+"?mode=ro&immutable=1")
+}
+
+func cacheDir() string {
+  if d := os.Getenv("QURAN_CACHE_DIR"); d != "" { return d }
+  if d, err := os.UserCacheDir(); err == nil { return filepath.Join(d, "quran-go") }
+  return filepath.Join(os.TempDir(), "quran-go")
+}
+
+// extract decompresses into a temp file and renames it into place, so a
+// concurrent or interrupted start never sees a partial database.
+func extract(data []byte, path string) error {
+  zr, err := zstd.NewReader(nil)
+  if err != nil { return err }
+  defer zr.Close()
+  raw, err := zr.DecodeAll(data, nil)
+  if err != nil { return err }
+  tmp, err := os.CreateTemp(filepath.Dir(path), ".quran-*.db")
+  if err != nil { return err }
+  defer os.Remove(tmp.Name())
+  if _, err := tmp.Write(raw); err != nil { tmp.Close(); return err }
+  if err := tmp.Close(); err != nil { return err }
+  return os.Rename(tmp.Name(), path)
+}
+
+// WriteSnapshot writes a compacted, zstd-compressed copy of d to w, suitable
+// for embedding with -tags embeddb.
+func WriteSnapshot(ctx context.Context, d *sqlx.DB, w io.Writer) error {
+  dir, err := os.MkdirTemp("", "quran-snapshot")
+  if err != nil { return err }
+  defer os.RemoveAll(dir)
+  path := filepath.Join(dir, "quran.db")
+  if _, err := d.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil { return err }
+
+  // reading plans are user data, not part of the dataset; a read-only,
+  // immutable open needs a rollback-journal database
+  c, err := sqlx.Open("sqlite", path)
+  if err != nil { return err }
+  _, err = c.ExecContext(ctx, `DELETE FROM reading_plan_part; DELETE FROM reading_plan; VACUUM; PRAGMA journal_mode=DELETE`)
+  c.Close()
+  if err != nil { return err }
+
+  f, err := os.Open(path)
+  if err != nil { return err }
+  defer f.Close()
+  zw, err := zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+  if err != nil { return err }
+  if _, err := io.Copy(zw, f); err != nil { zw.Close(); return err }
+  return zw.Close()
+}
@@ -0,0 +1,272 @@
+package db
+
+import (
+  "context"
+  "fmt"
+  "os"
+  "runtime"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+
+  "github.com/jmoiron/sqlx"
+
+  "github.com/foozio/quran-go/internal/search"
+)
+
+// Storage backends understood by OpenStore.
+const (
+  DriverSQLite   = "sqlite"
+  DriverPostgres = "postgres"
+)
+
+// Config selects a storage backend.
+type Config struct {
+  Driver string // sqlite (default) or postgres
+  DSN    string // file path for sqlite, connection URL for postgres
+
+  // ReadOnly opens a pool that cannot write (SQLite mode=ro + query_only,
+  // PostgreSQL read-only transactions); the servers use it.
+  ReadOnly bool
+  // MaxOpenConns caps the pool; 0 means unlimited, or 2×CPUs when ReadOnly.
+  MaxOpenConns int
+}
+
+// UsesSnapshot reports whether cfg opens the read-only embedded snapshot
+// rather than a database of its own.
+func (c Config) UsesSnapshot() bool {
+  return (c.Driver == "" || c.Driver == DriverSQLite) && usesSnapshot(c.DSN)
+}
+
+// ConfigFromEnv reads QURAN_DB_DRIVER, QURAN_DB_DSN and QURAN_DB_MAX_CONNS.
+// SQLite falls back to QURAN_DB_PATH and then quran.db; a postgres:// DSN
+// implies the postgres driver.
+func ConfigFromEnv() Config {
+  cfg := Config{Driver: strings.ToLower(os.Getenv("QURAN_DB_DRIVER")), DSN: os.Getenv("QURAN_DB_DSN")}
+  cfg.MaxOpenConns, _ = strconv.Atoi(os.Getenv("QURAN_DB_MAX_CONNS"))
+  if cfg.Driver == "" {
+    cfg.Driver = DriverSQLite
+    if strings.HasPrefix(cfg.DSN, "postgres://") || strings.HasPrefix(cfg.DSN, "postgresql://") { cfg.Driver = DriverPostgres }
+  }
+  if cfg.Driver == DriverSQLite && cfg.DSN == "" {
+    cfg.DSN = os.Getenv("QURAN_DB_PATH")
+    if cfg.DSN == "" { cfg.DSN = "quran.db" }
+  }
+  return cfg
+}
+
+// Surah is a row of the surah list.
+type Surah struct {
+  Number     int     `db:"number" json:"number"`
+  NameAr     string  `db:"name_ar" json:"name_ar"`
+  NameLatin  *string `db:"name_latin" json:"name_latin,omitempty"`
+  Revelation *string `db:"revelation" json:"revelation,omitempty"`
+  Verses     int     `db:"verses_count" json:"verses"`
+}
+
+// Ayah is one verse of a surah as served by the apps.
+type Ayah struct {
+  Ayah     int    `db:"ayah" json:"ayah"`
+  Arabic   string `db:"arabic" json:"arabic"`
+  Tajweed  string `db:"tajweed" json:"tajweed"`
+  Trans    string `db:"trans" json:"trans"`
+  AudioURL string `db:"audio_url" json:"audio_url"`
+}
+
+// Hit is a search result. Text is the whole ayah in Column ("arabic" or
+// "trans", whichever the query matched) and Matches are the matched ranges
+// in runes; frontends mark them up themselves, so no markup comes from the
+// data.
+type Hit struct {
+  Surah   int           `json:"surah"`
+  Number  int           `json:"number"`
+  Column  string        `json:"column"`
+  Text    string        `json:"text"`
+  Matches []search.Span `json:"matches"`
+}
+
+// Results are search hits plus, when query words look misspelt, the query
+// with the closest indexed words.
+type Results struct {
+  Hits       []Hit  `json:"hits"`
+  Suggestion string `json:"suggestion,omitempty"`
+}
+
+// Store is the storage backend behind the apps. Queries that differ between
+// engines (search, schema) live behind it; portable SQL elsewhere runs on
+// DB() with sqlx.Rebind.
+type Store interface {
+  Driver() string
+  DB() *sqlx.DB
+  Surahs(ctx context.Context) ([]Surah, error)
+  Ayat(ctx context.Context, surah int) ([]Ayah, error)
+  // Search matches q (see search.Query for the syntax) against the Arabic
+  // text and primary translation. Words also match their stem variants and,
+  // when not indexed, the nearest indexed words. Malformed queries return an
+  // error wrapping search.ErrSyntax.
+  Search(ctx context.Context, q string, limit int) (Results, error)
+  Close() error
+}
+
+// OpenStore connects to the configured backend and migrates its schema.
+// A missing SQLite file falls back to the embedded snapshot (see OpenDefault).
+// With ReadOnly the schema is migrated through a short-lived writable handle
+// and the returned pool can only read.
+func OpenStore(ctx context.Context, cfg Config) (Store, error) {
+  var d *sqlx.DB
+  var err error
+  switch cfg.Driver {
+  case "", DriverSQLite:
+    cfg.Driver = DriverSQLite
+    d, err = OpenDefault(ctx, cfg.DSN)
+    if err == nil && cfg.ReadOnly && !usesSnapshot(cfg.DSN) {
+      d.Close()
+      d, err = OpenReadOnly(cfg.DSN)
+    }
+  case DriverPostgres:
+    d, err = openPostgres(ctx, cfg.DSN, cfg.ReadOnly)
+  default:
+    return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+  }
+  if err != nil { return nil, err }
+  conns := cfg.MaxOpenConns
+  if conns == 0 && cfg.ReadOnly { conns = 2 * runtime.NumCPU() }
+  if conns > 0 {
+    // keep every connection idle-ready so prepared statements stay warm
+    d.SetMaxOpenConns(conns)
+    d.SetMaxIdleConns(conns)
+  }
+  st, err := newRepo(ctx, d, cfg.Driver)
+  if err != nil { d.Close(); return nil, err }
+  return st, nil
+}
+
+// OpenWriter opens the single-connection pool the servers write reading
+// plans through, next to their read-only one. Over the read-only embedded
+// snapshot there is nothing to write to, so it returns nil (plans then
+// answer 503).
+func OpenWriter(ctx context.Context, cfg Config) (*sqlx.DB, error) {
+  if cfg.UsesSnapshot() { return nil, nil }
+  cfg.ReadOnly, cfg.MaxOpenConns = false, 1
+  st, err := OpenStore(ctx, cfg)
+  if err != nil { return nil, err }
+  return st.DB(), nil
+}
+
+// NewStore wraps an open, migrated handle, picking the backend from its driver.
+func NewStore(ctx context.Context, d *sqlx.DB) (Store, error) {
+  if d.DriverName() == pgxDriver { return newRepo(ctx, d, DriverPostgres) }
+  return newRepo(ctx, d, DriverSQLite)
+}
+
+// queries are the backend-specific statements a repo prepares; write them
+// with ? placeholders, they are rebound per driver.
+type queries struct {
+  surahs, ayat string
+  match  string // compiled search.Query; args: expression, limit
+  list   string // empty query; args: limit
+  vocab  string // translation words as search.Term, not prepared
+}
+
+const surahsSQL = `SELECT number, name_ar, name_latin, revelation, verses_count FROM surah ORDER BY number`
+
+const ayatSQL = `SELECT number AS ayah, arabic, COALESCE(tajweed,'') AS tajweed, COALESCE(trans,'') AS trans,
+  COALESCE(audio_url,'') AS audio_url FROM ayah WHERE surah=? ORDER BY number`
+
+var sqliteQueries = queries{
+  surahs: surahsSQL,
+  ayat:   ayatSQL,
+  match:  searchSQL,
+  list:   listSQL,
+  vocab:  `SELECT term, doc AS docs FROM ayah_vocab WHERE col='trans'`,
+}
+
+// vocabTTL is how long a loaded vocabulary serves before it is reloaded to
+// pick up data updates.
+const vocabTTL = 5 * time.Minute
+
+// repo implements Store with statements prepared once per pool instead of
+// parsing the same SQL on every request.
+type repo struct {
+  driver string
+  d      *sqlx.DB
+  q      queries
+  surahs, ayat, match, list *sqlx.Stmt
+
+  mu    sync.Mutex
+  voc   *search.Vocab
+  vocAt time.Time
+}
+
+func newRepo(ctx context.Context, d *sqlx.DB, driver string) (*repo, error) {
+  q := sqliteQueries
+  if driver == DriverPostgres { q = postgresQueries }
+  r := &repo{driver: driver, d: d, q: q}
+  for _, p := range []struct {
+    dst **sqlx.Stmt
+    sql string
+  }{{&r.surahs, q.surahs}, {&r.ayat, q.ayat}, {&r.match, q.match}, {&r.list, q.list}} {
+    st, err := d.PreparexContext(ctx, d.Rebind(p.sql))
+    if err != nil { r.closeStmts(); return nil, fmt.Errorf("prepare: %w", err) }
+    *p.dst = st
+  }
+  return r, nil
+}
+
+func (r *repo) Driver() string { return r.driver }
+func (r *repo) DB() *sqlx.DB   { return r.d }
+
+func (r *repo) Close() error {
+  r.closeStmts()
+  return r.d.Close()
+}
+
+func (r *repo) closeStmts() {
+  for _, st := range []*sqlx.Stmt{r.surahs, r.ayat, r.match, r.list} {
+    if st != nil { st.Close() }
+  }
+}
+
+func (r *repo) Surahs(ctx context.Context) ([]Surah, error) {
+  out := []Surah{}
+  return out, r.surahs.SelectContext(ctx, &out)
+}
+
+func (r *repo) Ayat(ctx context.Context, surah int) ([]Ayah, error) {
+  out := []Ayah{}
+  return out, r.ayat.SelectContext(ctx, &out, surah)
+}
+
+func (r *repo) Search(ctx context.Context, q string, limit int) (Results, error) {
+  var res Results
+  var rows []hitRow
+  if strings.TrimSpace(q) == "" {
+    err := r.list.SelectContext(ctx, &rows, limit)
+    res.Hits = highlight(rows, nil)
+    return res, err
+  }
+  pq, err := search.Parse(q)
+  if err != nil { return res, err }
+  pq.Expand(r.vocab(ctx))
+  res.Suggestion = pq.Suggestion()
+  expr := pq.FTS5()
+  if r.driver == DriverPostgres { expr = pq.TSQuery() }
+  err = r.match.SelectContext(ctx, &rows, expr, limit)
+  res.Hits = highlight(rows, pq)
+  return res, err
+}
+
+// vocab returns the translation vocabulary, loading it on first use and
+// after vocabTTL. Without one (e.g. an old snapshot) words match exactly.
+func (r *repo) vocab(ctx context.Context) *search.Vocab {
+  r.mu.Lock()
+  defer r.mu.Unlock()
+  if r.voc != nil && time.Since(r.vocAt) < vocabTTL { return r.voc }
+  var lang string
+  var terms []search.Term
+  _ = r.d.GetContext(ctx, &lang, `SELECT value FROM meta WHERE key='primary_lang'`)
+  if err := r.d.SelectContext(ctx, &terms, r.q.vocab); err != nil { terms = nil }
+  r.voc, r.vocAt = search.NewVocab(lang, terms), time.Now()
+  return r.voc
+}
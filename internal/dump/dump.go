@@ -0,0 +1,182 @@
+// Package dump exports and imports the dataset tables as canonical,
+// deterministically ordered JSONL or CSV files (one file per table), so
+// datasets can be diffed between releases and seeded from curated files.
+package dump
+
+import (
+  "bufio"
+  "bytes"
+  "context"
+  "database/sql"
+  "encoding/csv"
+  "encoding/json"
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+
+  "github.com/jmoiron/sqlx"
+)
+
+// Supported file formats.
+const (
+  FormatJSONL = "jsonl"
+  FormatCSV   = "csv"
+)
+
+type column struct {
+  Name string
+  Int  bool
+}
+
+// Table describes how a dataset table is dumped: its columns in canonical
+// order and the primary key that determines row order.
+type Table struct {
+  Name string
+  Cols []column
+  Key  []string
+}
+
+// Tables lists the dumpable tables in dependency order (parents first).
+var Tables = []Table{
+  {"surah", cols("number:int", "name_ar", "name_latin", "revelation", "verses_count:int"), []string{"number"}},
+  {"ayah", cols("surah:int", "number:int", "juz:int", "arabic", "tajweed", "trans", "audio_url"), []string{"surah", "number"}},
+  {"translation", cols("surah:int", "number:int", "lang", "text"), []string{"surah", "number", "lang"}},
+  {"meta", cols("key", "value"), []string{"key"}},
+  {"mushaf_page", cols("page:int", "surah:int", "number:int"), []string{"page"}},
+}
+
+func cols(specs ...string) []column {
+  out := make([]column, len(specs))
+  for i, s := range specs {
+    name, kind, _ := strings.Cut(s, ":")
+    out[i] = column{Name: name, Int: kind == "int"}
+  }
+  return out
+}
+
+func (t Table) colNames() []string {
+  names := make([]string, len(t.Cols))
+  for i, c := range t.Cols { names[i] = c.Name }
+  return names
+}
+
+// Filename is the file a table is stored in for format.
+func (t Table) Filename(format string) string { return t.Name + "." + format }
+
+// Select returns the named tables (all when names is empty).
+func Select(names []string) ([]Table, error) {
+  if len(names) == 0 { return Tables, nil }
+  want := map[string]bool{}
+  for _, n := range names { want[strings.TrimSpace(n)] = true }
+  var out []Table
+  for _, t := range Tables {
+    if want[t.Name] { out = append(out, t); delete(want, t.Name) }
+  }
+  for n := range want { return nil, fmt.Errorf("unknown table %q", n) }
+  return out, nil
+}
+
+// Dump writes each table to dir as <table>.<format>. Rows are ordered by
+// primary key and NULLs are written as empty strings, so identical data
+// always produces byte-identical files.
+func Dump(ctx context.Context, d *sqlx.DB, dir, format string, tables []Table) error {
+  if format != FormatJSONL && format != FormatCSV { return fmt.Errorf("unsupported dump format %q", format) }
+  if err := os.MkdirAll(dir, 0o755); err != nil { return err }
+  for _, t := range tables {
+    if err := dumpFile(ctx, d, filepath.Join(dir, t.Filename(format)), format, t); err != nil {
+      return fmt.Errorf("dump %s: %w", t.Name, err)
+    }
+  }
+  return nil
+}
+
+func dumpFile(ctx context.Context, d *sqlx.DB, path, format string, t Table) error {
+  f, err := os.Create(path)
+  if err != nil { return err }
+  defer f.Close()
+  if err := DumpTable(ctx, d, f, format, t); err != nil { return err }
+  return f.Close()
+}
+
+// DumpTable writes a single table to w.
+func DumpTable(ctx context.Context, d *sqlx.DB, w io.Writer, format string, t Table) error {
+  exprs := make([]string, len(t.Cols))
+  for i, c := range t.Cols {
+    if c.Int { exprs[i] = c.Name } else { exprs[i] = "COALESCE(" + c.Name + ",'')" }
+  }
+  q := fmt.Sprintf(`SELECT %s FROM %s ORDER BY %s`, strings.Join(exprs, ", "), t.Name, strings.Join(t.Key, ", "))
+  rows, err := d.QueryContext(ctx, q)
+  if err != nil { return err }
+  defer rows.Close()
+
+  bw := bufio.NewWriter(w)
+  var cw *csv.Writer
+  if format == FormatCSV {
+    cw = csv.NewWriter(bw)
+    if err := cw.Write(t.colNames()); err != nil { return err }
+  }
+  ints := make([]sql.NullInt64, len(t.Cols))
+  strs := make([]string, len(t.Cols))
+  dest := make([]any, len(t.Cols))
+  for i, c := range t.Cols {
+    if c.Int { dest[i] = &ints[i] } else { dest[i] = &strs[i] }
+  }
+  for rows.Next() {
+    if err := rows.Scan(dest...); err != nil { return err }
+    if cw != nil {
+      rec := make([]string, len(t.Cols))
+      for i, c := range t.Cols {
+        rec[i] = strs[i]
+        if c.Int { rec[i] = formatInt(ints[i]) }
+      }
+      if err := cw.Write(rec); err != nil { return err }
+      continue
+    }
+    if err := writeObject(bw, t, ints, strs); err != nil { return err }
+  }
+  if err := rows.Err(); err != nil { return err }
+  if cw != nil {
+    cw.Flush()
+    if err := cw.Error(); err != nil { return err }
+  }
+  return bw.Flush()
+}
+
+func formatInt(v sql.NullInt64) string {
+  if !v.Valid { return "" }
+  return strconv.FormatInt(v.Int64, 10)
+}
+
+// writeObject emits one JSON object with keys in column order.
+func writeObject(w *bufio.Writer, t Table, ints []sql.NullInt64, strs []string) error {
+  w.WriteByte('{')
+  for i, c := range t.Cols {
+    if i > 0 { w.WriteByte(',') }
+    var v any = strs[i]
+    if c.Int {
+      v = nil
+      if ints[i].Valid { v = ints[i].Int64 }
+    }
+    k, err := marshal(c.Name)
+    if err != nil { return err }
+    b, err := marshal(v)
+    if err != nil { return err }
+    w.Write(k)
+    w.WriteByte(':')
+    w.Write(b)
+  }
+  _, err := w.WriteString("}\n")
+  return err
+}
+
+// marshal is json.Marshal without HTML escaping, so text stays readable in diffs.
+func marshal(v any) ([]byte, error) {
+  buf := &bytes.Buffer{}
+  enc := json.NewEncoder(buf)
+  enc.SetEscapeHTML(false)
+  if err := enc.Encode(v); err != nil { return nil, err }
+  return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
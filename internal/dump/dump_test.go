@@ -0,0 +1,77 @@
+package dump_test
+
+import (
+  "bytes"
+  "context"
+  "os"
+  "path/filepath"
+  "testing"
+
+  "github.com/jmoiron/sqlx"
+  _ "modernc.org/sqlite"
+
+  "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/internal/dump"
+)
+
+func setupDB(t *testing.T) *sqlx.DB {
+  t.Helper()
+  d, err := sqlx.Open("sqlite", ":memory:")
+  if err != nil { t.Fatal(err) }
+  d.SetMaxOpenConns(1)
+  if err := db.Migrate(context.Background(), d); err != nil { t.Fatal(err) }
+  return d
+}
+
+func seed(d *sqlx.DB) {
+  d.MustExec(`INSERT INTO surah(number,name_ar,name_latin,verses_count) VALUES(2,'البقرة',NULL,286),(1,'الفاتحة','Al-Fatihah',7)`)
+  d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,trans) VALUES(2,1,1,'الم','Alif Lam Mim'),(1,1,1,'بِسْمِ ٱللَّهِ','In the name of "Allah", <the> Merciful')`)
+  d.MustExec(`INSERT INTO translation(surah,number,lang,text) VALUES(1,1,'id','Dengan nama Allah')`)
+  d.MustExec(`INSERT INTO meta(key,value) VALUES('source','test')`)
+}
+
+func TestDumpLoad_RoundTrip(t *testing.T) {
+  ctx := context.Background()
+  for _, format := range []string{dump.FormatJSONL, dump.FormatCSV} {
+    src := setupDB(t)
+    seed(src)
+    dir := t.TempDir()
+    if err := dump.Dump(ctx, src, dir, format, dump.Tables); err != nil { t.Fatalf("%s dump: %v", format, err) }
+
+    dst := setupDB(t)
+    counts, err := dump.Load(ctx, dst, dir, dump.Tables, dump.LoadOptions{})
+    if err != nil { t.Fatalf("%s load: %v", format, err) }
+    if counts["ayah"] != 2 || counts["surah"] != 2 || counts["translation"] != 1 || counts["meta"] != 1 {
+      t.Fatalf("%s: unexpected counts %v", format, counts)
+    }
+
+    again := t.TempDir()
+    if err := dump.Dump(ctx, dst, again, format, dump.Tables); err != nil { t.Fatal(err) }
+    for _, tb := range dump.Tables {
+      a, _ := os.ReadFile(filepath.Join(dir, tb.Filename(format)))
+      b, _ := os.ReadFile(filepath.Join(again, tb.Filename(format)))
+      if !bytes.Equal(a, b) { t.Fatalf("%s %s: dump not canonical:\n%s\n---\n%s", format, tb.Name, a, b) }
+    }
+  }
+}
+
+func TestDump_CanonicalOrder(t *testing.T) {
+  d := setupDB(t)
+  seed(d)
+  var buf bytes.Buffer
+  ts, _ := dump.Select([]string{"ayah"})
+  if err := dump.DumpTable(context.Background(), d, &buf, dump.FormatJSONL, ts[0]); err != nil { t.Fatal(err) }
+  want := `{"surah":1,"number":1,"juz":1,"arabic":"بِسْمِ ٱللَّهِ","tajweed":"","trans":"In the name of \"Allah\", <the> Merciful","audio_url":""}
+{"surah":2,"number":1,"juz":1,"arabic":"الم","tajweed":"","trans":"Alif Lam Mim","audio_url":""}
+`
+  if buf.String() != want { t.Fatalf("got:\n%s\nwant:\n%s", buf.String(), want) }
+}
+
+func TestLoad_RejectsUnknownFields(t *testing.T) {
+  d := setupDB(t)
+  dir := t.TempDir()
+  if err := os.WriteFile(filepath.Join(dir, "meta.jsonl"), []byte(`{"key":"a","value":"b","extra":1}`+"\n"), 0o644); err != nil { t.Fatal(err) }
+  if _, err := dump.Load(context.Background(), d, dir, dump.Tables, dump.LoadOptions{}); err == nil {
+    t.Fatalf("expected error for unknown field")
+  }
+}
@@ -0,0 +1,168 @@
+package dump
+
+import (
+  "bufio"
+  "context"
+  "encoding/csv"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+
+  "github.com/jmoiron/sqlx"
+)
+
+// LoadOptions controls Load.
+type LoadOptions struct {
+  Format  string // jsonl or csv; empty picks whichever file exists per table
+  Replace bool   // delete existing rows of each loaded table first
+}
+
+// Load imports every table file found in dir in one transaction and returns
+// the number of rows read per table. Existing rows are upserted by primary
+// key (or removed first with Replace), so the FTS triggers stay consistent.
+func Load(ctx context.Context, d *sqlx.DB, dir string, tables []Table, opt LoadOptions) (map[string]int, error) {
+  type source struct{ t Table; path, format string }
+  var found []source
+  for _, t := range tables {
+    formats := []string{FormatJSONL, FormatCSV}
+    if opt.Format != "" { formats = []string{opt.Format} }
+    for _, f := range formats {
+      p := filepath.Join(dir, t.Filename(f))
+      if _, err := os.Stat(p); err == nil { found = append(found, source{t, p, f}); break }
+    }
+  }
+  if len(found) == 0 { return nil, fmt.Errorf("no table files found in %s", dir) }
+
+  tx, err := d.BeginTxx(ctx, nil)
+  if err != nil { return nil, err }
+  defer tx.Rollback()
+
+  if opt.Replace {
+    // children first so foreign keys never dangle
+    for i := len(found) - 1; i >= 0; i-- {
+      if _, err := tx.ExecContext(ctx, `DELETE FROM `+found[i].t.Name); err != nil { return nil, err }
+    }
+  }
+  counts := map[string]int{}
+  for _, s := range found {
+    n, err := loadFile(ctx, tx, s.path, s.format, s.t)
+    if err != nil { return nil, fmt.Errorf("load %s: %w", filepath.Base(s.path), err) }
+    counts[s.t.Name] = n
+  }
+  return counts, tx.Commit()
+}
+
+func loadFile(ctx context.Context, tx *sqlx.Tx, path, format string, t Table) (int, error) {
+  f, err := os.Open(path)
+  if err != nil { return 0, err }
+  defer f.Close()
+  return LoadTable(ctx, tx, f, format, t)
+}
+
+// LoadTable upserts rows read from r into t.
+func LoadTable(ctx context.Context, tx *sqlx.Tx, r io.Reader, format string, t Table) (int, error) {
+  stmt, err := tx.PrepareContext(ctx, tx.Rebind(upsertSQL(t)))
+  if err != nil { return 0, err }
+  defer stmt.Close()
+
+  next, err := reader(r, format, t)
+  if err != nil { return 0, err }
+  n := 0
+  for {
+    vals, err := next()
+    if errors.Is(err, io.EOF) { return n, nil }
+    if err != nil { return n, fmt.Errorf("row %d: %w", n+1, err) }
+    if _, err := stmt.ExecContext(ctx, vals...); err != nil { return n, fmt.Errorf("row %d: %w", n+1, err) }
+    n++
+  }
+}
+
+func upsertSQL(t Table) string {
+  names := t.colNames()
+  isKey := map[string]bool{}
+  for _, k := range t.Key { isKey[k] = true }
+  var set []string
+  for _, c := range names {
+    if !isKey[c] { set = append(set, c+"=excluded."+c) }
+  }
+  q := fmt.Sprintf(`INSERT INTO %s(%s) VALUES(%s) ON CONFLICT(%s)`,
+    t.Name, strings.Join(names, ","), strings.TrimSuffix(strings.Repeat("?,", len(names)), ","), strings.Join(t.Key, ","))
+  if len(set) == 0 { return q + ` DO NOTHING` }
+  return q + ` DO UPDATE SET ` + strings.Join(set, ", ")
+}
+
+// reader returns an iterator yielding row values in column order.
+func reader(r io.Reader, format string, t Table) (func() ([]any, error), error) {
+  switch format {
+  case FormatJSONL:
+    sc := bufio.NewScanner(r)
+    sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+    return func() ([]any, error) {
+      for sc.Scan() {
+        line := strings.TrimSpace(sc.Text())
+        if line == "" { continue }
+        dec := json.NewDecoder(strings.NewReader(line))
+        dec.UseNumber()
+        var obj map[string]any
+        if err := dec.Decode(&obj); err != nil { return nil, err }
+        return t.values(func(name string) (any, bool) { v, ok := obj[name]; return v, ok }, len(obj))
+      }
+      if err := sc.Err(); err != nil { return nil, err }
+      return nil, io.EOF
+    }, nil
+  case FormatCSV:
+    cr := csv.NewReader(r)
+    header, err := cr.Read()
+    if err != nil { return nil, fmt.Errorf("header: %w", err) }
+    idx := map[string]int{}
+    for i, h := range header { idx[strings.TrimSpace(h)] = i }
+    return func() ([]any, error) {
+      rec, err := cr.Read()
+      if err != nil { return nil, err }
+      return t.values(func(name string) (any, bool) {
+        i, ok := idx[name]
+        if !ok { return nil, false }
+        return rec[i], true
+      }, len(header))
+    }, nil
+  }
+  return nil, fmt.Errorf("unsupported dump format %q", format)
+}
+
+// values converts a decoded record into typed column values. Unknown fields
+// are rejected; missing ones become NULL.
+func (t Table) values(get func(string) (any, bool), fields int) ([]any, error) {
+  vals := make([]any, len(t.Cols))
+  seen := 0
+  for i, c := range t.Cols {
+    v, ok := get(c.Name)
+    if !ok { continue }
+    seen++
+    switch x := v.(type) {
+    case nil:
+    case json.Number:
+      if c.Int {
+        n, err := x.Int64()
+        if err != nil { return nil, fmt.Errorf("%s: %w", c.Name, err) }
+        vals[i] = n
+      } else {
+        vals[i] = x.String()
+      }
+    case string:
+      if !c.Int { vals[i] = x; break }
+      if x == "" { break }
+      n, err := strconv.ParseInt(x, 10, 64)
+      if err != nil { return nil, fmt.Errorf("%s: %w", c.Name, err) }
+      vals[i] = n
+    default:
+      return nil, fmt.Errorf("%s: unexpected value %v", c.Name, v)
+    }
+  }
+  if seen != fields { return nil, fmt.Errorf("unknown fields for table %s", t.Name) }
+  return vals, nil
+}
@@ -0,0 +1,88 @@
+package export
+
+import (
+  "archive/zip"
+  "crypto/sha1"
+  "fmt"
+  "html/template"
+  "io"
+  "time"
+)
+
+const xmlProlog = `<?xml version="1.0" encoding="utf-8"?>` + "\n"
+
+var epubChapter = template.Must(template.New("chapter").Funcs(template.FuncMap{"arnum": ArabicNumber}).Parse(sectionTmpl + `<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="ar" xml:lang="ar">
+<head><meta charset="utf-8"/><title>{{.NameAr}}</title><link rel="stylesheet" type="text/css" href="style.css"/></head>
+<body>{{template "section" .}}</body></html>
+`))
+
+var epubNav = template.Must(template.New("nav").Parse(`<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="en" xml:lang="en">
+<head><meta charset="utf-8"/><title>{{.Title}}</title></head>
+<body><nav epub:type="toc" id="toc"><h1>{{.Title}}</h1><ol>
+{{range .Sections}}<li><a href="s{{.Surah}}.xhtml">{{.Surah}}. {{.NameAr}}{{if .NameLatin}} — {{.NameLatin}}{{end}}</a></li>
+{{end}}</ol></nav></body></html>
+`))
+
+var epubOPF = template.Must(template.New("opf").Parse(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="bookid" xml:lang="ar">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:identifier id="bookid">{{.ID}}</dc:identifier>
+<dc:title>{{.Title}}</dc:title>
+<dc:language>ar</dc:language>
+<meta property="dcterms:modified">{{.Modified}}</meta>
+</metadata>
+<manifest>
+<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+<item id="css" href="style.css" media-type="text/css"/>
+{{range .Sections}}<item id="s{{.Surah}}" href="s{{.Surah}}.xhtml" media-type="application/xhtml+xml"/>
+{{end}}</manifest>
+<spine>
+{{range .Sections}}<itemref idref="s{{.Surah}}"/>
+{{end}}</spine>
+</package>
+`))
+
+const epubContainer = xmlProlog + `<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>
+`
+
+// WriteEPUB renders doc as an EPUB 3 book with one chapter per surah.
+func WriteEPUB(w io.Writer, doc *Document) error {
+  z := zip.NewWriter(w)
+  // The mimetype entry must come first and be stored uncompressed.
+  mw, err := z.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+  if err != nil { return err }
+  if _, err := io.WriteString(mw, "application/epub+zip"); err != nil { return err }
+
+  put := func(name string, render func(io.Writer) error) error {
+    f, err := z.Create(name)
+    if err != nil { return err }
+    return render(f)
+  }
+  text := func(s string) func(io.Writer) error {
+    return func(w io.Writer) error { _, err := io.WriteString(w, s); return err }
+  }
+  xml := func(t *template.Template, data any) func(io.Writer) error {
+    return func(w io.Writer) error {
+      if _, err := io.WriteString(w, xmlProlog); err != nil { return err }
+      return t.Execute(w, data)
+    }
+  }
+
+  if err := put("META-INF/container.xml", text(epubContainer)); err != nil { return err }
+  if err := put("OEBPS/style.css", text(css)); err != nil { return err }
+  meta := map[string]any{
+    "ID": fmt.Sprintf("urn:quran-go:%x", sha1.Sum([]byte(doc.Title+"|"+doc.Range.String()))),
+    "Title": doc.Title,
+    "Modified": time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+    "Sections": doc.Sections,
+  }
+  if err := put("OEBPS/content.opf", xml(epubOPF, meta)); err != nil { return err }
+  if err := put("OEBPS/nav.xhtml", xml(epubNav, meta)); err != nil { return err }
+  for _, s := range sectionViews(doc) {
+    if err := put(fmt.Sprintf("OEBPS/s%d.xhtml", s.Surah), xml(epubChapter, s)); err != nil { return err }
+  }
+  return z.Close()
+}
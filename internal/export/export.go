@@ -0,0 +1,189 @@
+// Package export renders a selection of ayah into shareable documents
+// (Markdown, HTML, EPUB and PDF) with right-to-left Arabic layout.
+package export
+
+import (
+  "context"
+  "fmt"
+  "io"
+  "strings"
+
+  "github.com/jmoiron/sqlx"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// Supported output formats.
+const (
+  FormatMarkdown = "md"
+  FormatHTML     = "html"
+  FormatEPUB     = "epub"
+  FormatPDF      = "pdf"
+)
+
+// Formats lists every supported format in display order.
+var Formats = []string{FormatMarkdown, FormatHTML, FormatEPUB, FormatPDF}
+
+// ContentTypes maps formats to their MIME type.
+var ContentTypes = map[string]string{
+  FormatMarkdown: "text/markdown; charset=utf-8",
+  FormatHTML:     "text/html; charset=utf-8",
+  FormatEPUB:     "application/epub+zip",
+  FormatPDF:      "application/pdf",
+}
+
+// Options controls what goes into a Document.
+type Options struct {
+  Title   string
+  Langs   []string // translation languages from the translation table; empty means the primary ayah.trans
+  NoTrans bool     // omit translations entirely
+  Numbers bool     // print verse numbers
+}
+
+type Translation struct {
+  Lang string
+  Text string
+}
+
+type Verse struct {
+  Surah  int
+  Number int
+  Arabic string
+  Trans  []Translation
+}
+
+type Section struct {
+  Surah     int
+  NameAr    string
+  NameLatin string
+  Verses    []Verse
+}
+
+// Document is a loaded selection ready to be written in any format.
+type Document struct {
+  Title    string
+  Range    quran.Range
+  Numbers  bool
+  Sections []Section
+}
+
+// Load reads every ayah in rng (plus surah names and translations) from d.
+func Load(ctx context.Context, d *sqlx.DB, rng quran.Range, opt Options) (*Document, error) {
+  doc := &Document{Title: opt.Title, Range: rng, Numbers: opt.Numbers}
+  if doc.Title == "" { doc.Title = "Al-Qur'an " + rng.String() }
+
+  type srow struct{
+    Number int `db:"number"`
+    NameAr string `db:"name_ar"`
+    NameLatin string `db:"name_latin"`
+  }
+  var surahs []srow
+  if err := d.SelectContext(ctx, &surahs, d.Rebind(`SELECT number, name_ar, COALESCE(name_latin,'') AS name_latin
+    FROM surah WHERE number BETWEEN ? AND ? ORDER BY number`), rng.From.Surah, rng.To.Surah); err != nil {
+    return nil, err
+  }
+  names := map[int]srow{}
+  for _, s := range surahs { names[s.Number] = s }
+
+  type arow struct{
+    Surah int `db:"surah"`
+    Number int `db:"number"`
+    Arabic string `db:"arabic"`
+    Trans string `db:"trans"`
+  }
+  var ayat []arow
+  if err := d.SelectContext(ctx, &ayat, d.Rebind(`SELECT surah, number, arabic, COALESCE(trans,'') AS trans
+    FROM ayah WHERE surah*1000+number BETWEEN ? AND ? ORDER BY surah, number`), rng.From.Key(), rng.To.Key()); err != nil {
+    return nil, err
+  }
+  if len(ayat) == 0 { return nil, fmt.Errorf("no ayah found for %s", rng) }
+
+  extra := map[quran.Ref][]Translation{}
+  if len(opt.Langs) > 0 && !opt.NoTrans {
+    q, args, err := sqlx.In(`SELECT surah, number, lang, text FROM translation
+      WHERE lang IN (?) AND surah*1000+number BETWEEN ? AND ?`, opt.Langs, rng.From.Key(), rng.To.Key())
+    if err != nil { return nil, err }
+    var rows []struct{
+      Surah int `db:"surah"`
+      Number int `db:"number"`
+      Lang string `db:"lang"`
+      Text string `db:"text"`
+    }
+    if err := d.SelectContext(ctx, &rows, d.Rebind(q), args...); err != nil { return nil, err }
+    byRef := map[quran.Ref]map[string]string{}
+    for _, r := range rows {
+      ref := quran.Ref{Surah: r.Surah, Ayah: r.Number}
+      if byRef[ref] == nil { byRef[ref] = map[string]string{} }
+      byRef[ref][r.Lang] = r.Text
+    }
+    // keep the caller's language order
+    for ref, m := range byRef {
+      for _, l := range opt.Langs {
+        if t, ok := m[l]; ok { extra[ref] = append(extra[ref], Translation{l, t}) }
+      }
+    }
+  }
+
+  for _, a := range ayat {
+    if n := len(doc.Sections); n == 0 || doc.Sections[n-1].Surah != a.Surah {
+      s := names[a.Surah]
+      doc.Sections = append(doc.Sections, Section{Surah: a.Surah, NameAr: s.NameAr, NameLatin: s.NameLatin})
+    }
+    v := Verse{Surah: a.Surah, Number: a.Number, Arabic: a.Arabic}
+    switch {
+    case opt.NoTrans:
+    case len(opt.Langs) > 0:
+      v.Trans = extra[quran.Ref{Surah: a.Surah, Ayah: a.Number}]
+    case strings.TrimSpace(a.Trans) != "":
+      v.Trans = []Translation{{Text: a.Trans}}
+    }
+    sec := &doc.Sections[len(doc.Sections)-1]
+    sec.Verses = append(sec.Verses, v)
+  }
+  return doc, nil
+}
+
+// Write renders doc in the given format.
+func Write(ctx context.Context, w io.Writer, format string, doc *Document) error {
+  switch format {
+  case FormatMarkdown:
+    return WriteMarkdown(w, doc)
+  case FormatHTML:
+    return WriteHTML(w, doc)
+  case FormatEPUB:
+    return WriteEPUB(w, doc)
+  case FormatPDF:
+    return WritePDF(ctx, w, doc)
+  }
+  return fmt.Errorf("unsupported export format %q", format)
+}
+
+// Filename suggests a file name for doc in format.
+func Filename(doc *Document, format string) string {
+  r := strings.NewReplacer(":", "_", " ", "")
+  return "quran-" + r.Replace(doc.Range.String()) + "." + format
+}
+
+// ArabicNumber renders n with Arabic-Indic digits inside ornate brackets, e.g. ﴿٢٥٥﴾.
+func ArabicNumber(n int) string {
+  b := &strings.Builder{}
+  b.WriteRune('﴿')
+  for _, c := range fmt.Sprint(n) { b.WriteRune('٠' + (c - '0')) }
+  b.WriteRune('﴾')
+  return b.String()
+}
+
+// SetTrans fills Langs/NoTrans from a comma-separated list such as "id,en".
+// "none" drops translations; an empty string keeps the primary translation.
+func (o *Options) SetTrans(s string) {
+  o.Langs, o.NoTrans = nil, false
+  for _, l := range strings.Split(s, ",") {
+    l = strings.TrimSpace(l)
+    switch l {
+    case "":
+    case "none":
+      o.NoTrans = true
+    default:
+      o.Langs = append(o.Langs, l)
+    }
+  }
+}
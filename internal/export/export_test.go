@@ -0,0 +1,108 @@
+package export_test
+
+import (
+  "archive/zip"
+  "bytes"
+  "context"
+  "strings"
+  "testing"
+
+  "github.com/jmoiron/sqlx"
+  _ "modernc.org/sqlite"
+
+  "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/internal/export"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+func setupDB(t *testing.T) *sqlx.DB {
+  t.Helper()
+  d, err := sqlx.Open("sqlite", ":memory:")
+  if err != nil { t.Fatal(err) }
+  d.SetMaxOpenConns(1)
+  if err := db.Migrate(context.Background(), d); err != nil { t.Fatal(err) }
+  d.MustExec(`INSERT INTO surah(number,name_ar,name_latin,verses_count) VALUES(1,'الفاتحة','Al-Fatihah',7)`)
+  d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,trans) VALUES(1,1,1,'بِسْمِ ٱللَّهِ','Dengan nama Allah')`)
+  d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,trans) VALUES(1,2,1,'ٱلْحَمْدُ لِلَّهِ','<script>alert(1)</script>')`)
+  d.MustExec(`INSERT INTO translation(surah,number,lang,text) VALUES(1,1,'en','In the name of Allah')`)
+  return d
+}
+
+func TestWriteMarkdown_EscapesSyntax(t *testing.T) {
+  doc := &export.Document{Title: "*Al-Qur'an* # 1", Sections: []export.Section{{Surah: 1, NameAr: "الفاتحة", NameLatin: "Al_Fatihah", Verses: []export.Verse{{
+    Surah: 1, Number: 1, Arabic: "بِسْمِ",
+    Trans: []export.Translation{{Text: "[click](http://x) *bold* `code` a_b <b>"}, {Text: "1. not a list\n- nor this"}},
+  }}}}}
+  var md bytes.Buffer
+  if err := export.WriteMarkdown(&md, doc); err != nil { t.Fatal(err) }
+  for _, want := range []string{
+    "# \\*Al-Qur'an\\* # 1\n",
+    "## 1. الفاتحة — Al\\_Fatihah\n",
+    "\\[click\\](http://x) \\*bold\\* \\`code\\` a\\_b &lt;b&gt;\n",
+    "1\\. not a list\n\\- nor this\n",
+  } {
+    if !strings.Contains(md.String(), want) { t.Errorf("missing %q in\n%s", want, md.String()) }
+  }
+}
+
+func TestLoad_Translations(t *testing.T) {
+  d := setupDB(t)
+  ctx := context.Background()
+  rng, _ := quran.ParseRange("1:1-2")
+
+  doc, err := export.Load(ctx, d, rng, export.Options{})
+  if err != nil { t.Fatal(err) }
+  if len(doc.Sections) != 1 || len(doc.Sections[0].Verses) != 2 {
+    t.Fatalf("unexpected document shape: %+v", doc)
+  }
+  if got := doc.Sections[0].Verses[0].Trans; len(got) != 1 || got[0].Text != "Dengan nama Allah" {
+    t.Fatalf("primary translation: %+v", got)
+  }
+
+  opt := export.Options{}
+  opt.SetTrans("en")
+  doc, err = export.Load(ctx, d, rng, opt)
+  if err != nil { t.Fatal(err) }
+  if got := doc.Sections[0].Verses[0].Trans; len(got) != 1 || got[0].Lang != "en" {
+    t.Fatalf("en translation: %+v", got)
+  }
+
+  opt.SetTrans("none")
+  doc, err = export.Load(ctx, d, rng, opt)
+  if err != nil { t.Fatal(err) }
+  if len(doc.Sections[0].Verses[0].Trans) != 0 { t.Fatalf("expected no translations") }
+}
+
+func TestWrite_Formats(t *testing.T) {
+  d := setupDB(t)
+  ctx := context.Background()
+  doc, err := export.Load(ctx, d, quran.SurahRange(1), export.Options{Numbers: true, Title: `<img src=x onerror=alert(1)>`})
+  if err != nil { t.Fatal(err) }
+
+  var md bytes.Buffer
+  if err := export.Write(ctx, &md, export.FormatMarkdown, doc); err != nil { t.Fatal(err) }
+  if !strings.Contains(md.String(), `<p dir="rtl" lang="ar">بِسْمِ ٱللَّهِ ﴿١﴾</p>`) {
+    t.Fatalf("markdown missing RTL ayah:\n%s", md.String())
+  }
+  if strings.Contains(md.String(), "<script>") || strings.Contains(md.String(), "<img") || !strings.Contains(md.String(), "&lt;script&gt;alert(1)&lt;/script&gt;") {
+    t.Fatalf("markdown export did not escape title or translation:\n%s", md.String())
+  }
+
+  var html bytes.Buffer
+  if err := export.Write(ctx, &html, export.FormatHTML, doc); err != nil { t.Fatal(err) }
+  if strings.Contains(html.String(), "<script>") { t.Fatalf("html export did not escape translation text") }
+  if !strings.Contains(html.String(), `dir="rtl"`) { t.Fatalf("html export missing rtl markup") }
+
+  var epub bytes.Buffer
+  if err := export.Write(ctx, &epub, export.FormatEPUB, doc); err != nil { t.Fatal(err) }
+  zr, err := zip.NewReader(bytes.NewReader(epub.Bytes()), int64(epub.Len()))
+  if err != nil { t.Fatal(err) }
+  if zr.File[0].Name != "mimetype" || zr.File[0].Method != zip.Store {
+    t.Fatalf("epub must start with stored mimetype, got %s", zr.File[0].Name)
+  }
+  names := map[string]bool{}
+  for _, f := range zr.File { names[f.Name] = true }
+  for _, want := range []string{"META-INF/container.xml", "OEBPS/content.opf", "OEBPS/nav.xhtml", "OEBPS/s1.xhtml"} {
+    if !names[want] { t.Fatalf("epub missing %s", want) }
+  }
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+  "html/template"
+  "io"
+)
+
+// css is shared by the HTML, EPUB and PDF outputs.
+const css = `
+body { font-family: "Noto Serif", Georgia, serif; line-height: 1.6; margin: 2em auto; max-width: 46em; padding: 0 1em; }
+h1 { text-align: center; }
+h2 { border-bottom: 1px solid #ccc; padding-bottom: .25em; margin-top: 2em; }
+h2 .ar { font-size: 1em; }
+.ayah { margin: 1.2em 0; page-break-inside: avoid; }
+.ar { direction: rtl; text-align: right; unicode-bidi: isolate; font-family: "KFGQPC Uthmanic Script HAFS", "Amiri Quran", "Amiri", "Scheherazade New", serif; font-size: 1.7em; line-height: 2.2; }
+.num { white-space: nowrap; }
+.tr { direction: ltr; text-align: left; margin-top: .3em; }
+.tr .ref { font-weight: bold; margin-right: .4em; }
+.tr .lang { color: #666; font-size: .85em; margin-right: .4em; }
+`
+
+// sectionView is what the "section" template renders.
+type sectionView struct {
+  Numbers bool
+  Section
+}
+
+func sectionViews(doc *Document) []sectionView {
+  out := make([]sectionView, len(doc.Sections))
+  for i, s := range doc.Sections { out[i] = sectionView{doc.Numbers, s} }
+  return out
+}
+
+// sectionTmpl renders one surah; it is shared by the HTML document and the EPUB chapters.
+const sectionTmpl = `{{define "section"}}{{$num := .Numbers}}
+<h2 id="s{{.Surah}}">{{.Surah}}. <span class="ar" lang="ar" dir="rtl">{{.NameAr}}</span>{{if .NameLatin}} — {{.NameLatin}}{{end}}</h2>
+{{range .Verses}}{{$v := .}}<div class="ayah" id="a{{.Surah}}-{{.Number}}">
+<p class="ar" lang="ar" dir="rtl">{{.Arabic}}{{if $num}} <span class="num">{{arnum .Number}}</span>{{end}}</p>
+{{range .Trans}}<p class="tr" dir="ltr"{{if .Lang}} lang="{{.Lang}}"{{end}}>{{if $num}}<span class="ref">{{$v.Surah}}:{{$v.Number}}</span>{{end}}{{if .Lang}}<span class="lang">({{.Lang}})</span>{{end}}{{.Text}}</p>
+{{end}}</div>
+{{end}}{{end}}`
+
+var htmlTpl = template.Must(template.New("doc").Funcs(template.FuncMap{"arnum": ArabicNumber}).Parse(sectionTmpl + `<!doctype html>
+<html lang="ar"><head><meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>{{.CSS}}</style>
+</head><body>
+<h1>{{.Title}}</h1>
+{{range .Sections}}{{template "section" .}}{{end}}
+</body></html>
+`))
+
+// WriteHTML renders doc as a standalone HTML page.
+func WriteHTML(w io.Writer, doc *Document) error {
+  return htmlTpl.Execute(w, map[string]any{
+    "Title": doc.Title,
+    "CSS": template.CSS(css),
+    "Sections": sectionViews(doc),
+  })
+}
@@ -0,0 +1,55 @@
+package export
+
+import (
+  "bufio"
+  "fmt"
+  "html"
+  "io"
+  "regexp"
+  "strings"
+)
+
+// WriteMarkdown renders doc as Markdown. Arabic paragraphs are wrapped in
+// <p dir="rtl"> since Markdown itself has no notion of text direction, and
+// are HTML-escaped like any raw HTML block. Every other field goes through
+// mdEscape, so translations and titles never become links, emphasis, lists
+// or markup.
+func WriteMarkdown(w io.Writer, doc *Document) error {
+  b := bufio.NewWriter(w)
+  fmt.Fprintf(b, "# %s\n", mdEscape(doc.Title))
+  for _, s := range doc.Sections {
+    fmt.Fprintf(b, "\n## %d. %s", s.Surah, mdEscape(s.NameAr))
+    if s.NameLatin != "" { fmt.Fprintf(b, " — %s", mdEscape(s.NameLatin)) }
+    fmt.Fprintln(b)
+    for _, v := range s.Verses {
+      ar := html.EscapeString(v.Arabic)
+      if doc.Numbers { ar += " " + ArabicNumber(v.Number) }
+      fmt.Fprintf(b, "\n<p dir=\"rtl\" lang=\"ar\">%s</p>\n", ar)
+      for _, t := range v.Trans {
+        fmt.Fprintln(b)
+        if doc.Numbers { fmt.Fprintf(b, "**%d:%d** ", v.Surah, v.Number) }
+        if t.Lang != "" { fmt.Fprintf(b, "_(%s)_ ", mdEscape(t.Lang)) }
+        fmt.Fprintln(b, mdEscape(t.Text))
+      }
+    }
+  }
+  return b.Flush()
+}
+
+// mdInline escapes the characters that start inline Markdown (emphasis,
+// code, links, tables, strikethrough) and raw HTML.
+var mdInline = strings.NewReplacer(
+  `\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `|`, `\|`, `~`, `\~`,
+  `&`, `&amp;`, `<`, `&lt;`, `>`, `&gt;`,
+)
+
+// mdBlock matches what turns a line into a heading, list item or rule.
+var mdBlock = regexp.MustCompile(`(?m)^([ \t]*)([#+=-]|\d+[.)])`)
+
+// mdEscape makes s render as the literal text.
+func mdEscape(s string) string {
+  return mdBlock.ReplaceAllStringFunc(mdInline.Replace(s), func(m string) string {
+    i := len(m) - 1 // the marker is the last byte: #, +, =, -, . or )
+    return m[:i] + `\` + m[i:]
+  })
+}
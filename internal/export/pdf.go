@@ -0,0 +1,63 @@
+package export
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "os/exec"
+  "path/filepath"
+  "strings"
+)
+
+// ErrNoPDFEngine is returned when no HTML-to-PDF renderer is installed.
+var ErrNoPDFEngine = errors.New("pdf export needs chromium, wkhtmltopdf or weasyprint (or QURAN_PDF_ENGINE)")
+
+// pdfEngines are tried in order. Arabic needs a real shaping engine, so PDF
+// output is produced by printing the HTML rendering rather than drawn by hand.
+var pdfEngines = []string{"chromium", "chromium-browser", "google-chrome", "weasyprint", "wkhtmltopdf"}
+
+// PDFEngine returns the renderer binary WritePDF would use.
+func PDFEngine() (string, error) {
+  if p := os.Getenv("QURAN_PDF_ENGINE"); p != "" { return exec.LookPath(p) }
+  for _, e := range pdfEngines {
+    if p, err := exec.LookPath(e); err == nil { return p, nil }
+  }
+  return "", ErrNoPDFEngine
+}
+
+// WritePDF renders doc to HTML and prints it to PDF with an external engine.
+func WritePDF(ctx context.Context, w io.Writer, doc *Document) error {
+  engine, err := PDFEngine()
+  if err != nil { return err }
+  dir, err := os.MkdirTemp("", "quran-export-*")
+  if err != nil { return err }
+  defer os.RemoveAll(dir)
+
+  in := filepath.Join(dir, "doc.html")
+  out := filepath.Join(dir, "doc.pdf")
+  f, err := os.Create(in)
+  if err != nil { return err }
+  if err := WriteHTML(f, doc); err != nil { f.Close(); return err }
+  if err := f.Close(); err != nil { return err }
+
+  var args []string
+  switch name := filepath.Base(engine); {
+  case strings.Contains(name, "wkhtmltopdf"):
+    args = []string{"--quiet", "--encoding", "utf-8", in, out}
+  case strings.Contains(name, "weasyprint"):
+    args = []string{in, out}
+  default: // chromium family
+    args = []string{"--headless", "--disable-gpu", "--no-sandbox", "--no-pdf-header-footer", "--print-to-pdf=" + out, "file://" + in}
+  }
+  cmd := exec.CommandContext(ctx, engine, args...)
+  if b, err := cmd.CombinedOutput(); err != nil {
+    return fmt.Errorf("%s: %w: %s", filepath.Base(engine), err, strings.TrimSpace(string(b)))
+  }
+  pdf, err := os.Open(out)
+  if err != nil { return err }
+  defer pdf.Close()
+  _, err = io.Copy(w, pdf)
+  return err
+}
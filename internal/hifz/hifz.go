@@ -0,0 +1,186 @@
+// Package hifz schedules ayah for memorization review with SM-2 spaced
+// repetition and masks ayah text progressively for recall practice.
+//
+// A Deck is personal: it lives in a JSON file of the user's (QURAN_HIFZ_PATH,
+// else the user config dir), not in the Quran database, which the servers
+// open read-only.
+package hifz
+
+import (
+  "encoding/json"
+  "errors"
+  "fmt"
+  "math"
+  "os"
+  "path/filepath"
+  "sort"
+  "time"
+)
+
+// Grades are SM-2 recall qualities; below GradePass the ayah starts over.
+const (
+  GradeBlackout = 0 // nothing recalled
+  GradeWrong    = 1 // wrong, but familiar once shown
+  GradeHard     = 2 // wrong, but easy to recall once shown
+  GradePass     = 3 // right with serious difficulty
+  GradeGood     = 4 // right after some hesitation
+  GradeEasy     = 5 // right without hesitation
+
+  minEase = 1.3
+  newEase = 2.5
+)
+
+// dateLayout is how due dates are stored: whole days, in local time.
+const dateLayout = "2006-01-02"
+
+// Card is the review state of one ayah.
+type Card struct {
+  Surah    int     `json:"surah"`
+  Ayah     int     `json:"ayah"`
+  Ease     float64 `json:"ease"`
+  Interval int     `json:"interval"` // days until the next review
+  Reps     int     `json:"reps"`     // successful reviews in a row
+  Due      string  `json:"due"`      // YYYY-MM-DD
+  Grade    int     `json:"grade"`    // last grade, -1 before the first review
+}
+
+// Review grades c (0-5) on day today and schedules its next review.
+func Review(c Card, grade int, today time.Time) (Card, error) {
+  if grade < GradeBlackout || grade > GradeEasy { return c, fmt.Errorf("grade %d out of range 0-5", grade) }
+  if c.Ease == 0 { c.Ease = newEase }
+  if grade < GradePass {
+    c.Reps, c.Interval = 0, 1
+  } else {
+    switch c.Reps {
+    case 0:
+      c.Interval = 1
+    case 1:
+      c.Interval = 6
+    default:
+      c.Interval = int(math.Round(float64(c.Interval) * c.Ease))
+    }
+    c.Reps++
+  }
+  q := float64(GradeEasy - grade)
+  c.Ease = math.Max(minEase, c.Ease+0.1-q*(0.08+q*0.02))
+  c.Grade = grade
+  c.Due = day(today).AddDate(0, 0, c.Interval).Format(dateLayout)
+  return c, nil
+}
+
+// Level is how much of an ayah Mask hides.
+type Level int
+
+const (
+  LevelFull   Level = iota // the whole text
+  LevelHint                // first letter of each word
+  LevelHalf                // every other word hidden, the rest hinted
+  LevelHidden              // every word hidden
+)
+
+// Suggested is the level to practise c at: hints for a new ayah, less
+// help with each successful review.
+func (c Card) Suggested() Level {
+  switch {
+  case c.Reps == 0:
+    return LevelHint
+  case c.Reps == 1:
+    return LevelHalf
+  default:
+    return LevelHidden
+  }
+}
+
+// Deck is one user's cards, kept in a JSON file.
+type Deck struct {
+  path  string
+  cards map[[2]int]Card
+}
+
+// Path is the deck file: QURAN_HIFZ_PATH, else hifz.json in the user config
+// dir.
+func Path() string {
+  if p := os.Getenv("QURAN_HIFZ_PATH"); p != "" { return p }
+  dir, err := os.UserConfigDir()
+  if err != nil { return "hifz.json" }
+  return filepath.Join(dir, "quran-go", "hifz.json")
+}
+
+// Open reads the deck at path; a missing file is an empty deck.
+func Open(path string) (*Deck, error) {
+  d := &Deck{path: path, cards: map[[2]int]Card{}}
+  b, err := os.ReadFile(path)
+  if errors.Is(err, os.ErrNotExist) { return d, nil }
+  if err != nil { return nil, err }
+  var cards []Card
+  if err := json.Unmarshal(b, &cards); err != nil { return nil, fmt.Errorf("%s: %w", path, err) }
+  for _, c := range cards { d.cards[[2]int{c.Surah, c.Ayah}] = c }
+  return d, nil
+}
+
+// Save writes the deck, replacing the file only once it is complete.
+func (d *Deck) Save() error {
+  b, err := json.MarshalIndent(d.Cards(), "", "  ")
+  if err != nil { return err }
+  if err := os.MkdirAll(filepath.Dir(d.path), 0o755); err != nil { return err }
+  tmp, err := os.CreateTemp(filepath.Dir(d.path), ".hifz-*.json")
+  if err != nil { return err }
+  defer os.Remove(tmp.Name())
+  if _, err := tmp.Write(append(b, '\n')); err != nil { tmp.Close(); return err }
+  if err := tmp.Close(); err != nil { return err }
+  return os.Rename(tmp.Name(), d.path)
+}
+
+// Add puts new cards, due today, for the ayah not yet in the deck and
+// returns how many were added.
+func (d *Deck) Add(today time.Time, surah int, ayat ...int) int {
+  n := 0
+  for _, a := range ayat {
+    k := [2]int{surah, a}
+    if _, ok := d.cards[k]; ok { continue }
+    d.cards[k] = Card{Surah: surah, Ayah: a, Ease: newEase, Due: day(today).Format(dateLayout), Grade: -1}
+    n++
+  }
+  return n
+}
+
+// Get returns the card of an ayah.
+func (d *Deck) Get(surah, ayah int) (Card, bool) {
+  c, ok := d.cards[[2]int{surah, ayah}]
+  return c, ok
+}
+
+// Grade reviews the card of an ayah and stores the result.
+func (d *Deck) Grade(surah, ayah, grade int, today time.Time) (Card, error) {
+  c, ok := d.Get(surah, ayah)
+  if !ok { return c, fmt.Errorf("%d:%d is not in the deck", surah, ayah) }
+  c, err := Review(c, grade, today)
+  if err != nil { return c, err }
+  d.cards[[2]int{surah, ayah}] = c
+  return c, nil
+}
+
+// Cards returns every card in mushaf order.
+func (d *Deck) Cards() []Card {
+  out := make([]Card, 0, len(d.cards))
+  for _, c := range d.cards { out = append(out, c) }
+  sort.Slice(out, func(i, j int) bool {
+    if out[i].Surah != out[j].Surah { return out[i].Surah < out[j].Surah }
+    return out[i].Ayah < out[j].Ayah
+  })
+  return out
+}
+
+// Due returns the cards due on or before today, the most overdue first and
+// in mushaf order within a day.
+func (d *Deck) Due(today time.Time) []Card {
+  end := day(today).Format(dateLayout)
+  var out []Card
+  for _, c := range d.Cards() {
+    if c.Due <= end { out = append(out, c) }
+  }
+  sort.SliceStable(out, func(i, j int) bool { return out[i].Due < out[j].Due })
+  return out
+}
+
+func day(t time.Time) time.Time { y, m, dd := t.Date(); return time.Date(y, m, dd, 0, 0, 0, 0, t.Location()) }
@@ -0,0 +1,71 @@
+package hifz
+
+import (
+  "path/filepath"
+  "testing"
+  "time"
+)
+
+var today = time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+
+func TestReview(t *testing.T) {
+  c := Card{Surah: 1, Ayah: 1, Ease: newEase, Grade: -1}
+  var err error
+  for i, want := range []int{1, 6, 15} {
+    if c, err = Review(c, GradeGood, today); err != nil { t.Fatal(err) }
+    if c.Interval != want || c.Reps != i+1 { t.Fatalf("review %d: %+v, want interval %d", i+1, c, want) }
+  }
+  if c.Due != "2026-03-16" || c.Ease != newEase { t.Fatalf("due/ease: %+v", c) }
+
+  c, _ = Review(c, GradeEasy, today)
+  if c.Interval != 38 || c.Ease != 2.6 { t.Fatalf("easy: %+v", c) }
+
+  c, _ = Review(c, GradeWrong, today)
+  if c.Reps != 0 || c.Interval != 1 || c.Due != "2026-03-02" || c.Ease >= 2.6 { t.Fatalf("lapse: %+v", c) }
+
+  for i := 0; i < 10; i++ { c, _ = Review(c, GradeBlackout, today) }
+  if c.Ease != minEase { t.Fatalf("ease floor: %v", c.Ease) }
+
+  if _, err := Review(c, 6, today); err == nil { t.Fatal("grade 6: want error") }
+}
+
+func TestDeck(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "sub", "hifz.json")
+  d, err := Open(path)
+  if err != nil { t.Fatal(err) }
+  if n := d.Add(today, 112, 1, 2, 3, 4); n != 4 { t.Fatalf("added %d", n) }
+  if n := d.Add(today, 112, 4, 5); n != 1 { t.Fatalf("re-added %d", n) }
+  d.Add(today, 1, 1)
+  if _, err := d.Grade(112, 2, GradeEasy, today); err != nil { t.Fatal(err) }
+  if _, err := d.Grade(2, 255, GradeEasy, today); err == nil { t.Fatal("grading a missing card: want error") }
+
+  due := d.Due(today)
+  if len(due) != 5 || due[0] != (Card{Surah: 1, Ayah: 1, Ease: newEase, Due: "2026-03-01", Grade: -1}) || due[1].Ayah != 1 || due[2].Ayah != 3 {
+    t.Fatalf("due: %+v", due)
+  }
+  if len(d.Due(today.AddDate(0, 0, 1))) != 6 { t.Fatal("everything is due tomorrow") }
+
+  if err := d.Save(); err != nil { t.Fatal(err) }
+  d2, err := Open(path)
+  if err != nil { t.Fatal(err) }
+  c, ok := d2.Get(112, 2)
+  if !ok || c.Reps != 1 || c.Grade != GradeEasy || c.Due != "2026-03-02" { t.Fatalf("reloaded: %+v", c) }
+  if len(d2.Cards()) != 6 { t.Fatalf("reloaded %d cards", len(d2.Cards())) }
+}
+
+func TestMask(t *testing.T) {
+  ar := "قُلْ هُوَ ٱللَّهُ أَحَدٌ ۝١"
+  for _, tc := range []struct {
+    level Level
+    want  string
+  }{
+    {LevelFull, ar},
+    {LevelHint, "قُ… هُ… ٱ… أَ… ۝١"},
+    {LevelHalf, "قُ… … ٱ… … ۝١"},
+    {LevelHidden, "… … … … ۝١"},
+  } {
+    if got := Mask(ar, tc.level); got != tc.want { t.Errorf("level %d: %q, want %q", tc.level, got, tc.want) }
+  }
+  if got := Mask("Say: He is Allah, the One.", LevelHint); got != "S… H… i… A… t… O…" { t.Errorf("trans: %q", got) }
+  if got := (Card{Reps: 3}).Suggested(); got != LevelHidden { t.Errorf("suggested: %d", got) }
+}
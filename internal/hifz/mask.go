@@ -0,0 +1,47 @@
+package hifz
+
+import (
+  "strings"
+  "unicode"
+)
+
+// gap stands for the hidden part of a word.
+const gap = "…"
+
+// Mask hides the words of text to level. A hinted word keeps its first
+// letter with its vowel marks; tokens without letters (pause marks, ayah
+// numbers) are kept.
+func Mask(text string, level Level) string {
+  if level <= LevelFull { return text }
+  words := strings.Fields(text)
+  n := 0
+  for i, w := range words {
+    if !hasLetter(w) { continue }
+    switch {
+    case level >= LevelHidden, level == LevelHalf && n%2 == 1:
+      words[i] = gap
+    default:
+      words[i] = hint(w)
+    }
+    n++
+  }
+  return strings.Join(words, " ")
+}
+
+// hint keeps the first letter of w and the marks on it.
+func hint(w string) string {
+  rs := []rune(w)
+  i := 0
+  for i < len(rs) && !unicode.IsLetter(rs[i]) { i++ }
+  j := i + 1
+  for j < len(rs) && unicode.Is(unicode.M, rs[j]) { j++ }
+  if j >= len(rs) { return w }
+  return string(rs[:j]) + gap
+}
+
+func hasLetter(w string) bool {
+  for _, r := range w {
+    if unicode.IsLetter(r) { return true }
+  }
+  return false
+}
@@ -0,0 +1,174 @@
+package httpx
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Encodings supported by Compress, in server preference order.
+var encodings = []string{"br", "zstd", "gzip"}
+
+// Compress encodes response bodies with brotli, zstd or gzip depending on the
+// client's Accept-Encoding. Responses that already carry a Content-Encoding,
+// are compressed formats themselves (see Incompressible), or have no body
+// (HEAD, 204, 304), are passed through untouched.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		enc := pickEncoding(r.Header.Get("Accept-Encoding"))
+		if enc == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, enc: enc}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// pickEncoding returns the preferred supported encoding allowed by an
+// Accept-Encoding header, or "" for identity.
+func pickEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+	q := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, weight := parseQ(part)
+		if name != "" {
+			q[strings.ToLower(name)] = weight
+		}
+	}
+	best, bestQ := "", 0.0
+	for _, e := range encodings {
+		w, ok := q[e]
+		if !ok {
+			w, ok = q["*"]
+		}
+		if ok && w > bestQ {
+			best, bestQ = e, w
+		}
+	}
+	return best
+}
+
+// parseQ splits a header element like "gzip;q=0.8" into its value and weight.
+func parseQ(part string) (string, float64) {
+	fields := strings.Split(part, ";")
+	name := strings.TrimSpace(fields[0])
+	weight := 1.0
+	for _, p := range fields[1:] {
+		p = strings.TrimSpace(p)
+		if strings.HasPrefix(p, "q=") {
+			if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+				weight = v
+			}
+		}
+	}
+	return name, weight
+}
+
+// Incompressible reports whether a Content-Type is already compressed
+// (archives, EPUB, PDF, zstd/gzip dumps, images, audio, video), so encoding
+// it again only costs CPU.
+func Incompressible(contentType string) bool {
+	ct, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	ct = strings.TrimSpace(ct)
+	switch ct {
+	case "application/zip", "application/epub+zip", "application/pdf", "application/zstd",
+		"application/gzip", "application/x-gzip", "application/x-bzip2", "application/x-xz",
+		"font/woff", "font/woff2":
+		return true
+	case "image/svg+xml":
+		return false
+	}
+	for _, p := range []string{"image/", "audio/", "video/"} {
+		if strings.HasPrefix(ct, p) {
+			return true
+		}
+	}
+	return strings.HasSuffix(ct, "+zip")
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	enc         string
+	w           io.WriteCloser
+	wroteHeader bool
+	passthrough bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	h := cw.Header()
+	if code < 200 || code == http.StatusNoContent || code == http.StatusNotModified || h.Get("Content-Encoding") != "" || Incompressible(h.Get("Content-Type")) {
+		cw.passthrough = true
+	} else {
+		h.Set("Content-Encoding", cw.enc)
+		h.Del("Content-Length")
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.passthrough {
+		return cw.ResponseWriter.Write(b)
+	}
+	if cw.w == nil {
+		cw.w = newEncoder(cw.enc, cw.ResponseWriter)
+	}
+	return cw.w.Write(b)
+}
+
+// Flush pushes buffered compressed bytes to the client (used by streaming handlers).
+func (cw *compressWriter) Flush() {
+	if f, ok := cw.w.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := cw.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (cw *compressWriter) Close() error {
+	if cw.w == nil {
+		return nil
+	}
+	return cw.w.Close()
+}
+
+func newEncoder(enc string, w io.Writer) io.WriteCloser {
+	switch enc {
+	case "br":
+		return brotli.NewWriterLevel(w, brotli.DefaultCompression)
+	case "zstd":
+		// Options are static, so NewWriter cannot fail here.
+		zw, _ := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		return zw
+	}
+	return gzip.NewWriter(w)
+}
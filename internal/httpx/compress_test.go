@@ -0,0 +1,118 @@
+package httpx
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestPickEncoding(t *testing.T) {
+	cases := map[string]string{
+		"":                     "",
+		"identity":             "",
+		"gzip":                 "gzip",
+		"gzip, deflate, br":    "br",
+		"gzip;q=1.0, br;q=0.5": "gzip",
+		"zstd, gzip":           "zstd",
+		"*":                    "br",
+		"br;q=0, *;q=0.3":      "zstd",
+		"GZIP":                 "gzip",
+	}
+	for in, want := range cases {
+		if got := pickEncoding(in); got != want {
+			t.Errorf("pickEncoding(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCompress_RoundTrip(t *testing.T) {
+	body := strings.Repeat("بِسْمِ اللَّهِ الرَّحْمَٰنِ الرَّحِيمِ ", 50)
+	h := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = io.WriteString(w, body)
+	}))
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+	for enc, dec := range decoders {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", enc)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if got := w.Header().Get("Content-Encoding"); got != enc {
+			t.Fatalf("%s: Content-Encoding = %q", enc, got)
+		}
+		r, err := dec(w.Body)
+		if err != nil {
+			t.Fatalf("%s: decoder: %v", enc, err)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: read: %v", enc, err)
+		}
+		if string(b) != body {
+			t.Fatalf("%s: body mismatch", enc)
+		}
+	}
+}
+
+func TestCompress_NoContentPassthrough(t *testing.T) {
+	h := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.Len() != 0 {
+		t.Fatalf("expected untouched 204, got encoding=%q len=%d", w.Header().Get("Content-Encoding"), w.Body.Len())
+	}
+}
+
+func TestCompress_SkipsCompressedTypes(t *testing.T) {
+	for ct, skip := range map[string]bool{"application/pdf": true, "application/epub+zip": true, "application/zstd": true, "image/png": true, "image/svg+xml": false, "application/json; charset=utf-8": false} {
+		h := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", ct)
+			io.WriteString(w, strings.Repeat("x", 1024))
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if got := w.Header().Get("Content-Encoding") == ""; got != skip {
+			t.Errorf("%s: Content-Encoding=%q", ct, w.Header().Get("Content-Encoding"))
+		}
+		if skip && w.Body.Len() != 1024 {
+			t.Errorf("%s: body changed (%d bytes)", ct, w.Body.Len())
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct{ url, accept, want string }{
+		{"/", "", FormatJSON},
+		{"/", "text/csv", FormatCSV},
+		{"/", "text/plain;q=0.5, application/msgpack", FormatMsgPack},
+		{"/", "text/html,*/*;q=0.8", FormatJSON},
+		{"/", "image/png", ""},
+		{"/?format=txt", "application/json", FormatText},
+		{"/?format=yaml", "", ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.url, nil)
+		if c.accept != "" {
+			req.Header.Set("Accept", c.accept)
+		}
+		if got := Negotiate(req); got != c.want {
+			t.Errorf("Negotiate(%s, %q) = %q, want %q", c.url, c.accept, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LocalAddr turns a listen address (":8080", "0.0.0.0:8080", "[::]:8080")
+// into one a client on the same host can dial.
+func LocalAddr(bind string) string {
+	for _, host := range []string{"0.0.0.0", "[::]", ""} {
+		if port, ok := strings.CutPrefix(bind, host+":"); ok {
+			return "127.0.0.1:" + port
+		}
+	}
+	return bind
+}
+
+// Selfcheck asks the server listening on bind for /healthz; the -selfcheck
+// flag of the servers runs it as the container healthcheck.
+func Selfcheck(bind string) error {
+	hc := &http.Client{Timeout: 2 * time.Second}
+	resp, err := hc.Get("http://" + LocalAddr(bind) + "/healthz")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("healthz: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLocalAddr(t *testing.T) {
+	for bind, want := range map[string]string{
+		":8080":          "127.0.0.1:8080",
+		"0.0.0.0:8080":   "127.0.0.1:8080",
+		"[::]:8090":      "127.0.0.1:8090",
+		"10.0.0.5:8080":  "10.0.0.5:8080",
+		"localhost:8080": "localhost:8080",
+	} {
+		if got := LocalAddr(bind); got != want {
+			t.Errorf("%s: %s, want %s", bind, got, want)
+		}
+	}
+}
+
+func TestSelfcheck(t *testing.T) {
+	code := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			code = http.StatusNotFound
+		}
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+	bind := strings.TrimPrefix(srv.URL, "http://")
+	if err := Selfcheck(bind); err != nil {
+		t.Fatal(err)
+	}
+	code = http.StatusServiceUnavailable
+	if err := Selfcheck(bind); err == nil {
+		t.Fatal("expected an error for 503")
+	}
+}
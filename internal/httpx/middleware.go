@@ -11,15 +11,18 @@ import (
     "golang.org/x/time/rate"
 )
 
-func CORS(next http.Handler) http.Handler {
+// CORS allows cross-origin requests from QURAN_ALLOWED_ORIGINS (default any
+// origin). Preflights allow GET and OPTIONS plus any extra methods given.
+func CORS(next http.Handler, methods ...string) http.Handler {
 	origins := strings.Split(os.Getenv("QURAN_ALLOWED_ORIGINS"), ",")
+	allow := strings.Join(append([]string{"GET", "OPTIONS"}, methods...), ",")
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		o := "*"
 		if len(origins) > 0 && origins[0] != "" { o = origins[0] }
 		w.Header().Set("Access-Control-Allow-Origin", o)
 		w.Header().Set("Vary", "Origin")
 		if r.Method == http.MethodOptions {
-			w.Header().Set("Access-Control-Allow-Methods", "GET,OPTIONS")
+			w.Header().Set("Access-Control-Allow-Methods", allow)
 			w.Header().Set("Access-Control-Allow-Headers", "Content-Type,Authorization")
 			w.WriteHeader(http.StatusNoContent); return
 		}
@@ -0,0 +1,66 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Response formats understood by Negotiate.
+const (
+	FormatJSON    = "json"
+	FormatText    = "text"
+	FormatCSV     = "csv"
+	FormatMsgPack = "msgpack"
+)
+
+var formatAliases = map[string]string{
+	"json":    FormatJSON,
+	"text":    FormatText,
+	"txt":     FormatText,
+	"csv":     FormatCSV,
+	"msgpack": FormatMsgPack,
+	"mpk":     FormatMsgPack,
+}
+
+var mediaFormats = map[string]string{
+	"application/json":        FormatJSON,
+	"text/plain":              FormatText,
+	"text/csv":                FormatCSV,
+	"application/msgpack":     FormatMsgPack,
+	"application/x-msgpack":   FormatMsgPack,
+	"application/vnd.msgpack": FormatMsgPack,
+}
+
+// ContentTypes maps each format to the Content-Type it is served with.
+var ContentTypes = map[string]string{
+	FormatJSON:    "application/json; charset=utf-8",
+	FormatText:    "text/plain; charset=utf-8",
+	FormatCSV:     "text/csv; charset=utf-8",
+	FormatMsgPack: "application/msgpack",
+}
+
+// Negotiate picks a response format for r. An explicit ?format= query
+// parameter wins over the Accept header; JSON is the default. It returns ""
+// when the client asked for something we cannot produce.
+func Negotiate(r *http.Request) string {
+	if f := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format"))); f != "" {
+		return formatAliases[f]
+	}
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return FormatJSON
+	}
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		media, q := parseQ(part)
+		media = strings.ToLower(media)
+		f, ok := mediaFormats[media]
+		if !ok && (media == "*/*" || media == "application/*") {
+			f, ok = FormatJSON, true
+		}
+		if ok && q > bestQ {
+			best, bestQ = f, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,99 @@
+// Package mushaf pages the text like the standard Madani mushaf, from the
+// built-in page table (quran.PageStarts) overridden by any page starts the
+// ingest stored in mushaf_page (see data.Ingest).
+package mushaf
+
+import (
+  "context"
+  "fmt"
+  "sort"
+
+  "github.com/jmoiron/sqlx"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// ErrInvalid is returned for a page number outside 1-604.
+var ErrInvalid = fmt.Errorf("page must be 1-%d", quran.MushafPages)
+
+// Ayah is one verse on a page, with the name of its surah for the title
+// printed where a surah begins.
+type Ayah struct {
+  Surah     int    `db:"surah"`
+  Number    int    `db:"number"`
+  Arabic    string `db:"arabic"`
+  Trans     string `db:"trans"`
+  NameAr    string `db:"name_ar"`
+  NameLatin string `db:"name_latin"`
+}
+
+// Page is one mushaf page.
+type Page struct {
+  Number int
+  Range  quran.Range
+  Ayat   []Ayah
+}
+
+// Prev and Next are the neighbouring page numbers, 0 at either end.
+func (p *Page) Prev() int {
+  if p.Number > 1 { return p.Number - 1 }
+  return 0
+}
+
+func (p *Page) Next() int {
+  if p.Number < quran.MushafPages { return p.Number + 1 }
+  return 0
+}
+
+// Range returns the ayah on page n.
+func Range(ctx context.Context, d *sqlx.DB, n int) (quran.Range, error) {
+  if n < 1 || n > quran.MushafPages { return quran.Range{}, ErrInvalid }
+  starts, err := Starts(ctx, d)
+  if err != nil { return quran.Range{}, err }
+  r := quran.Range{From: starts[n-1], To: quran.Ref{Surah: 114, Ayah: quran.VerseCounts[113]}}
+  if n < quran.MushafPages { r.To = before(starts[n]) }
+  return r, nil
+}
+
+// Of returns the page holding ref.
+func Of(ctx context.Context, d *sqlx.DB, ref quran.Ref) (int, error) {
+  starts, err := Starts(ctx, d)
+  if err != nil { return 0, err }
+  return sort.Search(quran.MushafPages, func(i int) bool { return starts[i].Key() > ref.Key() }), nil
+}
+
+// Starts returns the first ayah of every page: the standard Madani table
+// (quran.PageStarts), with any page the ingest stored in mushaf_page taking
+// that row's start instead.
+func Starts(ctx context.Context, d *sqlx.DB) ([quran.MushafPages]quran.Ref, error) {
+  starts := quran.PageStarts
+  var rows []struct {
+    Page   int `db:"page"`
+    Surah  int `db:"surah"`
+    Number int `db:"number"`
+  }
+  if err := d.SelectContext(ctx, &rows, `SELECT page, surah, number FROM mushaf_page`); err != nil { return starts, err }
+  for _, row := range rows {
+    if row.Page >= 1 && row.Page <= quran.MushafPages { starts[row.Page-1] = quran.Ref{Surah: row.Surah, Ayah: row.Number} }
+  }
+  return starts, nil
+}
+
+// Load returns page n with its ayah in order.
+func Load(ctx context.Context, d *sqlx.DB, n int) (*Page, error) {
+  r, err := Range(ctx, d, n)
+  if err != nil { return nil, err }
+  p := &Page{Number: n, Range: r}
+  err = d.SelectContext(ctx, &p.Ayat, d.Rebind(`SELECT a.surah, a.number, a.arabic, COALESCE(a.trans,'') AS trans,
+      s.name_ar, COALESCE(s.name_latin,'') AS name_latin
+    FROM ayah a JOIN surah s ON s.number = a.surah
+    WHERE a.surah BETWEEN ? AND ? AND (a.surah > ? OR a.number >= ?) AND (a.surah < ? OR a.number <= ?)
+    ORDER BY a.surah, a.number`), r.From.Surah, r.To.Surah, r.From.Surah, r.From.Ayah, r.To.Surah, r.To.Ayah)
+  if err != nil { return nil, err }
+  return p, nil
+}
+
+// before is the ayah preceding ref.
+func before(ref quran.Ref) quran.Ref {
+  if ref.Ayah > 1 { return quran.Ref{Surah: ref.Surah, Ayah: ref.Ayah - 1} }
+  return quran.Ref{Surah: ref.Surah - 1, Ayah: quran.VerseCounts[ref.Surah-2]}
+}
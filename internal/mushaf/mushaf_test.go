@@ -0,0 +1,58 @@
+package mushaf
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "testing"
+
+  "github.com/jmoiron/sqlx"
+  _ "modernc.org/sqlite"
+
+  "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+func testDB(t *testing.T) *sqlx.DB {
+  t.Helper()
+  d, err := sqlx.Open("sqlite", ":memory:")
+  if err != nil { t.Fatal(err) }
+  d.SetMaxOpenConns(1)
+  t.Cleanup(func() { d.Close() })
+  if err := db.Migrate(context.Background(), d); err != nil { t.Fatal(err) }
+  d.MustExec(`INSERT INTO surah(number,name_ar,name_latin,verses_count) VALUES(1,'الفاتحة','Al-Fatihah',7),(2,'البقرة','Al-Baqarah',286),(112,'الإخلاص','Al-Ikhlas',4)`)
+  for _, r := range [][2]int{{1, 1}, {1, 7}, {2, 1}, {2, 5}, {2, 6}, {2, 7}, {112, 1}} {
+    d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,trans) VALUES(?,?,1,'ا',?)`, r[0], r[1], fmt.Sprintf("%d:%d", r[0], r[1]))
+  }
+  return d
+}
+
+func TestRangeAndLoad(t *testing.T) {
+  ctx := context.Background()
+  d := testDB(t)
+  for _, tc := range []struct {
+    n    int
+    want string
+  }{{1, "1"}, {2, "2:1-5"}, {3, "2:6-16"}, {42, "2:253-256"}, {604, "112:1-114:6"}} {
+    r, err := Range(ctx, d, tc.n)
+    if err != nil || r.String() != tc.want { t.Errorf("page %d: %s %v, want %s", tc.n, r, err, tc.want) }
+  }
+  for _, n := range []int{0, 605} {
+    if _, err := Range(ctx, d, n); !errors.Is(err, ErrInvalid) { t.Errorf("page %d: %v", n, err) }
+  }
+
+  p, err := Load(ctx, d, 2)
+  if err != nil { t.Fatal(err) }
+  if len(p.Ayat) != 2 || p.Ayat[0].NameLatin != "Al-Baqarah" || p.Ayat[1].Trans != "2:5" || p.Prev() != 1 || p.Next() != 3 { t.Fatalf("page 2: %+v", p) }
+  if (&Page{Number: 604}).Next() != 0 || (&Page{Number: 1}).Prev() != 0 { t.Error("neighbours at the ends") }
+
+  for ref, want := range map[string]int{"1:7": 1, "2:5": 2, "2:6": 3, "2:255": 42, "50:1": 518, "114:6": 604} {
+    r, _ := quran.ParseRef(ref)
+    if n, err := Of(ctx, d, r); err != nil || n != want { t.Errorf("Of(%s) = %d %v, want %d", ref, n, err, want) }
+  }
+
+  // page starts stored by the ingest override the built-in table
+  d.MustExec(`INSERT INTO mushaf_page(page,surah,number) VALUES(3,2,7)`)
+  if r, _ := Range(ctx, d, 2); r.String() != "2:1-6" { t.Errorf("page 2 with an override: %s", r) }
+  if n, _ := Of(ctx, d, quran.Ref{Surah: 2, Ayah: 6}); n != 2 { t.Errorf("Of(2:6) with an override = %d", n) }
+}
@@ -0,0 +1,284 @@
+// Package plan splits the Quran into reading portions — over a number of
+// days or among participants of a group khatm — and keeps plans and their
+// progress in the reading_plan tables.
+package plan
+
+import (
+  "context"
+  "database/sql"
+  "errors"
+  "fmt"
+  "strings"
+  "time"
+
+  "github.com/jmoiron/sqlx"
+
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// Units a plan can be split by.
+const (
+  UnitJuz  = "juz"
+  UnitAyah = "ayah"
+  UnitPage = "page"
+)
+
+// Modes: one portion per day from the start date, or one per participant.
+const (
+  ModeDays         = "days"
+  ModeParticipants = "participants"
+)
+
+// DateLayout is how plan days are written.
+const DateLayout = "2006-01-02"
+
+var (
+  // ErrInvalid wraps every problem with a Spec or a portion number.
+  ErrInvalid = errors.New("invalid plan")
+  // ErrNotFound means there is no plan with the id.
+  ErrNotFound = errors.New("plan not found")
+)
+
+// Spec describes a plan to create.
+type Spec struct {
+  Name         string   `json:"name"`
+  Unit         string   `json:"unit"`  // juz (default), page or ayah
+  Mode         string   `json:"mode"`  // days (default) or participants
+  Parts        int      `json:"parts"` // days or participants; defaults to len(Participants)
+  Start        string   `json:"start"` // first day, YYYY-MM-DD (default today)
+  Participants []string `json:"participants,omitempty"`
+}
+
+// Portion is one day's or one participant's share.
+type Portion struct {
+  Part        int       `json:"part"`
+  Day         string    `json:"day,omitempty"`
+  Participant string    `json:"participant,omitempty"`
+  From        quran.Ref `json:"from"`
+  To          quran.Ref `json:"to"`
+  Ayat        int       `json:"ayat"`
+  Done        bool      `json:"done"`
+  DoneAt      string    `json:"done_at,omitempty"`
+}
+
+// Range is the ayah the portion covers.
+func (p Portion) Range() quran.Range { return quran.Range{From: p.From, To: p.To} }
+
+// Plan is a stored plan; Portions is filled by Get.
+type Plan struct {
+  ID       int64     `json:"id"`
+  Name     string    `json:"name"`
+  Unit     string    `json:"unit"`
+  Mode     string    `json:"mode"`
+  Parts    int       `json:"parts"`
+  Start    string    `json:"start"`
+  Created  string    `json:"created_at"`
+  Done     int       `json:"done"` // portions marked done
+  Portions []Portion `json:"portions,omitempty"`
+}
+
+// Split divides the whole Quran into n contiguous portions: whole juz (n at
+// most 30) or whole Madani mushaf pages (n at most 604) as evenly as
+// possible, or equal ayah counts.
+func Split(unit string, n int) ([]Portion, error) {
+  switch unit {
+  case UnitJuz:
+    if n < 1 || n > 30 { return nil, fmt.Errorf("%w: 30 juz cannot be split into %d parts; split by ayah", ErrInvalid, n) }
+    out := make([]Portion, n)
+    for i := range out {
+      first, last := i*30/n+1, (i+1)*30/n
+      out[i] = portion(i+1, quran.JuzStarts[first-1], quran.JuzRange(last).To)
+    }
+    return out, nil
+  case UnitAyah:
+    if n < 1 || n > quran.TotalAyah { return nil, fmt.Errorf("%w: parts must be 1-%d", ErrInvalid, quran.TotalAyah) }
+    out := make([]Portion, n)
+    for i := range out {
+      out[i] = portion(i+1, refAt(i*quran.TotalAyah/n), refAt((i+1)*quran.TotalAyah/n-1))
+    }
+    return out, nil
+  case UnitPage:
+    if n < 1 || n > quran.MushafPages { return nil, fmt.Errorf("%w: %d pages cannot be split into %d parts; split by ayah", ErrInvalid, quran.MushafPages, n) }
+    out := make([]Portion, n)
+    for i := range out {
+      first, last := i*quran.MushafPages/n+1, (i+1)*quran.MushafPages/n
+      out[i] = portion(i+1, quran.PageStarts[first-1], quran.PageRange(last).To)
+    }
+    return out, nil
+  }
+  return nil, fmt.Errorf("%w: unknown unit %q (use juz, page or ayah)", ErrInvalid, unit)
+}
+
+func portion(part int, from, to quran.Ref) Portion {
+  return Portion{Part: part, From: from, To: to, Ayat: index(to) - index(from) + 1}
+}
+
+// index is the position of ref in the mushaf, from 0.
+func index(ref quran.Ref) int {
+  n := ref.Ayah - 1
+  for s := 1; s < ref.Surah; s++ { n += quran.VerseCounts[s-1] }
+  return n
+}
+
+// refAt is the ayah at mushaf position i.
+func refAt(i int) quran.Ref {
+  s := 1
+  for i >= quran.VerseCounts[s-1] { i -= quran.VerseCounts[s-1]; s++ }
+  return quran.Ref{Surah: s, Ayah: i + 1}
+}
+
+// normalize fills in defaults and checks s.
+func (s *Spec) normalize(now time.Time) error {
+  s.Name = strings.TrimSpace(s.Name)
+  if s.Name == "" { s.Name = "Khatm" }
+  if len(s.Name) > 100 { return fmt.Errorf("%w: name too long", ErrInvalid) }
+  if s.Unit == "" { s.Unit = UnitJuz }
+  if s.Mode == "" { s.Mode = ModeDays }
+  switch s.Mode {
+  case ModeDays:
+    if len(s.Participants) > 0 { return fmt.Errorf("%w: participants need mode participants", ErrInvalid) }
+  case ModeParticipants:
+    for i, p := range s.Participants {
+      if s.Participants[i] = strings.TrimSpace(p); s.Participants[i] == "" { return fmt.Errorf("%w: empty participant name", ErrInvalid) }
+    }
+    if s.Parts == 0 { s.Parts = len(s.Participants) }
+    if len(s.Participants) > 0 && len(s.Participants) != s.Parts {
+      return fmt.Errorf("%w: %d participants for %d parts", ErrInvalid, len(s.Participants), s.Parts)
+    }
+  default:
+    return fmt.Errorf("%w: unknown mode %q (use days or participants)", ErrInvalid, s.Mode)
+  }
+  if s.Start == "" { s.Start = now.Format(DateLayout) }
+  if _, err := time.Parse(DateLayout, s.Start); err != nil { return fmt.Errorf("%w: start must be YYYY-MM-DD", ErrInvalid) }
+  return nil
+}
+
+// Create splits and stores a plan; now is the default start day.
+func Create(ctx context.Context, db *sqlx.DB, s Spec, now time.Time) (*Plan, error) {
+  if err := s.normalize(now); err != nil { return nil, err }
+  portions, err := Split(s.Unit, s.Parts)
+  if err != nil { return nil, err }
+  start, _ := time.Parse(DateLayout, s.Start)
+  for i := range portions {
+    if s.Mode == ModeDays { portions[i].Day = start.AddDate(0, 0, i).Format(DateLayout) }
+    if len(s.Participants) > 0 { portions[i].Participant = s.Participants[i] }
+  }
+
+  tx, err := db.BeginTxx(ctx, nil)
+  if err != nil { return nil, err }
+  defer tx.Rollback()
+  p := &Plan{Name: s.Name, Unit: s.Unit, Mode: s.Mode, Parts: s.Parts, Start: s.Start, Portions: portions}
+  if err := tx.QueryRowxContext(ctx, tx.Rebind(`INSERT INTO reading_plan(name,unit,mode,parts,start_date) VALUES(?,?,?,?,?) RETURNING id, created_at`),
+    p.Name, p.Unit, p.Mode, p.Parts, p.Start).Scan(&p.ID, &p.Created); err != nil {
+    return nil, err
+  }
+  for _, o := range portions {
+    if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO reading_plan_part(plan_id,part,day,participant,from_surah,from_ayah,to_surah,to_ayah,ayat)
+      VALUES(?,?,?,?,?,?,?,?,?)`), p.ID, o.Part, o.Day, o.Participant, o.From.Surah, o.From.Ayah, o.To.Surah, o.To.Ayah, o.Ayat); err != nil {
+      return nil, err
+    }
+  }
+  return p, tx.Commit()
+}
+
+type planRow struct {
+  ID      int64  `db:"id"`
+  Name    string `db:"name"`
+  Unit    string `db:"unit"`
+  Mode    string `db:"mode"`
+  Parts   int    `db:"parts"`
+  Start   string `db:"start_date"`
+  Created string `db:"created_at"`
+  Done    int    `db:"done"`
+}
+
+func (r planRow) plan() Plan {
+  return Plan{ID: r.ID, Name: r.Name, Unit: r.Unit, Mode: r.Mode, Parts: r.Parts, Start: r.Start, Created: r.Created, Done: r.Done}
+}
+
+const planSQL = `SELECT p.id, p.name, p.unit, p.mode, p.parts, p.start_date, p.created_at,
+  (SELECT COUNT(*) FROM reading_plan_part x WHERE x.plan_id = p.id AND x.done_at <> '') AS done
+  FROM reading_plan p`
+
+// List returns every plan with its progress, newest first, without portions.
+func List(ctx context.Context, db *sqlx.DB) ([]Plan, error) {
+  var rows []planRow
+  if err := db.SelectContext(ctx, &rows, planSQL+` ORDER BY p.id DESC`); err != nil { return nil, err }
+  out := make([]Plan, len(rows))
+  for i, r := range rows { out[i] = r.plan() }
+  return out, nil
+}
+
+// Get returns a plan with its portions.
+func Get(ctx context.Context, db *sqlx.DB, id int64) (*Plan, error) {
+  var r planRow
+  err := db.GetContext(ctx, &r, db.Rebind(planSQL+` WHERE p.id = ?`), id)
+  if errors.Is(err, sql.ErrNoRows) { return nil, fmt.Errorf("%w: %d", ErrNotFound, id) }
+  if err != nil { return nil, err }
+  p := r.plan()
+  var parts []struct {
+    Part        int    `db:"part"`
+    Day         string `db:"day"`
+    Participant string `db:"participant"`
+    FromSurah   int    `db:"from_surah"`
+    FromAyah    int    `db:"from_ayah"`
+    ToSurah     int    `db:"to_surah"`
+    ToAyah      int    `db:"to_ayah"`
+    Ayat        int    `db:"ayat"`
+    DoneAt      string `db:"done_at"`
+  }
+  if err := db.SelectContext(ctx, &parts, db.Rebind(`SELECT part, day, participant, from_surah, from_ayah, to_surah, to_ayah, ayat, done_at
+    FROM reading_plan_part WHERE plan_id = ? ORDER BY part`), id); err != nil {
+    return nil, err
+  }
+  for _, o := range parts {
+    p.Portions = append(p.Portions, Portion{Part: o.Part, Day: o.Day, Participant: o.Participant, From: quran.Ref{Surah: o.FromSurah, Ayah: o.FromAyah},
+      To: quran.Ref{Surah: o.ToSurah, Ayah: o.ToAyah}, Ayat: o.Ayat, Done: o.DoneAt != "", DoneAt: o.DoneAt})
+  }
+  return &p, nil
+}
+
+// SetDone marks a portion read (at now) or unread again.
+func SetDone(ctx context.Context, db *sqlx.DB, id int64, part int, done bool, now time.Time) error {
+  at := ""
+  if done { at = now.UTC().Format(time.DateTime) }
+  res, err := db.ExecContext(ctx, db.Rebind(`UPDATE reading_plan_part SET done_at = ? WHERE plan_id = ? AND part = ?`), at, id, part)
+  if err != nil { return err }
+  if n, _ := res.RowsAffected(); n == 0 {
+    if _, err := Get(ctx, db, id); err != nil { return err }
+    return fmt.Errorf("%w: plan %d has no part %d", ErrInvalid, id, part)
+  }
+  return nil
+}
+
+// Delete removes a plan and its progress.
+func Delete(ctx context.Context, db *sqlx.DB, id int64) error {
+  tx, err := db.BeginTxx(ctx, nil)
+  if err != nil { return err }
+  defer tx.Rollback()
+  if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM reading_plan_part WHERE plan_id = ?`), id); err != nil { return err }
+  res, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM reading_plan WHERE id = ?`), id)
+  if err != nil { return err }
+  if n, _ := res.RowsAffected(); n == 0 { return fmt.Errorf("%w: %d", ErrNotFound, id) }
+  return tx.Commit()
+}
+
+// Today returns what is left to read on day: in a days plan the portion of
+// that day, and in overdue the earlier ones not yet done; in a participants
+// plan every unread portion, or only the participant's when named.
+func (p *Plan) Today(day time.Time, participant string) (today, overdue []Portion) {
+  d := day.Format(DateLayout)
+  today, overdue = []Portion{}, []Portion{}
+  for _, o := range p.Portions {
+    if participant != "" && !strings.EqualFold(o.Participant, participant) { continue }
+    switch {
+    case p.Mode == ModeParticipants:
+      if !o.Done { today = append(today, o) }
+    case o.Day == d:
+      today = append(today, o)
+    case o.Day < d && !o.Done:
+      overdue = append(overdue, o)
+    }
+  }
+  return today, overdue
+}
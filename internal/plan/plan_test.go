@@ -0,0 +1,103 @@
+package plan
+
+import (
+  "context"
+  "errors"
+  "testing"
+  "time"
+
+  "github.com/jmoiron/sqlx"
+  _ "modernc.org/sqlite"
+
+  "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+var now = time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+
+func TestSplit(t *testing.T) {
+  ps, err := Split(UnitJuz, 30)
+  if err != nil { t.Fatal(err) }
+  if ps[1].Range() != quran.JuzRange(2) || ps[29].To != (quran.Ref{Surah: 114, Ayah: 6}) { t.Fatalf("juz 30: %+v %+v", ps[1], ps[29]) }
+
+  ps, _ = Split(UnitJuz, 7)
+  if ps[0].From != (quran.Ref{Surah: 1, Ayah: 1}) || ps[0].To != quran.JuzRange(4).To || ps[6].From != quran.JuzStarts[25] { t.Fatalf("juz 7: %+v", ps) }
+
+  ps, _ = Split(UnitAyah, 3)
+  total := 0
+  for i, p := range ps {
+    total += p.Ayat
+    if i > 0 && index(p.From) != index(ps[i-1].To)+1 { t.Fatalf("gap before part %d: %+v", p.Part, ps) }
+  }
+  if total != quran.TotalAyah || ps[2].To != (quran.Ref{Surah: 114, Ayah: 6}) || ps[0].Ayat != 2078 { t.Fatalf("ayah 3: %+v", ps) }
+
+  ps, _ = Split(UnitPage, 30)
+  total = 0
+  for i, p := range ps {
+    total += p.Ayat
+    if i > 0 && index(p.From) != index(ps[i-1].To)+1 { t.Fatalf("gap before part %d: %+v", p.Part, ps) }
+  }
+  if total != quran.TotalAyah || ps[0].To != (quran.Ref{Surah: 2, Ayah: 134}) || ps[29].From != quran.PageStarts[583] { t.Fatalf("page 30: %+v %+v", ps[0], ps[29]) }
+  if ps, _ = Split(UnitPage, quran.MushafPages); ps[1].Range() != quran.PageRange(2) { t.Fatalf("page 604: %+v", ps[1]) }
+
+  for _, tc := range []struct {
+    unit string
+    n    int
+    want error
+  }{{UnitJuz, 31, ErrInvalid}, {UnitAyah, 0, ErrInvalid}, {UnitPage, 605, ErrInvalid}, {"hizb", 60, ErrInvalid}} {
+    if _, err := Split(tc.unit, tc.n); !errors.Is(err, tc.want) { t.Errorf("%s %d: %v, want %v", tc.unit, tc.n, err, tc.want) }
+  }
+}
+
+func TestStore(t *testing.T) {
+  ctx := context.Background()
+  d := testDB(t)
+
+  p, err := Create(ctx, d, Spec{Name: "Ramadan", Parts: 30, Start: "2026-02-18"}, now)
+  if err != nil { t.Fatal(err) }
+  g, err := Create(ctx, d, Spec{Mode: ModeParticipants, Unit: UnitAyah, Participants: []string{"Aisha", " Umar "}}, now)
+  if err != nil { t.Fatal(err) }
+  if g.Name != "Khatm" || g.Start != "2026-03-01" || g.Parts != 2 || g.Portions[1].Participant != "Umar" { t.Fatalf("defaults: %+v", g) }
+
+  if err := SetDone(ctx, d, p.ID, 1, true, now); err != nil { t.Fatal(err) }
+  if err := SetDone(ctx, d, p.ID, 2, true, now); err != nil { t.Fatal(err) }
+  if err := SetDone(ctx, d, p.ID, 2, false, now); err != nil { t.Fatal(err) }
+  if err := SetDone(ctx, d, p.ID, 31, true, now); !errors.Is(err, ErrInvalid) { t.Fatalf("part 31: %v", err) }
+  if err := SetDone(ctx, d, 99, 1, true, now); !errors.Is(err, ErrNotFound) { t.Fatalf("plan 99: %v", err) }
+
+  got, err := Get(ctx, d, p.ID)
+  if err != nil { t.Fatal(err) }
+  if got.Done != 1 || !got.Portions[0].Done || got.Portions[0].DoneAt != "2026-03-01 20:00:00" || got.Portions[29].Day != "2026-03-19" { t.Fatalf("get: %+v", got) }
+  today, overdue := got.Today(now, "")
+  if len(today) != 1 || today[0].Part != 12 || len(overdue) != 10 || overdue[0].Part != 2 { t.Fatalf("today: %+v overdue: %+v", today, overdue) }
+
+  g, _ = Get(ctx, d, g.ID)
+  if today, _ := g.Today(now, "umar"); len(today) != 1 || today[0].Part != 2 { t.Fatalf("umar: %+v", today) }
+
+  list, err := List(ctx, d)
+  if err != nil { t.Fatal(err) }
+  if len(list) != 2 || list[0].ID != g.ID || list[1].Done != 1 || list[1].Portions != nil { t.Fatalf("list: %+v", list) }
+
+  if err := Delete(ctx, d, p.ID); err != nil { t.Fatal(err) }
+  if _, err := Get(ctx, d, p.ID); !errors.Is(err, ErrNotFound) { t.Fatalf("deleted: %v", err) }
+  if err := Delete(ctx, d, p.ID); !errors.Is(err, ErrNotFound) { t.Fatalf("delete twice: %v", err) }
+
+  for _, s := range []Spec{
+    {Mode: ModeParticipants, Parts: 3, Participants: []string{"a", "b"}},
+    {Participants: []string{"a"}},
+    {Parts: 10, Start: "1 March"},
+    {Parts: 10, Mode: "weeks"},
+  } {
+    if _, err := Create(ctx, d, s, now); !errors.Is(err, ErrInvalid) { t.Errorf("%+v: %v", s, err) }
+  }
+}
+
+func testDB(t *testing.T) *sqlx.DB {
+  t.Helper()
+  d, err := sqlx.Open("sqlite", ":memory:")
+  if err != nil { t.Fatal(err) }
+  d.SetMaxOpenConns(1)
+  t.Cleanup(func() { d.Close() })
+  if err := db.Migrate(context.Background(), d); err != nil { t.Fatal(err) }
+  return d
+}
@@ -0,0 +1,158 @@
+package search
+
+import (
+  "sort"
+  "strings"
+  "unicode"
+
+  "golang.org/x/text/unicode/norm"
+)
+
+// Term is an indexed translation word and the number of ayah containing it.
+type Term struct {
+  Text string `db:"term"`
+  Docs int    `db:"docs"`
+}
+
+// Vocab is the translation vocabulary of the search index, grouped by stem,
+// used to widen query words to their variants and to correct typos.
+type Vocab struct {
+  lang  string
+  docs  map[string]int
+  stems map[string][]string // stem -> words, most frequent first
+}
+
+// NewVocab indexes terms for lang (the primary translation language).
+func NewVocab(lang string, terms []Term) *Vocab {
+  v := &Vocab{lang: lang, docs: make(map[string]int, len(terms)), stems: map[string][]string{}}
+  for _, t := range terms {
+    if isLatin(t.Text) { v.docs[t.Text] += t.Docs }
+  }
+  known := func(w string) bool { return v.docs[w] > 0 }
+  for w := range v.docs {
+    st := stem(lang, w, known)
+    v.stems[st] = append(v.stems[st], w)
+  }
+  for _, ws := range v.stems { v.byDocs(ws) }
+  return v
+}
+
+func (v *Vocab) byDocs(ws []string) {
+  sort.Slice(ws, func(i, j int) bool {
+    if v.docs[ws[i]] != v.docs[ws[j]] { return v.docs[ws[i]] > v.docs[ws[j]] }
+    return ws[i] < ws[j]
+  })
+}
+
+// Len is the number of distinct words.
+func (v *Vocab) Len() int { return len(v.docs) }
+
+// Expansion is what one query word matches.
+type Expansion struct {
+  Word  string   // normalized query word
+  Terms []string // index terms to match (any of)
+  Fix   string   // closest indexed word when Word is a likely typo
+}
+
+// maxTerms bounds how many alternatives one query word expands to.
+const maxTerms = 12
+
+// Expand widens a query word to the indexed words sharing its stem; a word
+// that is not indexed at all is matched against words within a small edit
+// distance instead, and the closest becomes its suggested fix. Arabic and
+// other non-Latin words are kept as they are.
+func (v *Vocab) Expand(word string) Expansion {
+  w := Normalize(word)
+  e := Expansion{Word: w, Terms: []string{w}}
+  if v == nil || !isLatin(w) { return e }
+  known := func(s string) bool { return v.docs[s] > 0 }
+  if sib := v.stems[stem(v.lang, w, known)]; len(sib) > 0 {
+    e.Terms = merge(w, sib, known(w))
+    return e
+  }
+  if known(w) { return e }
+
+  max := 1
+  if len([]rune(w)) > 5 { max = 2 }
+  type cand struct {
+    w string
+    d int
+  }
+  var cs []cand
+  for t := range v.docs {
+    if d := Distance(w, t, max); d <= max { cs = append(cs, cand{t, d}) }
+  }
+  if len(cs) == 0 { return e }
+  sort.Slice(cs, func(i, j int) bool {
+    if cs[i].d != cs[j].d { return cs[i].d < cs[j].d }
+    if v.docs[cs[i].w] != v.docs[cs[j].w] { return v.docs[cs[i].w] > v.docs[cs[j].w] }
+    return cs[i].w < cs[j].w
+  })
+  // the word itself stays, in case the vocabulary predates it
+  e.Fix = cs[0].w
+  for _, c := range cs {
+    if len(e.Terms) >= maxTerms { break }
+    e.Terms = merge(w, append(e.Terms, v.stems[stem(v.lang, c.w, known)]...), true)
+  }
+  return e
+}
+
+// merge dedupes terms, keeping order, with w first when keep is set.
+func merge(w string, terms []string, keep bool) []string {
+  out := []string{}
+  seen := map[string]bool{}
+  if keep { out = append(out, w); seen[w] = true }
+  for _, t := range terms {
+    if seen[t] || len(out) >= maxTerms { continue }
+    seen[t] = true
+    out = append(out, t)
+  }
+  return out
+}
+
+// Normalize lowercases w and strips Latin diacritics, as the FTS5 unicode61
+// tokenizer does when indexing.
+func Normalize(w string) string {
+  w = strings.ToLower(w)
+  if !isLatin(w) { return w }
+  b := &strings.Builder{}
+  for _, r := range norm.NFD.String(w) {
+    if !unicode.Is(unicode.Mn, r) { b.WriteRune(r) }
+  }
+  return norm.NFC.String(b.String())
+}
+
+// isLatin reports whether w has a Latin letter and no letters of other scripts.
+func isLatin(w string) bool {
+  latin := false
+  for _, r := range w {
+    if unicode.IsLetter(r) {
+      if !unicode.Is(unicode.Latin, r) { return false }
+      latin = true
+    }
+  }
+  return latin
+}
+
+// Distance is the Levenshtein distance between a and b in runes, or max+1
+// once it is known to exceed max.
+func Distance(a, b string, max int) int {
+  ra, rb := []rune(a), []rune(b)
+  if d := len(ra) - len(rb); d > max || -d > max { return max + 1 }
+  prev := make([]int, len(rb)+1)
+  cur := make([]int, len(rb)+1)
+  for j := range prev { prev[j] = j }
+  for i := 1; i <= len(ra); i++ {
+    cur[0] = i
+    best := cur[0]
+    for j := 1; j <= len(rb); j++ {
+      cost := 1
+      if ra[i-1] == rb[j-1] { cost = 0 }
+      cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+      best = min(best, cur[j])
+    }
+    if best > max { return max + 1 }
+    prev, cur = cur, prev
+  }
+  return min(prev[len(rb)], max+1)
+}
@@ -0,0 +1,155 @@
+package search
+
+import (
+  "sort"
+  "strings"
+  "unicode"
+)
+
+// Span is a matched range of a text in runes (Unicode code points),
+// [Start, End).
+type Span struct {
+  Start int `json:"start"`
+  End   int `json:"end"`
+}
+
+// Highlight returns the ranges of text, a value of column col (ColArabic or
+// ColTrans), matched by the query's positive terms and their expansions.
+// Text is split into words the way the index does (runs of letters and
+// digits), so frontends can mark the result without the engine producing
+// markup. Combining marks and tatweel belong to the word they sit on, so
+// a matched Arabic word is marked up to its final haraka.
+func (q *Query) Highlight(col, text string) []Span {
+  words := tokenize(text)
+  var spans []Span
+  var rec func(n *node)
+  rec = func(n *node) {
+    if n.neg { return }
+    if n.kind != kindTerm {
+      for _, k := range n.kids { rec(k) }
+      return
+    }
+    if n.col != "" && n.col != col { return }
+    for _, p := range patterns(n) { spans = append(spans, p.find(words)...) }
+  }
+  rec(q.root)
+  return mergeSpans(spans)
+}
+
+type word struct {
+  text       string // normalized
+  start, end int
+}
+
+const tatweel = '\u0640'
+
+func tokenize(s string) []word {
+  var out []word
+  start, pos := -1, 0
+  b := &strings.Builder{}
+  flush := func() {
+    if start >= 0 { out = append(out, word{Normalize(b.String()), start, pos}) }
+    start = -1
+    b.Reset()
+  }
+  for _, r := range s {
+    if unicode.Is(unicode.Mn, r) || r == tatweel {
+      if start < 0 { start = pos }
+    } else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+      if start < 0 { start = pos }
+      b.WriteRune(r)
+    } else {
+      flush()
+    }
+    pos++
+  }
+  flush()
+  return out
+}
+
+// pattern is a sequence of words; the last one may be a prefix.
+type pattern struct {
+  words  []string
+  prefix bool
+}
+
+func patterns(n *node) []pattern {
+  if len(n.alts) > 0 {
+    ps := make([]pattern, 0, len(n.alts))
+    for _, a := range n.alts { ps = append(ps, newPattern(a, false)) }
+    return ps
+  }
+  return []pattern{newPattern(strings.Join(n.words, " "), n.prefix)}
+}
+
+func newPattern(s string, prefix bool) pattern {
+  p := pattern{prefix: prefix}
+  for _, w := range tokenize(s) { p.words = append(p.words, w.text) }
+  return p
+}
+
+func (p pattern) find(words []word) []Span {
+  if len(p.words) == 0 { return nil }
+  var out []Span
+  for i := 0; i+len(p.words) <= len(words); i++ {
+    ok := true
+    for j, w := range p.words {
+      t := words[i+j].text
+      if p.prefix && j == len(p.words)-1 { ok = strings.HasPrefix(t, w) } else { ok = t == w }
+      if !ok { break }
+    }
+    if ok { out = append(out, Span{words[i].start, words[i+len(p.words)-1].end}) }
+  }
+  return out
+}
+
+// mergeSpans sorts spans and joins overlapping ones.
+func mergeSpans(s []Span) []Span {
+  if len(s) == 0 { return nil }
+  sort.Slice(s, func(i, j int) bool { return s[i].Start < s[j].Start })
+  out := []Span{s[0]}
+  for _, x := range s[1:] {
+    last := &out[len(out)-1]
+    if x.Start <= last.End {
+      if x.End > last.End { last.End = x.End }
+      continue
+    }
+    out = append(out, x)
+  }
+  return out
+}
+
+// Segment is a piece of a text that is either all matched or unmatched.
+type Segment struct {
+  Text  string
+  Match bool
+}
+
+// Segments splits text at spans, skipping spans that overlap or fall
+// outside it.
+func Segments(text string, spans []Span) []Segment {
+  rs := []rune(text)
+  var out []Segment
+  at := 0
+  for _, s := range spans {
+    if s.Start < at || s.End > len(rs) || s.Start >= s.End { continue }
+    if s.Start > at { out = append(out, Segment{string(rs[at:s.Start]), false}) }
+    out = append(out, Segment{string(rs[s.Start:s.End]), true})
+    at = s.End
+  }
+  if at < len(rs) { out = append(out, Segment{string(rs[at:]), false}) }
+  return out
+}
+
+// Mark wraps the spans of text in open and close, passing every piece of
+// text through escape first (nil leaves it as is).
+func Mark(text string, spans []Span, open, close string, escape func(string) string) string {
+  if escape == nil { escape = func(s string) string { return s } }
+  b := &strings.Builder{}
+  for _, seg := range Segments(text, spans) {
+    if seg.Match { b.WriteString(open) }
+    b.WriteString(escape(seg.Text))
+    if seg.Match { b.WriteString(close) }
+  }
+  return b.String()
+}
@@ -0,0 +1,401 @@
+package search
+
+import (
+  "errors"
+  "fmt"
+  "slices"
+  "sort"
+  "strings"
+  "unicode"
+)
+
+// ErrSyntax is wrapped by every Parse error; servers answer it with 400.
+var ErrSyntax = errors.New("invalid query")
+
+// Searchable columns and the field prefixes that select them.
+const (
+  ColArabic = "ar"
+  ColTrans  = "tr"
+)
+
+var fields = map[string]string{"ar": ColArabic, "arabic": ColArabic, "tr": ColTrans, "trans": ColTrans}
+
+// Query is a parsed search query:
+//
+//    mercy lord          both words (AND is implied)
+//    mercy OR grace      either word
+//    mercy -fire         NOT, also written "mercy NOT fire"
+//    "lord of the"       phrase
+//    merc*               prefix
+//    tr:lord ar:الله     field: translation or Arabic text
+//    (a OR b) c          grouping
+//
+// Operators are uppercase; anything else is a search word, and words are
+// always quoted when compiled, so input can never reach the engine as syntax.
+type Query struct {
+  src  string
+  root *node
+}
+
+type nodeKind int
+
+const (
+  kindTerm nodeKind = iota
+  kindAnd
+  kindOr
+)
+
+type node struct {
+  kind nodeKind
+  kids []*node
+  neg  bool // excluded from the enclosing AND
+
+  // terms
+  words    []string // one word, or a phrase
+  phrase   bool
+  prefix   bool
+  col      string
+  pos, end int // byte span of a single word in the source
+  alts     []string
+  fix      string
+}
+
+// Parse parses q. Errors wrap ErrSyntax and name the byte offset.
+func Parse(q string) (*Query, error) {
+  toks, err := lex(q)
+  if err != nil { return nil, err }
+  p := &parser{src: q, toks: toks}
+  if len(toks) == 0 { return nil, p.errorf(0, "no search words") }
+  root, err := p.or("")
+  if err != nil { return nil, err }
+  if t := p.peek(); t.kind != tokEOF { return nil, p.errorf(t.pos, "unexpected %q", t.text) }
+  return &Query{src: q, root: root}, nil
+}
+
+// Expand widens plain words outside ar: to their variants in v (see
+// Vocab.Expand); phrases, prefixes and Arabic are left alone.
+func (q *Query) Expand(v *Vocab) {
+  q.walk(func(n *node) {
+    if n.phrase || n.prefix || n.col == ColArabic || len(n.words) != 1 { return }
+    e := v.Expand(n.words[0])
+    n.alts, n.fix = e.Terms, e.Fix
+  })
+}
+
+// Suggestion is the query with each misspelt word replaced by its fix, or
+// "" when Expand corrected nothing.
+func (q *Query) Suggestion() string {
+  var fixes []*node
+  q.walk(func(n *node) {
+    if n.fix != "" { fixes = append(fixes, n) }
+  })
+  if len(fixes) == 0 { return "" }
+  sort.Slice(fixes, func(i, j int) bool { return fixes[i].pos > fixes[j].pos })
+  s := q.src
+  for _, n := range fixes { s = s[:n.pos] + n.fix + s[n.end:] }
+  return s
+}
+
+func (q *Query) walk(fn func(*node)) {
+  var rec func(*node)
+  rec = func(n *node) {
+    if n.kind == kindTerm { fn(n); return }
+    for _, k := range n.kids { rec(k) }
+  }
+  rec(q.root)
+}
+
+// FTS5 compiles the query to an SQLite FTS5 MATCH expression over the
+// ayah_fts columns arabic and trans.
+func (q *Query) FTS5() string { return compile(q.root, fts5) }
+
+// TSQuery compiles the query for PostgreSQL to_tsquery, where Arabic is
+// weighted A and the translation B.
+func (q *Query) TSQuery() string { return compile(q.root, tsquery) }
+
+type dialect struct {
+  and, or string
+  not     func(pos, neg string) string
+  term    func(n *node) string
+}
+
+var fts5 = dialect{
+  and: " AND ",
+  or:  " OR ",
+  not: func(pos, neg string) string { return pos + " NOT " + neg },
+  term: func(n *node) string {
+    col := map[string]string{ColArabic: "arabic : ", ColTrans: "trans : "}[n.col]
+    quote := func(s string) string { return `"` + strings.ReplaceAll(s, `"`, `""`) + `"` }
+    if len(n.alts) > 0 {
+      qs := make([]string, len(n.alts))
+      for i, a := range n.alts { qs[i] = quote(a) }
+      if len(qs) == 1 { return col + qs[0] }
+      return col + "(" + strings.Join(qs, " OR ") + ")"
+    }
+    s := col + quote(strings.Join(n.words, " "))
+    if n.prefix { s += " *" }
+    return s
+  },
+}
+
+var tsquery = dialect{
+  and: " & ",
+  or:  " | ",
+  not: func(pos, neg string) string { return pos + " & !" + neg },
+  term: func(n *node) string {
+    weight := map[string]string{ColArabic: "A", ColTrans: "B"}[n.col]
+    lexeme := func(s string, prefix bool) string {
+      l := `'` + tsEscape.Replace(s) + `'`
+      if prefix || weight != "" { l += ":" }
+      if prefix { l += "*" }
+      return l + weight
+    }
+    if len(n.alts) > 0 {
+      ls := make([]string, len(n.alts))
+      for i, a := range n.alts { ls[i] = lexeme(a, false) }
+      if len(ls) == 1 { return ls[0] }
+      return "(" + strings.Join(ls, " | ") + ")"
+    }
+    ls := make([]string, len(n.words))
+    for i, w := range n.words { ls[i] = lexeme(w, n.prefix && i == len(n.words)-1) }
+    if len(ls) == 1 { return ls[0] }
+    return "(" + strings.Join(ls, " <-> ") + ")"
+  },
+}
+
+// tsEscape quotes a tsquery lexeme's contents: ' doubles and \ escapes.
+var tsEscape = strings.NewReplacer(`'`, `''`, `\`, `\\`)
+
+func compile(n *node, d dialect) string {
+  switch n.kind {
+  case kindTerm:
+    return d.term(n)
+  case kindOr:
+    parts := make([]string, len(n.kids))
+    for i, k := range n.kids { parts[i] = group(k, d) }
+    return strings.Join(parts, d.or)
+  }
+  var pos, neg []string
+  for _, k := range n.kids {
+    if k.neg { neg = append(neg, group(k, d)) } else { pos = append(pos, group(k, d)) }
+  }
+  s := strings.Join(pos, d.and)
+  if len(neg) > 0 {
+    if len(pos) > 1 { s = "(" + s + ")" }
+    for _, x := range neg { s = d.not(s, x) }
+  }
+  return s
+}
+
+// group compiles n, parenthesized unless it is a single term.
+func group(n *node, d dialect) string {
+  s := compile(n, d)
+  if n.kind == kindTerm { return s }
+  return "(" + s + ")"
+}
+
+type tokKind int
+
+const (
+  tokEOF tokKind = iota
+  tokWord
+  tokPhrase
+  tokField
+  tokLParen
+  tokRParen
+  tokAnd
+  tokOr
+  tokNot
+)
+
+type token struct {
+  kind     tokKind
+  text     string
+  prefix   bool
+  pos, end int
+}
+
+const special = `()"`
+
+func lex(q string) ([]token, error) {
+  var toks []token
+  for i := 0; i < len(q); {
+    r := rune(q[i])
+    switch {
+    case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+      i++
+    case r == '(':
+      toks = append(toks, token{kind: tokLParen, text: "(", pos: i, end: i + 1})
+      i++
+    case r == ')':
+      toks = append(toks, token{kind: tokRParen, text: ")", pos: i, end: i + 1})
+      i++
+    case r == '"':
+      j := strings.IndexByte(q[i+1:], '"')
+      if j < 0 { return nil, syntaxErr(q, i, "unterminated quote") }
+      t := token{kind: tokPhrase, text: q[i+1 : i+1+j], pos: i, end: i + j + 2}
+      i = t.end
+      if i < len(q) && q[i] == '*' { t.prefix = true; i++ }
+      toks = append(toks, t)
+    case r == '-' && i+1 < len(q) && !strings.ContainsRune(" \t\n\r)", rune(q[i+1])):
+      toks = append(toks, token{kind: tokNot, text: "-", pos: i, end: i + 1})
+      i++
+    default:
+      j := i
+      for j < len(q) && !strings.ContainsRune(" \t\n\r"+special, rune(q[j])) { j++ }
+      w := q[i:j]
+      if k := strings.IndexByte(w, ':'); k > 0 && isField(w[:k]) {
+        col, ok := fields[strings.ToLower(w[:k])]
+        if !ok { return nil, syntaxErr(q, i, fmt.Sprintf("unknown field %q (use ar: or tr:)", w[:k])) }
+        toks = append(toks, token{kind: tokField, text: col, pos: i, end: i + k + 1})
+        i += k + 1
+        continue
+      }
+      t := token{kind: tokWord, text: w, pos: i, end: j}
+      switch w {
+      case "AND":
+        t.kind = tokAnd
+      case "OR":
+        t.kind = tokOr
+      case "NOT":
+        t.kind = tokNot
+      }
+      if t.kind == tokWord {
+        if strings.HasSuffix(w, "*") { t.prefix = true; t.text = w[:len(w)-1]; t.end-- }
+        if strings.Contains(t.text, "*") || t.text == "" { return nil, syntaxErr(q, i, "* is only allowed at the end of a word") }
+      }
+      toks = append(toks, t)
+      i = j
+    }
+  }
+  return toks, nil
+}
+
+// isField reports whether s looks like a field name rather than part of a
+// word such as a verse reference (2:255).
+func isField(s string) bool {
+  for _, r := range s {
+    if !unicode.IsLetter(r) { return false }
+  }
+  return true
+}
+
+func syntaxErr(q string, pos int, msg string) error {
+  return fmt.Errorf("%w: %s at position %d", ErrSyntax, msg, len([]rune(q[:pos]))+1)
+}
+
+type parser struct {
+  src  string
+  toks []token
+  i    int
+}
+
+func (p *parser) peek() token {
+  if p.i < len(p.toks) { return p.toks[p.i] }
+  return token{kind: tokEOF, pos: len(p.src)}
+}
+
+func (p *parser) next() token { t := p.peek(); p.i++; return t }
+
+func (p *parser) errorf(pos int, format string, args ...any) error {
+  return syntaxErr(p.src, pos, fmt.Sprintf(format, args...))
+}
+
+// or := and ("OR" and)*
+func (p *parser) or(col string) (*node, error) {
+  first, err := p.and(col)
+  if err != nil { return nil, err }
+  kids := []*node{first}
+  for p.peek().kind == tokOr {
+    p.next()
+    n, err := p.and(col)
+    if err != nil { return nil, err }
+    kids = append(kids, n)
+  }
+  if len(kids) == 1 { return first, nil }
+  return &node{kind: kindOr, kids: kids}, nil
+}
+
+// and := unary (["AND"] unary)*, with at least one term not negated.
+func (p *parser) and(col string) (*node, error) {
+  start := p.peek()
+  var kids []*node
+  for {
+    t := p.peek()
+    if t.kind == tokAnd {
+      if len(kids) == 0 { return nil, p.errorf(t.pos, "AND needs a term before it") }
+      p.next()
+      t = p.peek()
+      if t.kind == tokEOF || t.kind == tokOr || t.kind == tokRParen { return nil, p.errorf(t.pos, "AND needs a term after it") }
+    }
+    if t.kind == tokEOF || t.kind == tokOr || t.kind == tokRParen { break }
+    n, err := p.unary(col)
+    if err != nil { return nil, err }
+    if n != nil { kids = append(kids, n) }
+  }
+  if len(kids) == 0 {
+    if start.kind == tokEOF || start.kind == tokOr || start.kind == tokRParen { return nil, p.errorf(start.pos, "missing search term") }
+    return nil, p.errorf(start.pos, "no search words")
+  }
+  positive := false
+  for _, k := range kids { positive = positive || !k.neg }
+  if !positive { return nil, p.errorf(start.pos, "a query cannot only exclude terms") }
+  if len(kids) == 1 { return kids[0], nil }
+  return &node{kind: kindAnd, kids: kids}, nil
+}
+
+// unary := ["NOT" | "-"] primary
+func (p *parser) unary(col string) (*node, error) {
+  neg := false
+  if t := p.peek(); t.kind == tokNot {
+    p.next()
+    neg = true
+    if n := p.peek().kind; n == tokEOF || n == tokOr || n == tokRParen || n == tokNot || n == tokAnd {
+      return nil, p.errorf(t.pos, "%s needs a term after it", t.text)
+    }
+  }
+  n, err := p.primary(col)
+  if err != nil || n == nil { return n, err }
+  n.neg = neg
+  return n, nil
+}
+
+// primary := "(" or ")" | field primary | word | phrase. It returns nil for
+// words without letters or digits, which the index would ignore anyway.
+func (p *parser) primary(col string) (*node, error) {
+  t := p.next()
+  switch t.kind {
+  case tokLParen:
+    n, err := p.or(col)
+    if err != nil { return nil, err }
+    if c := p.next(); c.kind != tokRParen { return nil, p.errorf(t.pos, "unclosed parenthesis") }
+    return n, nil
+  case tokField:
+    if col != "" && col != t.text { return nil, p.errorf(t.pos, "conflicting fields") }
+    switch p.peek().kind {
+    case tokWord, tokPhrase, tokLParen:
+    default:
+      return nil, p.errorf(t.pos, "%s: needs a term after it", t.text)
+    }
+    return p.primary(t.text)
+  case tokWord, tokPhrase:
+    words := strings.FieldsFunc(t.text, func(r rune) bool { return unicode.IsSpace(r) })
+    words = slices.DeleteFunc(words, func(w string) bool { return !hasWordChar(w) })
+    if len(words) == 0 {
+      if t.kind == tokPhrase { return nil, p.errorf(t.pos, "empty phrase") }
+      return nil, nil
+    }
+    n := &node{kind: kindTerm, words: words, phrase: t.kind == tokPhrase, prefix: t.prefix, col: col, pos: t.pos, end: t.end}
+    return n, nil
+  case tokRParen:
+    return nil, p.errorf(t.pos, "unmatched parenthesis")
+  }
+  return nil, p.errorf(t.pos, "unexpected %q", t.text)
+}
+
+func hasWordChar(w string) bool {
+  for _, r := range w {
+    if unicode.IsLetter(r) || unicode.IsDigit(r) { return true }
+  }
+  return false
+}
@@ -0,0 +1,177 @@
+package search
+
+import (
+  "errors"
+  "strings"
+  "testing"
+)
+
+func TestStem(t *testing.T) {
+  groups := []struct {
+    lang  string
+    words []string
+  }{
+    {"en", []string{"mercy", "mercies", "merciful", "mercifully"}},
+    {"en", []string{"believe", "believed", "believes", "believing", "believer", "believers"}},
+    {"en", []string{"sin", "sins", "sinned", "sinning"}},
+    {"id", []string{"ampun", "ampunan", "pengampun", "mengampuni", "diampuni"}},
+    {"id", []string{"sembah", "menyembah", "disembah", "penyembahan"}},
+    {"id", []string{"dengar", "mendengar", "pendengaran", "didengarnya"}},
+  }
+  for _, g := range groups {
+    want := Stem(g.lang, g.words[0])
+    for _, w := range g.words[1:] {
+      if got := Stem(g.lang, w); got != want { t.Errorf("%s: Stem(%q) = %q, Stem(%q) = %q", g.lang, w, got, g.words[0], want) }
+    }
+  }
+  if Stem("ar", "رحمة") != "رحمة" { t.Fatal("unknown language should be unchanged") }
+}
+
+func TestStem_IndonesianDictionary(t *testing.T) {
+  known := map[string]bool{"kasih": true, "tuhan": true, "murah": true, "beri": true}
+  has := func(w string) bool { return known[w] }
+  for w, want := range map[string]string{"pengasih": "kasih", "mengasihi": "kasih", "tuhanmu": "tuhan", "pemurah": "murah", "tuhan": "tuhan", "memberi": "beri", "diberikan": "beri"} {
+    if got := stem("id", w, has); got != want { t.Errorf("stem(%q) = %q, want %q", w, got, want) }
+  }
+}
+
+func TestDistance(t *testing.T) {
+  cases := []struct {
+    a, b string
+    max, want int
+  }{
+    {"merciful", "mercifull", 2, 1},
+    {"kasih", "kasi", 2, 1},
+    {"allah", "alah", 1, 1},
+    {"night", "light", 2, 1},
+    {"abc", "xyz", 2, 3},
+    {"short", "muchlongerword", 2, 3},
+    {"رحمن", "رحمان", 1, 1},
+  }
+  for _, c := range cases {
+    if got := Distance(c.a, c.b, c.max); got != c.want { t.Errorf("Distance(%q,%q,%d) = %d, want %d", c.a, c.b, c.max, got, c.want) }
+  }
+}
+
+func TestVocab_Expand(t *testing.T) {
+  v := NewVocab("en", []Term{
+    {"merciful", 100}, {"mercy", 50}, {"mercies", 3}, {"lord", 900}, {"worlds", 70}, {"world", 40}, {"words", 30},
+    {"الله", 2000},
+  })
+  e := v.Expand("Mercy")
+  if e.Word != "mercy" || e.Fix != "" || len(e.Terms) != 3 || e.Terms[0] != "mercy" { t.Fatalf("stem variants: %+v", e) }
+
+  e = v.Expand("mercifull")
+  if e.Fix != "merciful" || len(e.Terms) != 4 || e.Terms[0] != "mercifull" { t.Fatalf("typo: %+v", e) }
+
+  e = v.Expand("lrod")
+  if e.Fix != "" || len(e.Terms) != 1 || e.Terms[0] != "lrod" { t.Fatalf("too far for a 4-letter word: %+v", e) }
+
+  e = v.Expand("wrlds")
+  if e.Fix != "worlds" { t.Fatalf("closest, most frequent fix: %+v", e) }
+
+  e = v.Expand("الله")
+  if len(e.Terms) != 1 || e.Fix != "" { t.Fatalf("arabic is kept: %+v", e) }
+
+}
+
+func TestParse(t *testing.T) {
+  cases := []struct{ q, fts5, tsq string }{
+    {"lord", `"lord"`, `'lord'`},
+    {"Lord  of worlds", `"Lord" AND "of" AND "worlds"`, `'Lord' & 'of' & 'worlds'`},
+    {"mercy OR grace", `"mercy" OR "grace"`, `'mercy' | 'grace'`},
+    {"mercy -fire NOT hell", `"mercy" NOT "fire" NOT "hell"`, `'mercy' & !'fire' & !'hell'`},
+    {"a b -c", `("a" AND "b") NOT "c"`, `('a' & 'b') & !'c'`},
+    {`"lord of the worlds"`, `"lord of the worlds"`, `('lord' <-> 'of' <-> 'the' <-> 'worlds')`},
+    {"merc*", `"merc" *`, `'merc':*`},
+    {`tr:lord ar:"بسم الله"`, `trans : "lord" AND arabic : "بسم الله"`, `'lord':B & ('بسم':A <-> 'الله':A)`},
+    {"tr:(a OR b*) c", `(trans : "a" OR trans : "b" *) AND "c"`, `('a':B | 'b':*B) & 'c'`},
+    {"(a OR b) AND c", `("a" OR "b") AND "c"`, `('a' | 'b') & 'c'`},
+    {`say "it's" 2:255 ?`, `"say" AND "it's" AND "2:255"`, `'say' & 'it''s' & '2:255'`},
+    {`a\`, `"a\"`, `'a\\'`},
+    {`a"b`, ``, ``},
+  }
+  for _, c := range cases {
+    q, err := Parse(c.q)
+    if c.fts5 == "" {
+      if !errors.Is(err, ErrSyntax) { t.Errorf("Parse(%q): expected syntax error, got %v", c.q, err) }
+      continue
+    }
+    if err != nil { t.Errorf("Parse(%q): %v", c.q, err); continue }
+    if got := q.FTS5(); got != c.fts5 { t.Errorf("FTS5(%q) = %s, want %s", c.q, got, c.fts5) }
+    if got := q.TSQuery(); got != c.tsq { t.Errorf("TSQuery(%q) = %s, want %s", c.q, got, c.tsq) }
+  }
+}
+
+func TestParse_Errors(t *testing.T) {
+  for q, msg := range map[string]string{
+    `"lord of`:     "unterminated quote at position 1",
+    "(mercy":       "unclosed parenthesis",
+    "mercy)":       "unexpected",
+    "mercy OR":     "missing search term",
+    "AND mercy":    "AND needs a term before it",
+    "mercy AND":    "AND needs a term after it",
+    "-fire":        "cannot only exclude",
+    "mercy NOT":    "NOT needs a term after it",
+    "lo*rd":        "* is only allowed at the end",
+    "*":            "* is only allowed at the end",
+    "foo:bar":      `unknown field "foo"`,
+    "tr:":          "needs a term after it",
+    "tr:(ar:x)":    "conflicting fields",
+    `""`:           "empty phrase",
+    "? !":          "no search words",
+    "()":           "missing search term",
+    "mercy OR -x":  "cannot only exclude",
+  } {
+    _, err := Parse(q)
+    if !errors.Is(err, ErrSyntax) || !strings.Contains(err.Error(), msg) { t.Errorf("Parse(%q) = %v, want %q", q, err, msg) }
+  }
+}
+
+func TestQuery_ExpandAndSuggest(t *testing.T) {
+  v := NewVocab("en", []Term{{"merciful", 100}, {"mercy", 50}, {"lord", 900}, {"worlds", 70}, {"world", 40}})
+  q, err := Parse(`lord of the wrlds -mercifull "the wrlds" ar:wrlds wrld*`)
+  if err != nil { t.Fatal(err) }
+  q.Expand(v)
+  want := `("lord" AND "of" AND "the" AND ("wrlds" OR "worlds" OR "world") AND "the wrlds" AND arabic : "wrlds" AND "wrld" *) NOT ("mercifull" OR "merciful" OR "mercy")`
+  if got := q.FTS5(); got != want { t.Fatalf("FTS5:\n got %s\nwant %s", got, want) }
+  if s := q.Suggestion(); s != `lord of the worlds -merciful "the wrlds" ar:wrlds wrld*` { t.Fatalf("suggestion: %q", s) }
+
+  q, _ = Parse("tr:mercy")
+  q.Expand(v)
+  if got := q.TSQuery(); got != `('mercy':B | 'merciful':B)` { t.Fatalf("tsquery: %s", got) }
+  if q.Suggestion() != "" { t.Fatalf("nothing to suggest: %q", q.Suggestion()) }
+}
+
+func TestNormalize(t *testing.T) {
+  if Normalize("Ádám") != "adam" { t.Fatalf("normalize: %q", Normalize("Ádám")) }
+  if Normalize("الله") != "الله" { t.Fatal("arabic is unchanged") }
+}
+
+func TestQuery_Highlight(t *testing.T) {
+  v := NewVocab("en", []Term{{"merciful", 100}, {"mercy", 50}, {"lord", 900}})
+  cases := []struct{ q, col, text, want string }{
+    {"lord", ColTrans, "The Lord, my LORD's lordship", "The [Lord], my [LORD]'s lordship"},
+    {"mercy", ColTrans, "Mercy of the Merciful", "[Mercy] of the [Merciful]"},
+    {"lord*", ColTrans, "lordship and lords", "[lordship] and [lords]"},
+    {`"lord of the"`, ColTrans, "Lord of the worlds; lord of mercy", "[Lord of the] worlds; lord of mercy"},
+    {"lord -mercy", ColTrans, "Lord of mercy", "[Lord] of mercy"},
+    {"tr:lord", ColArabic, "lord", "lord"},
+    {"ar:الله", ColArabic, "بسم الله الرحمن", "بسم [الله] الرحمن"},
+    {"café", ColTrans, "a <b>cafe</b> & Café", "a <b>[cafe]</b> & [Café]"},
+    {"of OR of the", ColTrans, "of the", "[of] [the]"},
+    // vowelled queries, as FTS5 only matches them against the same marks
+    {"بِسْمِ", ColArabic, "بِسْمِ اللَّـهِ الرَّحْمَٰنِ", "[بِسْمِ] اللَّـهِ الرَّحْمَٰنِ"},
+    {"اللَّـهِ", ColArabic, "بِسْمِ اللَّـهِ الرَّحْمَٰنِ", "بِسْمِ [اللَّـهِ] الرَّحْمَٰنِ"},
+    {"ar:الرَّحْمَٰنِ", ColArabic, "بِسْمِ اللَّـهِ الرَّحْمَٰنِ", "بِسْمِ اللَّـهِ [الرَّحْمَٰنِ]"},
+  }
+  for _, c := range cases {
+    q, err := Parse(c.q)
+    if err != nil { t.Fatal(err) }
+    q.Expand(v)
+    if got := Mark(c.text, q.Highlight(c.col, c.text), "[", "]", nil); got != c.want { t.Errorf("%s: %q, want %q", c.q, got, c.want) }
+  }
+  if got := Mark("<a> & b", []Span{{4, 5}}, "<mark>", "</mark>", func(s string) string { return strings.ReplaceAll(s, "<", "&lt;") }); got != "&lt;a> <mark>&</mark> b" {
+    t.Fatalf("escaped: %q", got)
+  }
+}
@@ -0,0 +1,150 @@
+package search
+
+import (
+  "strings"
+)
+
+// Stem reduces a lowercase translation word to a stem for grouping
+// morphological variants ("mercy", "mercies", "merciful"). English and
+// Indonesian have light rule-based stemmers; other languages are returned
+// unchanged. Stems are only compared with each other, never shown.
+func Stem(lang, word string) string { return stem(lang, word, nil) }
+
+// stem is Stem with an optional dictionary of known words, used to choose
+// between the roots an ambiguous Indonesian prefix allows (pengasih: asih or
+// kasih).
+func stem(lang, w string, known func(string) bool) string {
+  switch lang {
+  case "en":
+    return stemEN(w)
+  case "id", "ms":
+    return stemID(w, known)
+  }
+  return w
+}
+
+func isVowel(c byte) bool { return strings.IndexByte("aeiou", c) >= 0 }
+
+func hasVowel(s string) bool {
+  for i := 0; i < len(s); i++ {
+    if isVowel(s[i]) { return true }
+  }
+  return false
+}
+
+// stemEN strips plural and common derivational suffixes, then normalizes
+// the ending (undoubled consonant, no final e, y as i) so that e.g.
+// believe/believed/believing and mercy/mercies/merciful share a stem.
+func stemEN(w string) string {
+  if len(w) <= 3 { return w }
+  switch {
+  case strings.HasSuffix(w, "ies") && len(w) > 4:
+    w = w[:len(w)-3] + "y"
+  case strings.HasSuffix(w, "sses"):
+    w = w[:len(w)-2]
+  case strings.HasSuffix(w, "ss"), strings.HasSuffix(w, "us"), strings.HasSuffix(w, "is"):
+  case strings.HasSuffix(w, "s"):
+    w = w[:len(w)-1]
+  }
+  for _, suf := range []string{"fulness", "fully", "ful", "ness", "ment", "ingly", "edly", "ing", "ed", "ly", "er"} {
+    if r := strings.TrimSuffix(w, suf); r != w && len(r) >= 3 && hasVowel(r) {
+      if suf == "er" && len(r) < 4 { continue }
+      w = r
+      break
+    }
+  }
+  if n := len(w); n > 3 && w[n-1] == w[n-2] && !isVowel(w[n-1]) && w[n-1] != 'l' && w[n-1] != 's' {
+    w = w[:n-1]
+  }
+  if n := len(w); n > 3 && w[n-1] == 'e' { w = w[:n-1] }
+  if n := len(w); n > 2 && w[n-1] == 'y' { w = w[:n-1] + "i" }
+  return w
+}
+
+// stemID removes Indonesian particles (-lah, -kah), possessives
+// (-ku, -mu, -nya), derivational suffixes (-kan, -an, -i) and up to two
+// prefixes (di-, ke-, se-, ber-, ter-, per-, me(N)-, pe(N)-), following the
+// shape of Nazief & Adriani's algorithm. Nasal prefixes can hide the root's
+// first letter; with a dictionary the known root wins, otherwise the first
+// rule applies.
+func stemID(w string, known func(string) bool) string {
+  if len(w) <= 4 { return w }
+  for _, group := range [][]string{{"lah", "kah"}, {"nya", "ku", "mu"}} {
+    for _, suf := range group {
+      if r := strings.TrimSuffix(w, suf); r != w && len(r) >= 4 { w = r; break }
+    }
+  }
+  stems := []string{w}
+  for _, suf := range []string{"kan", "an", "i"} {
+    if r := strings.TrimSuffix(w, suf); r != w && len(r) >= 3 { stems = append(stems, r); break }
+  }
+  if known != nil {
+    var cands []string
+    for _, s := range stems {
+      for _, r := range prefixesID(s) { cands = append(cands, r); cands = append(cands, prefixesID(r)...) }
+    }
+    cands = append(cands, stems[1:]...)
+    for _, c := range cands {
+      if known(c) { return c }
+    }
+    if known(w) { return w }
+  }
+  return greedyID(stems[len(stems)-1])
+}
+
+func greedyID(s string) string {
+  for i := 0; i < 2; i++ {
+    rs := prefixesID(s)
+    if len(rs) == 0 { break }
+    s = rs[0]
+  }
+  return s
+}
+
+// prefixesID lists the roots left after removing one prefix from s.
+func prefixesID(s string) []string {
+  ok := func(r string) bool { return len(r) >= 3 && hasVowel(r) }
+  var out []string
+  add := func(rs ...string) {
+    for _, r := range rs {
+      if ok(r) { out = append(out, r) }
+    }
+  }
+  for _, p := range []string{"meng", "peng"} {
+    if r, found := strings.CutPrefix(s, p); found && r != "" {
+      if isVowel(r[0]) { add(r, "k"+r) } else { add(r) }
+      return out
+    }
+  }
+  for _, p := range []string{"meny", "peny"} {
+    if r, found := strings.CutPrefix(s, p); found && r != "" && isVowel(r[0]) {
+      add("s" + r)
+      return out
+    }
+  }
+  for _, p := range []string{"mem", "pem"} {
+    if r, found := strings.CutPrefix(s, p); found && r != "" {
+      if isVowel(r[0]) { add("p"+r, "m"+r) } else { add(r) }
+      return out
+    }
+  }
+  for _, p := range []string{"men", "pen"} {
+    if r, found := strings.CutPrefix(s, p); found && r != "" {
+      if isVowel(r[0]) { add("t"+r, "n"+r) } else { add(r) }
+      return out
+    }
+  }
+  for _, p := range []string{"ber", "ter", "per", "bel", "pel"} {
+    if r, found := strings.CutPrefix(s, p); found {
+      add(r)
+      if len(out) > 0 { return out }
+    }
+  }
+  for _, p := range []string{"me", "pe", "be", "te", "di", "ke", "se"} {
+    if r, found := strings.CutPrefix(s, p); found {
+      add(r)
+      return out
+    }
+  }
+  return out
+}
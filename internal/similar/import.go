@@ -0,0 +1,44 @@
+package similar
+
+import (
+  "bufio"
+  "context"
+  "encoding/json"
+  "fmt"
+  "io"
+
+  "github.com/jmoiron/sqlx"
+
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// Import replaces the stored vectors with embeddings computed elsewhere,
+// e.g. by a sentence-embedding model run locally over an export. r holds
+// one JSON object per line:
+//
+//    {"surah": 2, "ayah": 255, "vector": [0.12, -0.03, ...]}
+//
+// All vectors must have the same length; they are normalized on import.
+func Import(ctx context.Context, d *sqlx.DB, r io.Reader, model string) (int, error) {
+  vecs := map[quran.Ref][]float32{}
+  dims := 0
+  sc := bufio.NewScanner(r)
+  sc.Buffer(make([]byte, 1<<20), 16<<20)
+  for line := 1; sc.Scan(); line++ {
+    if len(sc.Bytes()) == 0 { continue }
+    var rec struct {
+      Surah  int       `json:"surah"`
+      Ayah   int       `json:"ayah"`
+      Vector []float32 `json:"vector"`
+    }
+    if err := json.Unmarshal(sc.Bytes(), &rec); err != nil { return 0, fmt.Errorf("line %d: %w", line, err) }
+    ref := quran.Ref{Surah: rec.Surah, Ayah: rec.Ayah}
+    if !ref.Valid() { return 0, fmt.Errorf("line %d: invalid ayah %s", line, ref) }
+    if dims == 0 { dims = len(rec.Vector) }
+    if dims == 0 || len(rec.Vector) != dims { return 0, fmt.Errorf("line %d: vector has %d dimensions, want %d", line, len(rec.Vector), dims) }
+    vecs[ref] = rec.Vector
+  }
+  if err := sc.Err(); err != nil { return 0, err }
+  if len(vecs) == 0 { return 0, fmt.Errorf("no vectors in input") }
+  return len(vecs), Save(ctx, d, model, vecs)
+}
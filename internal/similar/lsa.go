@@ -0,0 +1,276 @@
+package similar
+
+import (
+  "context"
+  "math"
+  "math/rand/v2"
+  "sort"
+  "strings"
+  "unicode"
+
+  "github.com/jmoiron/sqlx"
+
+  "github.com/foozio/quran-go/internal/search"
+  "github.com/foozio/quran-go/internal/verify"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// ModelLSA names vectors built by Build.
+const ModelLSA = "lsa-tfidf"
+
+// Dims is the number of LSA dimensions kept.
+const Dims = 128
+
+// Build computes LSA vectors for every ayah and replaces the stored ones.
+// Each ayah is a bag of stemmed primary-translation words plus Arabic words
+// without diacritics, weighted by TF-IDF; a truncated SVD of that matrix
+// (randomized, with a fixed seed so rebuilds are reproducible) maps ayah
+// into a space where verses sharing themes, not just words, lie close.
+func Build(ctx context.Context, d *sqlx.DB) (int, error) {
+  var docs []struct {
+    Surah  int    `db:"surah"`
+    Number int    `db:"number"`
+    Arabic string `db:"arabic"`
+    Trans  string `db:"trans"`
+  }
+  if err := d.SelectContext(ctx, &docs, `SELECT surah, number, arabic, COALESCE(trans,'') AS trans FROM ayah ORDER BY surah, number`); err != nil { return 0, err }
+  if len(docs) == 0 { return 0, nil }
+  var lang string
+  _ = d.GetContext(ctx, &lang, `SELECT value FROM meta WHERE key='primary_lang'`)
+
+  bags := make([]map[string]int, len(docs))
+  for i, doc := range docs { bags[i] = bag(lang, doc.Arabic, doc.Trans) }
+  a, _ := tfidf(bags)
+  vecs := lsa(a, Dims)
+
+  out := make(map[quran.Ref][]float32, len(docs))
+  for i, doc := range docs { out[quran.Ref{Surah: doc.Surah, Ayah: doc.Number}] = vecs[i] }
+  if err := ctx.Err(); err != nil { return 0, err }
+  return len(out), Save(ctx, d, ModelLSA, out)
+}
+
+// bag counts the terms of one ayah; translation stems and Arabic words are
+// prefixed so they never collide.
+func bag(lang, arabic, trans string) map[string]int {
+  b := map[string]int{}
+  split := func(s string) []string {
+    return strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+  }
+  for _, w := range split(trans) {
+    w = search.Normalize(w)
+    if len([]rune(w)) < 3 { continue }
+    b["t:"+search.Stem(lang, w)]++
+  }
+  ar, _ := verify.Normalize(arabic, verify.NormSimple)
+  for _, w := range split(ar) {
+    if len([]rune(w)) < 2 { continue }
+    b["a:"+w]++
+  }
+  return b
+}
+
+// entry is one non-zero of the sparse document-term matrix.
+type entry struct {
+  col int
+  w   float64
+}
+
+// tfidf weights the bags (1+log tf, log idf), drops terms found in one
+// document or in more than half of them, and L2-normalizes each row.
+func tfidf(bags []map[string]int) ([][]entry, int) {
+  df := map[string]int{}
+  for _, b := range bags {
+    for t := range b { df[t]++ }
+  }
+  n := len(bags)
+  terms := make([]string, 0, len(df))
+  for t, c := range df {
+    if c > 1 && c <= n/2 { terms = append(terms, t) }
+  }
+  sort.Strings(terms)
+  col := make(map[string]int, len(terms))
+  for i, t := range terms { col[t] = i }
+
+  rows := make([][]entry, n)
+  for i, b := range bags {
+    var norm float64
+    for t, tf := range b {
+      c, ok := col[t]
+      if !ok { continue }
+      w := (1 + math.Log(float64(tf))) * math.Log(float64(n)/float64(df[t]))
+      rows[i] = append(rows[i], entry{c, w})
+      norm += w * w
+    }
+    sort.Slice(rows[i], func(x, y int) bool { return rows[i][x].col < rows[i][y].col })
+    if norm > 0 {
+      norm = math.Sqrt(norm)
+      for j := range rows[i] { rows[i][j].w /= norm }
+    }
+  }
+  return rows, len(terms)
+}
+
+// lsa returns the rows of U_k·Σ_k for the sparse n×v matrix a, using a
+// randomized range finder with two power iterations (Halko et al.).
+func lsa(a [][]entry, k int) [][]float32 {
+  n := len(a)
+  v := 0
+  for _, row := range a {
+    for _, e := range row { v = max(v, e.col+1) }
+  }
+  l := min(k+10, n, v)
+  out := make([][]float32, n)
+  if l == 0 {
+    for i := range out { out[i] = make([]float32, 1) }
+    return out
+  }
+  k = min(k, l)
+
+  rng := rand.New(rand.NewPCG(1, 2))
+  omega := cols(l, v)
+  for _, c := range omega {
+    for j := range c { c[j] = rng.NormFloat64() }
+  }
+  y := mul(a, omega, n)
+  for it := 0; it < 2; it++ {
+    orthonormalize(y)
+    z := mulT(a, y, v)
+    orthonormalize(z)
+    y = mul(a, z, n)
+  }
+  q := y
+  orthonormalize(q)
+
+  // B = Qᵀ·A is l×v; its left singular vectors come from the eigen-
+  // decomposition of the small l×l matrix B·Bᵀ.
+  bt := mulT(a, q, v) // columns of Bᵀ: bt[c] is row c of B
+  c := make([][]float64, l)
+  for i := range c {
+    c[i] = make([]float64, l)
+    for j := 0; j <= i; j++ { c[i][j] = dot(bt[i], bt[j]); c[j][i] = c[i][j] }
+  }
+  vals, vecs := eigen(c)
+
+  for i := range out {
+    row := make([]float32, k)
+    for comp := 0; comp < k; comp++ {
+      s := math.Sqrt(math.Max(vals[comp], 0))
+      var u float64
+      for m := 0; m < l; m++ { u += q[m][i] * vecs[m][comp] }
+      row[comp] = float32(u * s)
+    }
+    out[i] = row
+  }
+  return out
+}
+
+// cols allocates c zero columns of length r.
+func cols(c, r int) [][]float64 {
+  m := make([][]float64, c)
+  for i := range m { m[i] = make([]float64, r) }
+  return m
+}
+
+// mul returns A·X for X given as columns of length v; the result has
+// columns of length n.
+func mul(a [][]entry, x [][]float64, n int) [][]float64 {
+  y := cols(len(x), n)
+  for c := range x {
+    xc, yc := x[c], y[c]
+    for i, row := range a {
+      var s float64
+      for _, e := range row { s += e.w * xc[e.col] }
+      yc[i] = s
+    }
+  }
+  return y
+}
+
+// mulT returns Aᵀ·Y for Y given as columns of length n.
+func mulT(a [][]entry, y [][]float64, v int) [][]float64 {
+  z := cols(len(y), v)
+  for c := range y {
+    yc, zc := y[c], z[c]
+    for i, row := range a {
+      if yc[i] == 0 { continue }
+      for _, e := range row { zc[e.col] += e.w * yc[i] }
+    }
+  }
+  return z
+}
+
+func dot(a, b []float64) float64 {
+  var s float64
+  for i := range a { s += a[i] * b[i] }
+  return s
+}
+
+// orthonormalize applies modified Gram-Schmidt to the columns in place;
+// columns that become (numerically) zero are left zero.
+func orthonormalize(m [][]float64) {
+  for i := range m {
+    for j := 0; j < i; j++ {
+      p := dot(m[i], m[j])
+      for r := range m[i] { m[i][r] -= p * m[j][r] }
+    }
+    nrm := math.Sqrt(dot(m[i], m[i]))
+    if nrm < 1e-10 {
+      for r := range m[i] { m[i][r] = 0 }
+      continue
+    }
+    for r := range m[i] { m[i][r] /= nrm }
+  }
+}
+
+// eigen diagonalizes the symmetric matrix s with cyclic Jacobi rotations.
+// It returns the eigenvalues in descending order and the eigenvectors as
+// the columns of vecs.
+func eigen(s [][]float64) ([]float64, [][]float64) {
+  n := len(s)
+  a := make([][]float64, n)
+  v := make([][]float64, n)
+  for i := range a {
+    a[i] = append([]float64(nil), s[i]...)
+    v[i] = make([]float64, n)
+    v[i][i] = 1
+  }
+  for sweep := 0; sweep < 100; sweep++ {
+    var off float64
+    for i := 0; i < n; i++ {
+      for j := i + 1; j < n; j++ { off += a[i][j] * a[i][j] }
+    }
+    if off < 1e-22 { break }
+    for p := 0; p < n; p++ {
+      for q := p + 1; q < n; q++ {
+        if math.Abs(a[p][q]) < 1e-300 { continue }
+        theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+        t := math.Copysign(1, theta) / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+        c := 1 / math.Sqrt(t*t+1)
+        sn := t * c
+        for k := 0; k < n; k++ {
+          akp, akq := a[k][p], a[k][q]
+          a[k][p], a[k][q] = c*akp-sn*akq, sn*akp+c*akq
+        }
+        for k := 0; k < n; k++ {
+          apk, aqk := a[p][k], a[q][k]
+          a[p][k], a[q][k] = c*apk-sn*aqk, sn*apk+c*aqk
+        }
+        for k := 0; k < n; k++ {
+          vkp, vkq := v[k][p], v[k][q]
+          v[k][p], v[k][q] = c*vkp-sn*vkq, sn*vkp+c*vkq
+        }
+      }
+    }
+  }
+  order := make([]int, n)
+  for i := range order { order[i] = i }
+  sort.Slice(order, func(x, y int) bool { return a[order[x]][order[x]] > a[order[y]][order[y]] })
+  vals := make([]float64, n)
+  vecs := make([][]float64, n)
+  for r := range vecs { vecs[r] = make([]float64, n) }
+  for c, o := range order {
+    vals[c] = a[o][o]
+    for r := 0; r < n; r++ { vecs[r][c] = v[r][o] }
+  }
+  return vals, vecs
+}
@@ -0,0 +1,174 @@
+// Package similar finds thematically related ayah by cosine similarity of
+// per-ayah vectors stored in the ayah_vector table. Vectors are built
+// offline with TF-IDF + LSA (Build) or imported from any local embedding
+// model (Import); queries need no external service.
+package similar
+
+import (
+  "context"
+  "encoding/binary"
+  "errors"
+  "math"
+  "sort"
+  "sync"
+  "time"
+
+  "github.com/jmoiron/sqlx"
+
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// ErrNoVectors means ayah_vector is empty.
+var ErrNoVectors = errors.New("no similarity vectors; run quran-cli vectors")
+
+// ErrUnknownAyah means the ayah has no vector (it is not in the database).
+var ErrUnknownAyah = errors.New("ayah not found")
+
+// Match is a related ayah; Score is the cosine similarity (1 is identical).
+type Match struct {
+  Surah  int     `db:"surah" json:"surah"`
+  Number int     `db:"number" json:"number"`
+  Score  float64 `db:"-" json:"score"`
+  Arabic string  `db:"arabic" json:"arabic"`
+  Trans  string  `db:"trans" json:"trans"`
+}
+
+// Index holds every vector in memory, normalized to unit length, so a query
+// is one dot product per ayah.
+type Index struct {
+  Model string
+  Dims  int
+  refs  []quran.Ref
+  pos   map[quran.Ref]int
+  vecs  []float32 // len(refs) * Dims
+}
+
+// Len is the number of ayah with a vector.
+func (ix *Index) Len() int { return len(ix.refs) }
+
+// Load reads all vectors. It returns ErrNoVectors when there are none.
+func Load(ctx context.Context, d *sqlx.DB) (*Index, error) {
+  rows, err := d.QueryxContext(ctx, `SELECT surah, number, model, vec FROM ayah_vector ORDER BY surah, number`)
+  if err != nil { return nil, err }
+  defer rows.Close()
+  ix := &Index{pos: map[quran.Ref]int{}}
+  for rows.Next() {
+    var r quran.Ref
+    var model string
+    var blob []byte
+    if err := rows.Scan(&r.Surah, &r.Ayah, &model, &blob); err != nil { return nil, err }
+    v := decode(blob)
+    if ix.Dims == 0 { ix.Dims, ix.Model = len(v), model }
+    if len(v) != ix.Dims || len(v) == 0 { return nil, errors.New("ayah_vector: vectors of mixed dimensions") }
+    ix.pos[r] = len(ix.refs)
+    ix.refs = append(ix.refs, r)
+    ix.vecs = append(ix.vecs, v...)
+  }
+  if err := rows.Err(); err != nil { return nil, err }
+  if len(ix.refs) == 0 { return nil, ErrNoVectors }
+  return ix, nil
+}
+
+// Nearest returns up to k ayah most similar to ref, best first, excluding
+// ref itself. Only Surah, Number and Score are set.
+func (ix *Index) Nearest(ref quran.Ref, k int) ([]Match, error) {
+  i, ok := ix.pos[ref]
+  if !ok { return nil, ErrUnknownAyah }
+  q := ix.vecs[i*ix.Dims : (i+1)*ix.Dims]
+  out := make([]Match, 0, len(ix.refs)-1)
+  for j, r := range ix.refs {
+    if j == i { continue }
+    v := ix.vecs[j*ix.Dims : (j+1)*ix.Dims]
+    var dot float64
+    for c := range q { dot += float64(q[c]) * float64(v[c]) }
+    out = append(out, Match{Surah: r.Surah, Number: r.Ayah, Score: math.Round(dot*1e4) / 1e4})
+  }
+  sort.SliceStable(out, func(a, b int) bool { return out[a].Score > out[b].Score })
+  if k < len(out) { out = out[:k] }
+  return out, nil
+}
+
+// Finder answers similarity queries with ayah text, loading the index on
+// first use and again after TTL so rebuilt vectors are picked up.
+type Finder struct {
+  d   *sqlx.DB
+  TTL time.Duration
+
+  mu sync.Mutex
+  ix *Index
+  at time.Time
+}
+
+// NewFinder returns a Finder reloading every five minutes.
+func NewFinder(d *sqlx.DB) *Finder { return &Finder{d: d, TTL: 5 * time.Minute} }
+
+// Similar returns up to limit ayah related to ref, with their text.
+func (f *Finder) Similar(ctx context.Context, ref quran.Ref, limit int) ([]Match, error) {
+  ix, err := f.index(ctx)
+  if err != nil { return nil, err }
+  ms, err := ix.Nearest(ref, limit)
+  if err != nil || len(ms) == 0 { return ms, err }
+
+  keys := make([]int, len(ms))
+  for i, m := range ms { keys[i] = m.Surah*1000 + m.Number }
+  q, args, err := sqlx.In(`SELECT surah, number, arabic, COALESCE(trans,'') AS trans FROM ayah WHERE surah*1000+number IN (?)`, keys)
+  if err != nil { return nil, err }
+  var texts []Match
+  if err := f.d.SelectContext(ctx, &texts, f.d.Rebind(q), args...); err != nil { return nil, err }
+  byRef := map[int]Match{}
+  for _, t := range texts { byRef[t.Surah*1000+t.Number] = t }
+  for i := range ms {
+    t := byRef[keys[i]]
+    ms[i].Arabic, ms[i].Trans = t.Arabic, t.Trans
+  }
+  return ms, nil
+}
+
+func (f *Finder) index(ctx context.Context) (*Index, error) {
+  f.mu.Lock()
+  defer f.mu.Unlock()
+  if f.ix != nil && time.Since(f.at) < f.TTL { return f.ix, nil }
+  ix, err := Load(ctx, f.d)
+  if err != nil { return nil, err }
+  f.ix, f.at = ix, time.Now()
+  return ix, nil
+}
+
+// Save replaces all stored vectors with vecs (normalized to unit length)
+// in one transaction.
+func Save(ctx context.Context, d *sqlx.DB, model string, vecs map[quran.Ref][]float32) error {
+  tx, err := d.BeginTxx(ctx, nil)
+  if err != nil { return err }
+  defer tx.Rollback()
+  if _, err := tx.ExecContext(ctx, `DELETE FROM ayah_vector`); err != nil { return err }
+  ins, err := tx.PreparexContext(ctx, tx.Rebind(`INSERT INTO ayah_vector(surah,number,model,vec) VALUES(?,?,?,?)`))
+  if err != nil { return err }
+  defer ins.Close()
+  for r, v := range vecs {
+    if _, err := ins.ExecContext(ctx, r.Surah, r.Ayah, model, encode(normalize(v))); err != nil { return err }
+  }
+  return tx.Commit()
+}
+
+func normalize(v []float32) []float32 {
+  var n float64
+  for _, x := range v { n += float64(x) * float64(x) }
+  if n == 0 { return v }
+  n = math.Sqrt(n)
+  out := make([]float32, len(v))
+  for i, x := range v { out[i] = float32(float64(x) / n) }
+  return out
+}
+
+// encode stores v as little-endian float32.
+func encode(v []float32) []byte {
+  b := make([]byte, 4*len(v))
+  for i, x := range v { binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(x)) }
+  return b
+}
+
+func decode(b []byte) []float32 {
+  v := make([]float32, len(b)/4)
+  for i := range v { v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:])) }
+  return v
+}
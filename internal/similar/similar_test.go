@@ -0,0 +1,117 @@
+package similar
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "math"
+  "strings"
+  "testing"
+
+  "github.com/jmoiron/sqlx"
+  _ "modernc.org/sqlite"
+
+  "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// themedDB has 2 surah of 12 ayah; ayah cycle through three themes with
+// varying wording, so neighbours should share the theme (number mod 3).
+func themedDB(t *testing.T) *sqlx.DB {
+  t.Helper()
+  d, err := sqlx.Open("sqlite", ":memory:")
+  if err != nil { t.Fatal(err) }
+  d.SetMaxOpenConns(1)
+  if err := db.Migrate(context.Background(), d); err != nil { t.Fatal(err) }
+  d.MustExec(`INSERT INTO meta(key,value) VALUES('primary_lang','en')`)
+  themes := [][]string{
+    {"rain", "water", "rivers", "gardens", "fruits", "green", "sends", "sky"},
+    {"fire", "punishment", "burning", "painful", "wrongdoers", "blaze", "torment", "hell"},
+    {"prayer", "charity", "establish", "give", "poor", "worship", "bow", "alms"},
+  }
+  common := []string{"and", "the", "lord", "people", "day", "those", "who", "indeed"}
+  for s := 1; s <= 2; s++ {
+    d.MustExec(`INSERT INTO surah(number,name_ar,verses_count) VALUES(?,?,12)`, s, fmt.Sprint("s", s))
+    for n := 1; n <= 12; n++ {
+      th := themes[n%3]
+      words := []string{}
+      for i := 0; i < 5; i++ { words = append(words, th[(n+s+i*3)%len(th)], common[(n*s+i)%len(common)]) }
+      d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,trans) VALUES(?,?,1,?,?)`, s, n, fmt.Sprintf("آية %d", n), strings.Join(words, " "))
+    }
+  }
+  return d
+}
+
+func TestBuild_NeighboursShareTheme(t *testing.T) {
+  d := themedDB(t)
+  ctx := context.Background()
+  n, err := Build(ctx, d)
+  if err != nil { t.Fatal(err) }
+  if n != 24 { t.Fatalf("built %d vectors", n) }
+
+  ix, err := Load(ctx, d)
+  if err != nil { t.Fatal(err) }
+  if ix.Model != ModelLSA || ix.Len() != 24 || ix.Dims == 0 { t.Fatalf("index: %s %d %d", ix.Model, ix.Len(), ix.Dims) }
+  for _, ref := range []quran.Ref{{Surah: 1, Ayah: 1}, {Surah: 2, Ayah: 5}, {Surah: 1, Ayah: 9}} {
+    ms, err := ix.Nearest(ref, 4)
+    if err != nil { t.Fatal(err) }
+    if len(ms) != 4 { t.Fatalf("%s: %d matches", ref, len(ms)) }
+    for _, m := range ms {
+      if m.Number%3 != ref.Ayah%3 { t.Errorf("%s: neighbour %d:%d (%.3f) has another theme", ref, m.Surah, m.Number, m.Score) }
+      if m.Surah == ref.Surah && m.Number == ref.Ayah { t.Errorf("%s: returned itself", ref) }
+    }
+    if ms[0].Score < ms[3].Score { t.Errorf("%s: not sorted: %+v", ref, ms) }
+  }
+
+  // rebuild is deterministic
+  before, _ := ix.Nearest(quran.Ref{Surah: 1, Ayah: 1}, 5)
+  if _, err := Build(ctx, d); err != nil { t.Fatal(err) }
+  ix, _ = Load(ctx, d)
+  after, _ := ix.Nearest(quran.Ref{Surah: 1, Ayah: 1}, 5)
+  if fmt.Sprint(before) != fmt.Sprint(after) { t.Fatalf("rebuild differs:\n%v\n%v", before, after) }
+}
+
+func TestFinder(t *testing.T) {
+  d := themedDB(t)
+  ctx := context.Background()
+  f := NewFinder(d)
+  if _, err := f.Similar(ctx, quran.Ref{Surah: 1, Ayah: 1}, 3); !errors.Is(err, ErrNoVectors) { t.Fatalf("expected ErrNoVectors, got %v", err) }
+  if _, err := Build(ctx, d); err != nil { t.Fatal(err) }
+  ms, err := f.Similar(ctx, quran.Ref{Surah: 1, Ayah: 1}, 3)
+  if err != nil { t.Fatal(err) }
+  if len(ms) != 3 || ms[0].Trans == "" || !strings.HasPrefix(ms[0].Arabic, "آية") { t.Fatalf("matches: %+v", ms) }
+  if _, err := f.Similar(ctx, quran.Ref{Surah: 3, Ayah: 1}, 3); !errors.Is(err, ErrUnknownAyah) { t.Fatalf("expected ErrUnknownAyah, got %v", err) }
+}
+
+func TestImport(t *testing.T) {
+  d := themedDB(t)
+  ctx := context.Background()
+  in := `{"surah":1,"ayah":1,"vector":[3,4]}
+{"surah":1,"ayah":2,"vector":[4,3]}
+
+{"surah":1,"ayah":3,"vector":[-1,0]}
+`
+  n, err := Import(ctx, d, strings.NewReader(in), "test-model")
+  if err != nil || n != 3 { t.Fatalf("import: %d %v", n, err) }
+  ix, err := Load(ctx, d)
+  if err != nil { t.Fatal(err) }
+  ms, _ := ix.Nearest(quran.Ref{Surah: 1, Ayah: 1}, 5)
+  if ix.Model != "test-model" || len(ms) != 2 || ms[0].Number != 2 || ms[0].Score != 0.96 { t.Fatalf("nearest: %s %+v", ix.Model, ms) }
+
+  for _, bad := range []string{`{"surah":1,"ayah":1,"vector":[1,2]}` + "\n" + `{"surah":1,"ayah":2,"vector":[1]}`, `{"surah":115,"ayah":1,"vector":[1]}`, `nope`, ``} {
+    if _, err := Import(ctx, d, strings.NewReader(bad), "x"); err == nil { t.Errorf("expected error for %q", bad) }
+  }
+}
+
+func TestEigen(t *testing.T) {
+  s := [][]float64{{4, 1, 2}, {1, 3, 0}, {2, 0, 5}}
+  vals, vecs := eigen(s)
+  if vals[0] < vals[1] || vals[1] < vals[2] { t.Fatalf("not descending: %v", vals) }
+  for c := range vals {
+    for r := range s {
+      var sv float64
+      for k := range s { sv += s[r][k] * vecs[k][c] }
+      if math.Abs(sv-vals[c]*vecs[r][c]) > 1e-9 { t.Fatalf("S·v != λ·v for eigenpair %d", c) }
+    }
+  }
+}
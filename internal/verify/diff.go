@@ -0,0 +1,81 @@
+package verify
+
+import (
+  "fmt"
+)
+
+// CharDiff is one differing span between reference and stored text. Offset
+// counts runes in the reference; code points are listed because most
+// alterations (harakat, hamza forms) are invisible when printed.
+type CharDiff struct {
+  Offset        int      `json:"offset"`
+  Expected      string   `json:"expected"`
+  Actual        string   `json:"actual"`
+  ExpectedCodes []string `json:"expected_codes,omitempty"`
+  ActualCodes   []string `json:"actual_codes,omitempty"`
+}
+
+// Diff returns the rune-level differences turning want into got.
+func Diff(want, got string) []CharDiff {
+  a, b := []rune(want), []rune(got)
+  // trim the common prefix and suffix; the middle is usually tiny
+  pre := 0
+  for pre < len(a) && pre < len(b) && a[pre] == b[pre] { pre++ }
+  suf := 0
+  for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] { suf++ }
+  a, b = a[pre:len(a)-suf], b[pre:len(b)-suf]
+  if len(a) == 0 && len(b) == 0 { return nil }
+
+  // LCS table over the differing middle
+  n, m := len(a), len(b)
+  lcs := make([][]int32, n+1)
+  for i := range lcs { lcs[i] = make([]int32, m+1) }
+  for i := n - 1; i >= 0; i-- {
+    for j := m - 1; j >= 0; j-- {
+      if a[i] == b[j] {
+        lcs[i][j] = lcs[i+1][j+1] + 1
+      } else if lcs[i+1][j] >= lcs[i][j+1] {
+        lcs[i][j] = lcs[i+1][j]
+      } else {
+        lcs[i][j] = lcs[i][j+1]
+      }
+    }
+  }
+
+  var out []CharDiff
+  var cur *CharDiff
+  flush := func() {
+    if cur != nil {
+      cur.ExpectedCodes, cur.ActualCodes = codes(cur.Expected), codes(cur.Actual)
+      out = append(out, *cur)
+      cur = nil
+    }
+  }
+  open := func(i int) {
+    if cur == nil { cur = &CharDiff{Offset: pre + i} }
+  }
+  i, j := 0, 0
+  for i < n || j < m {
+    switch {
+    case i < n && j < m && a[i] == b[j]:
+      flush()
+      i++; j++
+    case j < m && (i == n || lcs[i][j+1] >= lcs[i+1][j]):
+      open(i)
+      cur.Actual += string(b[j])
+      j++
+    default:
+      open(i)
+      cur.Expected += string(a[i])
+      i++
+    }
+  }
+  flush()
+  return out
+}
+
+func codes(s string) []string {
+  var out []string
+  for _, r := range s { out = append(out, fmt.Sprintf("U+%04X", r)) }
+  return out
+}
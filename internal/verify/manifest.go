@@ -0,0 +1,132 @@
+package verify
+
+import (
+  "crypto/sha256"
+  "embed"
+  "encoding/hex"
+  "encoding/json"
+  "fmt"
+  "io"
+  "io/fs"
+  "os"
+  "path"
+  "sort"
+  "strings"
+
+  "github.com/foozio/quran-go/internal/data"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// Bundled reference manifests live in manifests/<name>.json and are compiled
+// into the binary. Generate them from an official text with
+// `quran-verify -ref <file> -write-manifest internal/verify/manifests/<name>.json`.
+//
+//go:embed manifests
+var bundled embed.FS
+
+// Manifest records the expected hash of every ayah in a reference corpus.
+type Manifest struct {
+  Corpus        string          `json:"corpus"`
+  Source        string          `json:"source,omitempty"`
+  Normalization string          `json:"normalization"`
+  Hash          string          `json:"hash"`
+  Surahs        []SurahManifest `json:"surahs"`
+}
+
+// SurahManifest holds per-ayah hashes (index 0 is ayah 1) and their digest.
+type SurahManifest struct {
+  Surah int      `json:"surah"`
+  Hash  string   `json:"hash"`
+  Ayah  []string `json:"ayah"`
+}
+
+// Text maps each ayah to its text.
+type Text map[quran.Ref]string
+
+// HashText returns the hex SHA-256 of s.
+func HashText(s string) string {
+  sum := sha256.Sum256([]byte(s))
+  return hex.EncodeToString(sum[:])
+}
+
+// digest hashes a list of child hashes in order.
+func digest(hashes []string) string { return HashText(strings.Join(hashes, "\n")) }
+
+// BuildManifest hashes every ayah of text after normalizing it with mode.
+func BuildManifest(corpus, source, mode string, text Text) (*Manifest, error) {
+  m := &Manifest{Corpus: corpus, Source: source, Normalization: mode}
+  bySurah := map[int][]quran.Ref{}
+  for ref := range text { bySurah[ref.Surah] = append(bySurah[ref.Surah], ref) }
+  var surahHashes []string
+  for s := 1; s <= 114; s++ {
+    refs := bySurah[s]
+    sort.Slice(refs, func(i, j int) bool { return refs[i].Ayah < refs[j].Ayah })
+    sm := SurahManifest{Surah: s}
+    for i, ref := range refs {
+      if ref.Ayah != i+1 { return nil, fmt.Errorf("surah %d: ayah %d missing from reference", s, i+1) }
+      n, err := Normalize(text[ref], mode)
+      if err != nil { return nil, err }
+      sm.Ayah = append(sm.Ayah, HashText(n))
+    }
+    if len(sm.Ayah) == 0 { return nil, fmt.Errorf("surah %d missing from reference", s) }
+    sm.Hash = digest(sm.Ayah)
+    surahHashes = append(surahHashes, sm.Hash)
+    m.Surahs = append(m.Surahs, sm)
+  }
+  m.Hash = digest(surahHashes)
+  return m, nil
+}
+
+// WriteManifest encodes m as indented JSON.
+func WriteManifest(w io.Writer, m *Manifest) error {
+  enc := json.NewEncoder(w)
+  enc.SetIndent("", " ")
+  return enc.Encode(m)
+}
+
+// LoadManifest reads a manifest from a file path or, failing that, from the
+// bundled manifests by name (e.g. "tanzil-uthmani").
+func LoadManifest(nameOrPath string) (*Manifest, error) {
+  b, err := os.ReadFile(nameOrPath)
+  if err != nil {
+    b, err = bundled.ReadFile(path.Join("manifests", strings.TrimSuffix(nameOrPath, ".json")+".json"))
+    if err != nil { return nil, fmt.Errorf("manifest %q not found (bundled: %s)", nameOrPath, strings.Join(Bundled(), ", ")) }
+  }
+  var m Manifest
+  if err := json.Unmarshal(b, &m); err != nil { return nil, fmt.Errorf("manifest %s: %w", nameOrPath, err) }
+  if len(m.Surahs) != 114 { return nil, fmt.Errorf("manifest %s: expected 114 surahs, got %d", nameOrPath, len(m.Surahs)) }
+  return &m, nil
+}
+
+// DefaultManifest is the bundled manifest quran-verify checks against when
+// given neither -manifest nor -ref.
+const DefaultManifest = "tanzil-uthmani"
+
+// Default returns DefaultManifest, or another bundled manifest when that one
+// is missing, or "" when none is bundled.
+func Default() string {
+  names := Bundled()
+  for _, n := range names { if n == DefaultManifest { return n } }
+  if len(names) > 0 { return names[0] }
+  return ""
+}
+
+// Bundled lists the names of manifests compiled into the binary.
+func Bundled() []string {
+  var out []string
+  entries, _ := fs.ReadDir(bundled, "manifests")
+  for _, e := range entries {
+    if n, ok := strings.CutSuffix(e.Name(), ".json"); ok { out = append(out, n) }
+  }
+  return out
+}
+
+// ReadTanzil parses Tanzil's pipe-delimited text format ("sura|aya|text",
+// '#' comments), as downloaded from tanzil.net/download.
+func ReadTanzil(r io.Reader) (Text, error) {
+  ayat, err := data.ReadTanzilText(r)
+  if err != nil { return nil, err }
+  text := Text{}
+  for _, a := range ayat { text[quran.Ref{Surah: a.Surah, Ayah: a.Number}] = a.Arabic }
+  return text, nil
+}
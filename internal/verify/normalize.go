@@ -0,0 +1,52 @@
+package verify
+
+import (
+  "fmt"
+  "strings"
+  "unicode"
+
+  "golang.org/x/text/unicode/norm"
+)
+
+// Normalization modes applied to ayah text before hashing.
+const (
+  NormExact  = "exact"  // byte-for-byte, surrounding whitespace trimmed
+  NormNFC    = "nfc"    // Unicode NFC, whitespace collapsed
+  NormSimple = "simple" // NFC without diacritics, Quranic marks and tatweel (like Tanzil "simple-clean")
+)
+
+// Normalize prepares s for hashing according to mode.
+func Normalize(s, mode string) (string, error) {
+  switch mode {
+  case NormExact, "":
+    return strings.TrimSpace(s), nil
+  case NormNFC:
+    return strings.Join(strings.Fields(norm.NFC.String(s)), " "), nil
+  case NormSimple:
+    b := &strings.Builder{}
+    for _, r := range norm.NFC.String(s) {
+      switch {
+      case isMark(r):
+        continue
+      case r == 'ٱ': // alef wasla
+        r = 'ا'
+      }
+      b.WriteRune(r)
+    }
+    return strings.Join(strings.Fields(b.String()), " "), nil
+  }
+  return "", fmt.Errorf("unknown normalization %q", mode)
+}
+
+// isMark reports Arabic harakat, Quranic annotation signs and tatweel.
+func isMark(r rune) bool {
+  switch {
+  case r >= 0x064B && r <= 0x065F, r == 0x0670, r == 0x0640:
+    return true
+  case r >= 0x06D6 && r <= 0x06ED:
+    return true
+  case r >= 0x08D3 && r <= 0x08FF:
+    return true
+  }
+  return unicode.Is(unicode.Mn, r) && unicode.Is(unicode.Arabic, r)
+}
@@ -0,0 +1,159 @@
+// Package verify checks a seeded database for structural consistency and
+// textual integrity against reference manifests.
+package verify
+
+import (
+  "context"
+  "fmt"
+  "sort"
+
+  "github.com/jmoiron/sqlx"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+// CountMismatch is a surah whose verses_count disagrees with its ayah rows.
+type CountMismatch struct {
+  Surah  int `db:"surah" json:"surah"`
+  Verses int `db:"verses" json:"verses"`
+  Ayah   int `db:"ayah" json:"ayah"`
+}
+
+// CountReport summarizes row counts; it is also served by GET /stats.
+type CountReport struct {
+  OK         bool            `json:"ok"`
+  SurahTotal int             `json:"surah_total"`
+  AyahTotal  int             `json:"ayah_total"`
+  Tail       int             `json:"tail_94_114_ayah"`
+  Mismatches []CountMismatch `json:"mismatches"`
+}
+
+// Counts checks there are 114 surah, that every verses_count matches the
+// stored ayah rows and that the short tail surah (94-114) were ingested.
+func Counts(ctx context.Context, d *sqlx.DB) (CountReport, error) {
+  r := CountReport{Mismatches: []CountMismatch{}}
+  if err := d.GetContext(ctx, &r.SurahTotal, `SELECT COUNT(*) FROM surah`); err != nil { return r, err }
+  if err := d.GetContext(ctx, &r.AyahTotal, `SELECT COUNT(*) FROM ayah`); err != nil { return r, err }
+  if err := d.GetContext(ctx, &r.Tail, `SELECT COUNT(*) FROM ayah WHERE surah BETWEEN 94 AND 114`); err != nil { return r, err }
+  rows := []CountMismatch{}
+  if err := d.SelectContext(ctx, &rows, `
+    SELECT s.number AS surah, s.verses_count AS verses,
+      (SELECT COUNT(*) FROM ayah a WHERE a.surah = s.number) AS ayah
+    FROM surah s ORDER BY s.number`); err != nil {
+    return r, err
+  }
+  for _, m := range rows { if m.Verses != m.Ayah { r.Mismatches = append(r.Mismatches, m) } }
+  r.OK = r.SurahTotal == 114 && len(r.Mismatches) == 0 && r.Tail > 0
+  return r, nil
+}
+
+// Issue kinds reported per ayah.
+const (
+  IssueMissing = "missing" // in the reference, not in the database
+  IssueExtra   = "extra"   // in the database, not in the reference
+  IssueAltered = "altered" // present in both with different text
+)
+
+// AyahIssue describes one ayah that does not match the reference.
+type AyahIssue struct {
+  Ref      string     `json:"ref"`
+  Kind     string     `json:"kind"`
+  Expected string     `json:"expected_hash,omitempty"`
+  Actual   string     `json:"actual_hash,omitempty"`
+  Diffs    []CharDiff `json:"diffs,omitempty"`
+}
+
+// TextReport is the outcome of comparing stored text with a manifest.
+type TextReport struct {
+  OK            bool        `json:"ok"`
+  Corpus        string      `json:"corpus"`
+  Normalization string      `json:"normalization"`
+  ExpectedHash  string      `json:"expected_hash"`
+  ActualHash    string      `json:"actual_hash"`
+  AyahChecked   int         `json:"ayah_checked"`
+  Surahs        []int       `json:"surah_mismatches"`
+  Issues        []AyahIssue `json:"ayah_issues"`
+}
+
+// Report is the full JSON document written by quran-verify -json.
+type Report struct {
+  OK     bool        `json:"ok"`
+  Counts CountReport `json:"counts"`
+  Text   *TextReport `json:"text,omitempty"`
+}
+
+// LoadText reads the Arabic text of every stored ayah.
+func LoadText(ctx context.Context, d *sqlx.DB) (Text, error) {
+  var rows []struct{
+    Surah int `db:"surah"`
+    Number int `db:"number"`
+    Arabic string `db:"arabic"`
+  }
+  if err := d.SelectContext(ctx, &rows, `SELECT surah, number, arabic FROM ayah ORDER BY surah, number`); err != nil {
+    return nil, err
+  }
+  t := Text{}
+  for _, r := range rows { t[quran.Ref{Surah: r.Surah, Ayah: r.Number}] = r.Arabic }
+  return t, nil
+}
+
+// CheckText hashes got with the manifest's normalization and compares each
+// ayah. When ref (the reference text itself) is non-nil, altered ayah also
+// carry character-level diffs.
+func CheckText(m *Manifest, got, ref Text) (*TextReport, error) {
+  r := &TextReport{Corpus: m.Corpus, Normalization: m.Normalization, ExpectedHash: m.Hash, Surahs: []int{}, Issues: []AyahIssue{}}
+  var surahHashes []string
+  for _, sm := range m.Surahs {
+    var actual []string
+    bad := false
+    for i, want := range sm.Ayah {
+      key := quran.Ref{Surah: sm.Surah, Ayah: i + 1}
+      text, ok := got[key]
+      if !ok {
+        r.Issues = append(r.Issues, AyahIssue{Ref: key.String(), Kind: IssueMissing, Expected: want})
+        actual = append(actual, "")
+        bad = true
+        continue
+      }
+      n, err := Normalize(text, m.Normalization)
+      if err != nil { return nil, err }
+      h := HashText(n)
+      actual = append(actual, h)
+      r.AyahChecked++
+      if h == want { continue }
+      bad = true
+      is := AyahIssue{Ref: key.String(), Kind: IssueAltered, Expected: want, Actual: h}
+      if rt, ok := ref[key]; ok {
+        rn, err := Normalize(rt, m.Normalization)
+        if err != nil { return nil, err }
+        is.Diffs = Diff(rn, n)
+      }
+      r.Issues = append(r.Issues, is)
+    }
+    for key := range got {
+      if key.Surah == sm.Surah && key.Ayah > len(sm.Ayah) {
+        r.Issues = append(r.Issues, AyahIssue{Ref: key.String(), Kind: IssueExtra})
+        bad = true
+      }
+    }
+    if bad { r.Surahs = append(r.Surahs, sm.Surah) }
+    surahHashes = append(surahHashes, digest(actual))
+  }
+  for key := range got {
+    if key.Surah < 1 || key.Surah > len(m.Surahs) {
+      r.Issues = append(r.Issues, AyahIssue{Ref: key.String(), Kind: IssueExtra})
+    }
+  }
+  sortIssues(r.Issues)
+  r.ActualHash = digest(surahHashes)
+  r.OK = len(r.Issues) == 0 && r.ActualHash == r.ExpectedHash
+  return r, nil
+}
+
+func sortIssues(is []AyahIssue) {
+  key := func(s string) int {
+    var a, b int
+    _, _ = fmt.Sscanf(s, "%d:%d", &a, &b)
+    return quran.Ref{Surah: a, Ayah: b}.Key()
+  }
+  sort.SliceStable(is, func(i, j int) bool { return key(is[i].Ref) < key(is[j].Ref) })
+}
@@ -0,0 +1,79 @@
+package verify
+
+import (
+  "strings"
+  "testing"
+
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+func fullText() Text {
+  t := Text{}
+  for s, n := range quran.VerseCounts {
+    for a := 1; a <= n; a++ { t[quran.Ref{Surah: s + 1, Ayah: a}] = "بِسْمِ ٱللَّهِ ٱلرَّحْمَٰنِ" }
+  }
+  return t
+}
+
+func TestNormalize(t *testing.T) {
+  got, err := Normalize("  بِسْمِ   ٱللَّهِ ", NormSimple)
+  if err != nil { t.Fatal(err) }
+  if got != "بسم الله" { t.Fatalf("simple normalization = %q", got) }
+  if _, err := Normalize("x", "bogus"); err == nil { t.Fatalf("expected error for unknown mode") }
+}
+
+func TestDiff(t *testing.T) {
+  d := Diff("ٱلرَّحْمَٰنِ", "ٱلرَّحمَٰنِ")
+  if len(d) != 1 || d[0].Expected != "ْ" || d[0].Actual != "" || d[0].Offset != 6 {
+    t.Fatalf("unexpected diff: %+v", d)
+  }
+  if d[0].ExpectedCodes[0] != "U+0652" { t.Fatalf("codes: %v", d[0].ExpectedCodes) }
+  if Diff("same", "same") != nil { t.Fatalf("expected no diff for equal text") }
+}
+
+func TestCheckText(t *testing.T) {
+  ref := fullText()
+  m, err := BuildManifest("test", "", NormNFC, ref)
+  if err != nil { t.Fatal(err) }
+
+  r, err := CheckText(m, fullText(), nil)
+  if err != nil { t.Fatal(err) }
+  if !r.OK || r.AyahChecked != quran.TotalAyah || r.ActualHash != m.Hash {
+    t.Fatalf("expected clean report, got %+v", r)
+  }
+
+  got := fullText()
+  got[quran.Ref{Surah: 2, Ayah: 255}] = strings.Replace(got[quran.Ref{Surah: 2, Ayah: 255}], "ْ", "", 1)
+  delete(got, quran.Ref{Surah: 114, Ayah: 6})
+  got[quran.Ref{Surah: 1, Ayah: 8}] = "extra"
+  r, err = CheckText(m, got, ref)
+  if err != nil { t.Fatal(err) }
+  if r.OK { t.Fatalf("expected failure") }
+  if len(r.Issues) != 3 { t.Fatalf("expected 3 issues, got %+v", r.Issues) }
+  want := []string{"1:8 extra", "2:255 altered", "114:6 missing"}
+  for i, is := range r.Issues {
+    if is.Ref+" "+is.Kind != want[i] { t.Fatalf("issue %d = %s %s, want %s", i, is.Ref, is.Kind, want[i]) }
+  }
+  if len(r.Issues[1].Diffs) != 1 { t.Fatalf("expected character diff for altered ayah") }
+  if len(r.Surahs) != 3 { t.Fatalf("surah mismatches: %v", r.Surahs) }
+}
+
+func TestReadTanzil(t *testing.T) {
+  in := "\ufeff1|1|بِسْمِ ٱللَّهِ\n# comment\n\n1|2|ٱلْحَمْدُ لِلَّهِ\n"
+  txt, err := ReadTanzil(strings.NewReader(in))
+  if err != nil { t.Fatal(err) }
+  if len(txt) != 2 || txt[quran.Ref{Surah: 1, Ayah: 2}] != "ٱلْحَمْدُ لِلَّهِ" { t.Fatalf("unexpected parse: %v", txt) }
+}
+
+func TestBundledManifests(t *testing.T) {
+  names := Bundled()
+  if len(names) == 0 { t.Fatal("no bundled manifests: run make manifests and commit internal/verify/manifests/*.json") }
+  for _, want := range []string{"tanzil-uthmani", "tanzil-simple"} {
+    m, err := LoadManifest(want)
+    if err != nil { t.Errorf("bundled %s: %v", want, err); continue }
+    n := 0
+    for _, s := range m.Surahs { n += len(s.Ayah) }
+    if len(m.Surahs) != 114 || n != 6236 { t.Errorf("%s: %d surahs, %d ayah", want, len(m.Surahs), n) }
+  }
+  if d := Default(); d != DefaultManifest { t.Fatalf("Default() = %q with bundled %v", d, names) }
+}
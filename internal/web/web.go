@@ -0,0 +1,146 @@
+// Package web implements the server-rendered UI shared by quran-web and
+// quran-all.
+package web
+
+import (
+  "embed"
+  "errors"
+  "html/template"
+  "net/http"
+  "strconv"
+  "strings"
+
+  "github.com/foozio/quran-go/internal/assets"
+  "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/internal/httpx"
+  "github.com/foozio/quran-go/internal/mushaf"
+  "github.com/foozio/quran-go/internal/search"
+  "github.com/foozio/quran-go/pkg/quran"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// Every page and fragment goes through html/template, so ayah text, tajweed,
+// audio URLs and search hits are escaped for the context they land in.
+var tpl = template.Must(template.New("").Funcs(assets.Funcs).Funcs(template.FuncMap{"digits": arabicDigits}).
+  ParseFS(templateFS, "templates/*.html"))
+
+// PartialHeader marks the fetches of app.js, which get a fragment instead of
+// the whole page.
+const PartialHeader = "X-Partial"
+
+type ayahView struct {
+  Number  int
+  Arabic  string
+  Tajweed string
+  Trans   string
+  Audio   string
+}
+
+type surahView struct {
+  Surah int
+  Ayat  []ayahView
+}
+
+type hitView struct {
+  Surah, Number int
+  Segments      []search.Segment
+}
+
+// New serves the UI from s behind a strict Content-Security-Policy; it loads
+// nothing from other origins.
+func New(s db.Store) http.Handler {
+  mux := http.NewServeMux()
+  mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request){
+    w.Header().Set("Content-Type","application/json")
+    w.WriteHeader(http.StatusOK)
+    w.Write([]byte(`{"ok":true}`))
+  })
+  mux.Handle(assets.Prefix, assets.Handler())
+  mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request){
+    if r.URL.Path != "/" { http.NotFound(w, r); return }
+    renderPage(w, r, s, nil)
+  })
+  mux.HandleFunc("/s/", func(w http.ResponseWriter, r *http.Request){
+    n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/s/"))
+    if err != nil || n < 1 || n > 114 { http.Error(w, "invalid surah number", http.StatusBadRequest); return }
+    rows, err := s.Ayat(r.Context(), n)
+    if err != nil { http.Error(w, err.Error(), http.StatusInternalServerError); return }
+    sv := &surahView{Surah: n}
+    for _, a := range rows {
+      sv.Ayat = append(sv.Ayat, ayahView{a.Ayah, a.Arabic, a.Tajweed, a.Trans, a.AudioURL})
+    }
+    // app.js swaps the fragment in; a direct visit gets the whole page
+    if r.Header.Get(PartialHeader) == "" { renderPage(w, r, s, sv); return }
+    render(w, http.StatusOK, "surah", sv)
+  })
+  mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request){
+    q := strings.TrimSpace(r.URL.Query().Get("q"))
+    if len(q) > 100 { render(w, http.StatusBadRequest, "results", map[string]any{"Error": "query too long"}); return }
+    res, err := s.Search(r.Context(), q, 50)
+    if errors.Is(err, search.ErrSyntax) { render(w, http.StatusBadRequest, "results", map[string]any{"Error": err.Error()}); return }
+    if err != nil { http.Error(w, err.Error(), http.StatusInternalServerError); return }
+    hits := make([]hitView, len(res.Hits))
+    for i, h := range res.Hits {
+      hits[i] = hitView{h.Surah, h.Number, search.Segments(h.Text, h.Matches)}
+    }
+    render(w, http.StatusOK, "results", map[string]any{"Hits": hits, "Suggestion": res.Suggestion})
+  })
+  mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request){
+    // the go-to form (?n=42) and links to an ayah (?ref=2:255)
+    if n := r.URL.Query().Get("n"); n != "" {
+      http.Redirect(w, r, "/page/"+strconv.Itoa(atoi(n)), http.StatusFound); return
+    }
+    ref, err := quran.ParseRef(r.URL.Query().Get("ref"))
+    if err != nil { http.Error(w, err.Error(), http.StatusBadRequest); return }
+    n, err := mushaf.Of(r.Context(), s.DB(), ref)
+    if err != nil { mushafError(w, err); return }
+    http.Redirect(w, r, "/page/"+strconv.Itoa(n)+"#a"+strconv.Itoa(ref.Surah)+"-"+strconv.Itoa(ref.Ayah), http.StatusFound)
+  })
+  mux.HandleFunc("/page/", func(w http.ResponseWriter, r *http.Request){
+    n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/page/"))
+    if err != nil { n = 0 }
+    p, err := mushaf.Load(r.Context(), s.DB(), n)
+    if err != nil { mushafError(w, err); return }
+    render(w, http.StatusOK, "mushaf", map[string]any{"Page": p})
+  })
+  return httpx.CSP(mux)
+}
+
+// mushafError shows err on the reader page: 400 for a bad page number.
+func mushafError(w http.ResponseWriter, err error) {
+  status := http.StatusInternalServerError
+  if errors.Is(err, mushaf.ErrInvalid) { status = http.StatusBadRequest }
+  render(w, status, "mushaf", map[string]any{"Error": err.Error()})
+}
+
+func atoi(s string) int {
+  n, _ := strconv.Atoi(strings.TrimSpace(s))
+  return n
+}
+
+// arabicDigits writes n in Arabic-Indic digits, as verse numbers are printed.
+func arabicDigits(n int) string {
+  rs := []rune(strconv.Itoa(n))
+  for i, r := range rs { rs[i] = r - '0' + '٠' }
+  return string(rs)
+}
+
+func renderPage(w http.ResponseWriter, r *http.Request, s db.Store, content *surahView) {
+  list, err := s.Surahs(r.Context())
+  if err != nil { http.Error(w, err.Error(), http.StatusInternalServerError); return }
+  render(w, http.StatusOK, "page", map[string]any{"Surah": list, "Content": content})
+}
+
+// render executes a template into a buffer first, so a failure still
+// produces a clean 500 instead of half a page.
+func render(w http.ResponseWriter, status int, name string, data any) {
+  var b strings.Builder
+  if err := tpl.ExecuteTemplate(&b, name, data); err != nil {
+    http.Error(w, err.Error(), http.StatusInternalServerError); return
+  }
+  w.Header().Set("Content-Type","text/html; charset=utf-8")
+  w.WriteHeader(status)
+  _, _ = w.Write([]byte(b.String()))
+}
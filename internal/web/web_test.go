@@ -0,0 +1,157 @@
+package web
+
+import (
+  "context"
+  "net/http"
+  "net/http/httptest"
+  "regexp"
+  "strings"
+  "testing"
+
+  "github.com/jmoiron/sqlx"
+  _ "modernc.org/sqlite"
+
+  "github.com/foozio/quran-go/internal/db"
+)
+
+// hostileHandler serves a database whose every text column tries to inject
+// markup or script.
+func hostileHandler(t *testing.T) http.Handler {
+  t.Helper()
+  return handler(t, hostileDB(t))
+}
+
+func hostileDB(t *testing.T) *sqlx.DB {
+  t.Helper()
+  d, err := sqlx.Open("sqlite", ":memory:")
+  if err != nil { t.Fatal(err) }
+  d.SetMaxOpenConns(1)
+  if err := db.Migrate(context.Background(), d); err != nil { t.Fatal(err) }
+  d.MustExec(`INSERT INTO surah(number,name_ar,verses_count) VALUES(1,'</a><script>alert("name")</script>',2)`)
+  d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,tajweed,trans,audio_url) VALUES(1,1,1,?,?,?,?)`,
+    `<script>alert("arabic")</script>`, `<img src=x onerror=alert("tajweed")>`,
+    `Allah <b onmouseover="alert('trans')">Pengasih</b>`, `javascript:alert("audio")`)
+  d.MustExec(`INSERT INTO ayah(surah,number,juz,arabic,tajweed,trans,audio_url) VALUES(1,2,1,'ٱلْحَمْدُ','','Segala puji', ?)`,
+    `https://cdn.example/1/2.mp3" onplay="alert('audio')`)
+  return d
+}
+
+func handler(t *testing.T, d *sqlx.DB) http.Handler {
+  t.Helper()
+  st, err := db.NewStore(context.Background(), d)
+  if err != nil { t.Fatal(err) }
+  t.Cleanup(func() { st.Close() })
+  return New(st)
+}
+
+func get(h http.Handler, url string, partial bool) *httptest.ResponseRecorder {
+  req := httptest.NewRequest(http.MethodGet, url, nil)
+  if partial { req.Header.Set(PartialHeader, "1") }
+  w := httptest.NewRecorder()
+  h.ServeHTTP(w, req)
+  return w
+}
+
+var (
+  tags = regexp.MustCompile(`<[a-zA-Z][^>]*>`)
+  // injected matches tags or attributes only hostile data could produce.
+  injected = regexp.MustCompile(`^<(img|b[\s>]|script>)|\son\w+=|javascript:`)
+)
+
+// rawTags returns the tags of body that came from the data rather than the
+// templates.
+func rawTags(body string) []string {
+  var out []string
+  for _, tag := range tags.FindAllString(body, -1) {
+    if injected.MatchString(tag) { out = append(out, tag) }
+  }
+  return out
+}
+
+func TestWeb_HostileContentIsEscaped(t *testing.T) {
+  h := hostileHandler(t)
+  for _, tc := range []struct {
+    url     string
+    partial bool
+    want    string
+  }{
+    {"/", false, `&lt;/a&gt;&lt;script&gt;alert(&#34;name&#34;)&lt;/script&gt;`},
+    {"/s/1", true, `&lt;script&gt;alert(&#34;arabic&#34;)&lt;/script&gt;`},
+    {"/s/1", false, `&lt;img src=x onerror=alert(&#34;tajweed&#34;)&gt;`},
+    {"/search?q=pengasih", true, `<mark>Pengasih</mark>&lt;/b&gt;`},
+  } {
+    w := get(h, tc.url, tc.partial)
+    body := w.Body.String()
+    if w.Code != http.StatusOK { t.Fatalf("%s: %d %s", tc.url, w.Code, body) }
+    if !strings.Contains(body, tc.want) { t.Errorf("%s: missing %s in\n%s", tc.url, tc.want, body) }
+    if raw := rawTags(body); raw != nil { t.Errorf("%s: unescaped %q", tc.url, raw) }
+  }
+
+  body := get(h, "/s/1", true).Body.String()
+  if strings.Contains(body, `src="javascript:`) || !strings.Contains(body, `src="#ZgotmplZ"`) { t.Errorf("javascript: audio URL not neutralised:\n%s", body) }
+  if !strings.Contains(body, `src="https://cdn.example/1/2.mp3%22%20onplay=%22alert%28%27audio%27%29"`) { t.Errorf("audio URL attribute not escaped:\n%s", body) }
+  if strings.Contains(body, "<html") { t.Errorf("partial request got the full page") }
+}
+
+func TestWeb_CSP(t *testing.T) {
+  h := hostileHandler(t)
+  w := get(h, "/", false)
+  csp := w.Header().Get("Content-Security-Policy")
+  if !strings.Contains(csp, "script-src 'self';") || !strings.Contains(csp, "style-src 'self';") || strings.Contains(csp, "https:/") || !strings.Contains(csp, "object-src 'none'") || !strings.Contains(csp, "frame-ancestors 'none'") { t.Fatalf("CSP: %q", csp) }
+  body := w.Body.String()
+  if regexp.MustCompile(`<script>|<style|onclick=|style="`).MatchString(body) { t.Errorf("inline script or style breaks the CSP:\n%s", body) }
+  if strings.Contains(body, "https://") { t.Errorf("page loads from another origin:\n%s", body) }
+  if w.Header().Get("X-Content-Type-Options") != "nosniff" { t.Errorf("missing nosniff") }
+}
+
+func TestWeb_Assets(t *testing.T) {
+  h := hostileHandler(t)
+  body := get(h, "/", false).Body.String()
+  m := regexp.MustCompile(`<script src="(/static/app\.[0-9a-f]+\.js)" integrity="(sha384-[^"]+)"`).FindStringSubmatch(body)
+  if m == nil { t.Fatalf("no hashed script with SRI in\n%s", body) }
+  w := get(h, m[1], false)
+  if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "X-Partial") || !strings.Contains(w.Header().Get("Cache-Control"), "immutable") { t.Fatalf("%s: %d %v", m[1], w.Code, w.Header()) }
+  if !strings.Contains(body, `<link rel="stylesheet" href="/static/app.`) { t.Errorf("no hashed stylesheet") }
+}
+
+func TestWeb_SearchSuggestion(t *testing.T) {
+  body := get(hostileHandler(t), "/search?q=pengasi", true).Body.String()
+  if !strings.Contains(body, `Did you mean <a href="#" data-get="/search?q=pengasih"`) || !strings.Contains(body, "<mark>Pengasih</mark>") { t.Fatalf("no suggestion:\n%s", body) }
+}
+
+func TestWeb_Mushaf(t *testing.T) {
+  d := hostileDB(t)
+  h := handler(t, d)
+  for url, code := range map[string]int{"/page/0": 400, "/page/605": 400, "/page/x": 400, "/page?ref=1:9": 400} {
+    if w := get(h, url, false); w.Code != code { t.Errorf("%s: %d, want %d", url, w.Code, code) }
+  }
+  // the built-in Madani table puts all of al-Fatihah on page 1
+  w := get(h, "/page/1", false)
+  if body := w.Body.String(); w.Code != http.StatusOK || !strings.Contains(body, `id="a1-1"`) || !strings.Contains(body, `id="a1-2"`) { t.Fatalf("/page/1 without mushaf_page: %d\n%s", w.Code, body) }
+  if w := get(h, "/page?ref=1:2", false); w.Header().Get("Location") != "/page/1#a1-2" { t.Errorf("/page?ref=1:2: %d %q", w.Code, w.Header().Get("Location")) }
+
+  // stored page starts override it
+  d.MustExec(`INSERT INTO mushaf_page(page,surah,number) VALUES(1,1,1),(2,1,2)`)
+  w = get(h, "/page/1", false)
+  body := w.Body.String()
+  if w.Code != http.StatusOK { t.Fatalf("/page/1: %d %s", w.Code, body) }
+  for _, want := range []string{`<link rel="next" href="/page/2">`, `data-key="next" href="/page/2"`, `id="a1-1"`, `﴿١﴾`, `&lt;script&gt;alert(&#34;arabic&#34;)`, `data-mushaf`} {
+    if !strings.Contains(body, want) { t.Errorf("/page/1: missing %s in\n%s", want, body) }
+  }
+  if strings.Contains(body, `rel="prev"`) || strings.Contains(body, `id="a1-2"`) { t.Errorf("/page/1 has a previous page or the next page's ayah") }
+  if raw := rawTags(body); raw != nil { t.Errorf("/page/1: unescaped %q", raw) }
+
+  for url, loc := range map[string]string{"/page?ref=1:2": "/page/2#a1-2", "/page?n=42": "/page/42"} {
+    if w := get(h, url, false); w.Code != http.StatusFound || w.Header().Get("Location") != loc { t.Errorf("%s: %d %q", url, w.Code, w.Header().Get("Location")) }
+  }
+}
+
+func TestWeb_InvalidInput(t *testing.T) {
+  h := hostileHandler(t)
+  for _, url := range []string{"/s/0", "/s/115", "/s/x", "/s/", "/s/1/2"} {
+    if w := get(h, url, true); w.Code != http.StatusBadRequest { t.Errorf("%s: expected 400, got %d", url, w.Code) }
+  }
+  w := get(h, `/search?q=%22%3Cscript%3E`, true)
+  if w.Code != http.StatusBadRequest || strings.Contains(w.Body.String(), "<script>") { t.Errorf("syntax error: %d %s", w.Code, w.Body.String()) }
+  if w := get(h, "/nope", false); w.Code != http.StatusNotFound { t.Errorf("/nope: %d", w.Code) }
+}
@@ -16,4 +16,5 @@ type Ayah struct {
     Trans   string `json:"translation,omitempty"`
     Juz     int    `json:"juz"`
     Audio   string `json:"audio,omitempty"`
+    Page    int    `json:"page,omitempty"` // Madani mushaf page, 0 if unknown
 }
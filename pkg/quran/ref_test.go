@@ -0,0 +1,92 @@
+package quran
+
+import "testing"
+
+func TestVerseCountsTotal(t *testing.T) {
+    sum := 0
+    for _, c := range VerseCounts {
+        sum += c
+    }
+    if sum != TotalAyah {
+        t.Fatalf("verse counts sum to %d, want %d", sum, TotalAyah)
+    }
+}
+
+func TestParseRange(t *testing.T) {
+    cases := map[string]Range{
+        "2":          {Ref{2, 1}, Ref{2, 286}},
+        "2:255":      {Ref{2, 255}, Ref{2, 255}},
+        "2:255-257":  {Ref{2, 255}, Ref{2, 257}},
+        "2:285-3:2":  {Ref{2, 285}, Ref{3, 2}},
+        "112-114":    {Ref{112, 1}, Ref{114, 6}},
+        "juz:30":     {Ref{78, 1}, Ref{114, 6}},
+        "j1":         {Ref{1, 1}, Ref{2, 141}},
+        "J14":        {Ref{15, 1}, Ref{16, 128}},
+    }
+    for in, want := range cases {
+        got, err := ParseRange(in)
+        if err != nil {
+            t.Fatalf("ParseRange(%q): %v", in, err)
+        }
+        if got != want {
+            t.Errorf("ParseRange(%q) = %v, want %v", in, got, want)
+        }
+    }
+    for _, bad := range []string{"", "0", "115", "2:0", "2:287", "2:10-5", "juz:31", "a:b"} {
+        if _, err := ParseRange(bad); err == nil {
+            t.Errorf("ParseRange(%q): expected error", bad)
+        }
+    }
+}
+
+func TestJuzOf(t *testing.T) {
+    cases := map[Ref]int{{1, 1}: 1, {2, 141}: 1, {2, 142}: 2, {2, 255}: 3, {18, 74}: 15, {114, 6}: 30}
+    for r, want := range cases {
+        if got := JuzOf(r); got != want {
+            t.Errorf("JuzOf(%v) = %d, want %d", r, got, want)
+        }
+    }
+}
+
+func TestRangeLen(t *testing.T) {
+    cases := map[string]int{"2:255": 1, "2": 286, "2:285-3:2": 4, "juz:30": 564, "1-114": TotalAyah}
+    for in, want := range cases {
+        r, _ := ParseRange(in)
+        if got := r.Len(); got != want {
+            t.Errorf("%s: Len() = %d, want %d", in, got, want)
+        }
+    }
+}
+
+func TestPages(t *testing.T) {
+    for i := 1; i < MushafPages; i++ {
+        if PageStarts[i-1].Key() >= PageStarts[i].Key() || !PageStarts[i].Valid() {
+            t.Fatalf("page %d starts at %v after %v", i+1, PageStarts[i], PageStarts[i-1])
+        }
+    }
+    // juz n is on page 20(n-1)+2, except juz 1 (page 1), 7 (121) and 11 (201)
+    for j, s := range JuzStarts {
+        want := 20*j + 2
+        switch j + 1 {
+        case 1:
+            want = 1
+        case 7, 11:
+            want = 20*j + 1
+        }
+        if got := PageOf(s); got != want {
+            t.Errorf("juz %d (%v) on page %d, want %d", j+1, s, got, want)
+        }
+    }
+    cases := map[Ref]int{{1, 7}: 1, {2, 1}: 2, {2, 255}: 42, {3, 1}: 50, {18, 1}: 293, {36, 1}: 440, {67, 1}: 562, {112, 1}: 604, {114, 6}: 604}
+    for r, want := range cases {
+        if got := PageOf(r); got != want {
+            t.Errorf("PageOf(%v) = %d, want %d", r, got, want)
+        }
+    }
+    if r := PageRange(2); r != (Range{Ref{2, 1}, Ref{2, 5}}) {
+        t.Errorf("PageRange(2) = %v", r)
+    }
+    if r := PageRange(604); r != (Range{Ref{112, 1}, Ref{114, 6}}) {
+        t.Errorf("PageRange(604) = %v", r)
+    }
+}
@@ -2,19 +2,129 @@ package main
 
 import (
   "context"
+  "flag"
+  "fmt"
+  "io"
   "log"
   "os"
+  "os/signal"
+  "strings"
+  "syscall"
+
+  "github.com/jmoiron/sqlx"
 
   "github.com/foozio/quran-go/internal/data"
   "github.com/foozio/quran-go/internal/db"
+  "github.com/foozio/quran-go/internal/similar"
 )
 
 func main(){
-  ctx := context.Background()
-  path := os.Getenv("QURAN_DB_PATH")
-  if path == "" { path = "quran.db" }
-  d, err := db.Open(path); if err != nil { log.Fatal(err) }
-  if err := db.Migrate(ctx, d); err != nil { log.Fatal(err) }
-  if err := data.IngestAll(ctx, d, "id"); err != nil { log.Fatal(err) }
+  ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+  defer stop()
+  source := flag.String("source", "quranjson", "quranjson, tanzil-xml, tanzil-txt, qurancom or csv")
+  lang := flag.String("lang", "", "translation language (quranjson default \"id\"; marks tanzil files as translations; required for csv)")
+  workers := flag.Int("workers", 4, "concurrent downloads (quranjson)")
+  force := flag.Bool("force", false, "ignore checkpoints and download everything again (quranjson)")
+  update := flag.Bool("update", false, "apply only rows that differ from the database and record a data version")
+  note := flag.String("note", "", "note stored with the data version (-update)")
+  vectors := flag.Bool("vectors", true, "rebuild the similar-verse vectors after loading")
+  flag.Parse()
+  files := flag.Args()
+
+  d, err := openDB(ctx, db.ConfigFromEnv()); if err != nil { log.Fatal(err) }
+
+  if *source == "quranjson" {
+    l := *lang
+    if l == "" { l = "id" }
+    cfg := data.IngestConfig{Workers: *workers, Force: *force, Progress: progressBar(os.Stderr)}
+    if *update {
+      ds, err := data.FetchDataset(ctx, l, cfg)
+      fmt.Fprintln(os.Stderr)
+      if err != nil { log.Fatal(err) }
+      if applyUpdate(ctx, d, ds, data.UpdateOptions{PrimaryLang: l, Note: *note}) && *vectors { buildVectors(ctx, d) }
+      return
+    }
+    if err := data.IngestAll(ctx, d, l, cfg); err != nil {
+      fmt.Fprintln(os.Stderr)
+      if ctx.Err() != nil { log.Fatal("interrupted; rerun to resume from the last completed surah") }
+      log.Fatalf("%v (rerun to resume)", err)
+    }
+    fmt.Fprintln(os.Stderr)
+    if *vectors { buildVectors(ctx, d) }
+    log.Println("Done.")
+    return
+  }
+
+  if len(files) == 0 { log.Fatalf("-source %s needs at least one input file", *source) }
+  var adapters []data.Adapter
+  for _, f := range files {
+    switch *source {
+    case "tanzil-xml":
+      adapters = append(adapters, data.TanzilXML{Path: f, Lang: *lang})
+    case "tanzil-txt":
+      adapters = append(adapters, data.TanzilText{Path: f, Lang: *lang})
+    case "csv":
+      adapters = append(adapters, data.TranslationCSV{Path: f, Lang: *lang})
+    case "qurancom":
+      if len(adapters) == 0 { adapters = append(adapters, data.QuranCom{Paths: files, Lang: *lang}) }
+    default:
+      log.Fatalf("unknown source %q", *source)
+    }
+  }
+  changed := false
+  for _, a := range adapters {
+    ds, err := a.Load(ctx)
+    if err != nil { log.Fatal(err) }
+    if *update {
+      changed = applyUpdate(ctx, d, ds, data.UpdateOptions{Note: *note}) || changed
+      continue
+    }
+    if err := data.Ingest(ctx, d, ds, data.IngestOptions{}); err != nil { log.Fatal(err) }
+    log.Printf("%s: %d surah, %d ayah, %d translations", ds.Source, len(ds.Surahs), len(ds.Ayat), len(ds.Translations))
+    changed = true
+  }
+  if changed && *vectors { buildVectors(ctx, d) }
   log.Println("Done.")
 }
+
+// openDB opens the configured backend for writing. SQLite files are opened
+// directly so a missing quran.db is created rather than falling back to an
+// embedded read-only snapshot.
+func openDB(ctx context.Context, cfg db.Config) (*sqlx.DB, error) {
+  if cfg.Driver != db.DriverSQLite {
+    st, err := db.OpenStore(ctx, cfg)
+    if err != nil { return nil, err }
+    return st.DB(), nil
+  }
+  d, err := db.Open(cfg.DSN)
+  if err != nil { return nil, err }
+  return d, db.Migrate(ctx, d)
+}
+
+// applyUpdate applies ds and reports whether anything changed.
+func applyUpdate(ctx context.Context, d *sqlx.DB, ds *data.Dataset, opt data.UpdateOptions) bool {
+  v, err := data.Update(ctx, d, ds, opt)
+  if err != nil { log.Fatal(err) }
+  if v.Empty() { log.Printf("%s: already up to date", ds.Source); return false }
+  log.Printf("%s: version %d: %d surah changed, %d ayah added, %d changed, %d translations added, %d changed",
+    ds.Source, v.ID, v.SurahChanged, v.AyahAdded, v.AyahChanged, v.TransAdded, v.TransChanged)
+  return true
+}
+
+// buildVectors recomputes the TF-IDF/LSA vectors behind similar-verse search.
+func buildVectors(ctx context.Context, d *sqlx.DB) {
+  n, err := similar.Build(ctx, d)
+  if err != nil { log.Fatalf("vectors: %v", err) }
+  log.Printf("vectors: %d ayah", n)
+}
+
+// progressBar renders IngestAll progress as a single updating line.
+func progressBar(w io.Writer) func(data.Progress) {
+  const width = 30
+  return func(p data.Progress) {
+    filled := width * p.Done / p.Total
+    note := ""
+    if p.Skipped { note = " (resumed)" }
+    fmt.Fprintf(w, "\r[%s%s] %3d/%d surah %-3d%s   ", strings.Repeat("#", filled), strings.Repeat(".", width-filled), p.Done, p.Total, p.Surah, note)
+  }
+}
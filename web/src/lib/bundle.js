@@ -0,0 +1,115 @@
+// Answers for the API's reading routes computed from the offline bundle
+// (GET /bundle), in the same JSON shapes, so pages work unchanged offline.
+// Shared by the service worker and the app.
+
+export const DATA_CACHE = 'quran-data';
+export const BUNDLE_KEY = '/offline/bundle';
+
+// GET /surah
+export function surahList(b) {
+  return b.surahs;
+}
+
+// GET /surah/:n, or null when the bundle has no such surah
+export function surah(b, n) {
+  if (!b.surahs.some((s) => s.number === n)) return null;
+  const ayah = b.ayah
+    .filter((a) => a.surah === n)
+    .map((a) => ({ ayah: a.ayah, arabic: a.arabic, tajweed: '', trans: a.trans || '', audio_url: '' }));
+  return { surah: n, ayah };
+}
+
+// Harakat, Quranic annotation marks and tatweel are ignored when matching,
+// and the alef forms are folded, so a query typed without diacritics finds
+// the vowelled text.
+const MARKS = /[\u0610-\u061A\u0640\u064B-\u065F\u0670\u06D6-\u06ED]/;
+const ALEF = /[\u0622\u0623\u0625\u0671]/;
+const ARABIC = /[\u0600-\u06FF]/;
+
+// fold returns the folded text and, for every UTF-16 unit of it, the code
+// point offset in the original, which is what match ranges count.
+function fold(text) {
+  let s = '';
+  const pos = [];
+  const cp = Array.from(text);
+  cp.forEach((c, i) => {
+    if (MARKS.test(c)) return;
+    const f = ALEF.test(c) ? '\u0627' : c.toLowerCase();
+    s += f;
+    for (let k = 0; k < f.length; k++) pos.push(i);
+  });
+  return { s, pos, cp };
+}
+
+// parse reads the API's query syntax as far as plain substring matching
+// allows: "phrases", OR between alternatives, -word to exclude, ar:/tr: to
+// pick the column, and a trailing * (already implied by substring matching).
+function parse(q) {
+  const groups = [];
+  const not = [];
+  let or = false;
+  for (let tok of q.match(/-?(?:(?:ar|tr):)?"[^"]*"|\S+/g) || []) {
+    if (tok === 'OR') { or = groups.length > 0; continue; }
+    const neg = tok.startsWith('-');
+    if (neg) tok = tok.slice(1);
+    let col = '';
+    const m = /^(ar|tr):/.exec(tok);
+    if (m) { col = m[1] === 'ar' ? 'arabic' : 'trans'; tok = tok.slice(3); }
+    const text = fold(tok.replace(/^"|"$/g, '').replace(/\*$/, '')).s.trim();
+    if (!text) continue;
+    const term = { text, col };
+    if (neg) not.push(term);
+    else if (or) groups[groups.length - 1].push(term);
+    else groups.push([term]);
+    or = false;
+  }
+  return { groups, not };
+}
+
+// spans returns the merged ranges of text where any term occurs, or null
+// when some group has no term in it.
+function spans(f, col, groups) {
+  const found = [];
+  for (const g of groups) {
+    let hit = false;
+    for (const t of g) {
+      if (t.col && t.col !== col) continue;
+      for (let at = f.s.indexOf(t.text); at >= 0; at = f.s.indexOf(t.text, at + t.text.length)) {
+        let end = f.pos[at + t.text.length - 1] + 1;
+        while (end < f.cp.length && MARKS.test(f.cp[end])) end++; // the last letter's marks
+        found.push({ start: f.pos[at], end });
+        hit = true;
+      }
+    }
+    if (!hit) return null;
+  }
+  found.sort((a, b) => a.start - b.start || b.end - a.end);
+  const out = [];
+  for (const s of found) {
+    const last = out[out.length - 1];
+    if (last && s.start <= last.end) last.end = Math.max(last.end, s.end);
+    else out.push({ ...s });
+  }
+  return out;
+}
+
+// GET /search?q=, first limit hits in mushaf order
+export function search(b, q, limit = 50) {
+  const { groups, not } = parse(q);
+  const hits = [];
+  if (!groups.length) return { q, hits };
+  const cols = ARABIC.test(q) ? ['arabic', 'trans'] : ['trans', 'arabic'];
+  for (const a of b.ayah) {
+    const folded = {};
+    const text = (col) => (folded[col] ||= fold(a[col] || ''));
+    if (not.some((t) => (t.col ? [t.col] : cols).some((c) => text(c).s.includes(t.text)))) continue;
+    for (const col of cols) {
+      const matches = spans(text(col), col, groups);
+      if (!matches) continue;
+      hits.push({ surah: a.surah, number: a.ayah, column: col, text: a[col], matches });
+      break;
+    }
+    if (hits.length >= limit) break;
+  }
+  return { q, hits };
+}
@@ -0,0 +1,101 @@
+// Memorization (hifz) deck kept in the browser's localStorage: SM-2 spaced
+// repetition and progressive masking, the same rules as internal/hifz in Go.
+
+const KEY = 'quran-hifz';
+const GAP = '…';
+
+export const LEVEL_FULL = 0;   // the whole text
+export const LEVEL_HINT = 1;   // first letter of each word
+export const LEVEL_HALF = 2;   // every other word hidden, the rest hinted
+export const LEVEL_HIDDEN = 3; // every word hidden
+
+export const GRADES = [
+  { grade: 0, label: 'Forgot' },
+  { grade: 1, label: 'Wrong' },
+  { grade: 2, label: 'Almost' },
+  { grade: 3, label: 'Hard' },
+  { grade: 4, label: 'Good' },
+  { grade: 5, label: 'Perfect' }
+];
+
+// today as YYYY-MM-DD in local time
+export function today(d = new Date()) {
+  const p = (n) => String(n).padStart(2, '0');
+  return `${d.getFullYear()}-${p(d.getMonth() + 1)}-${p(d.getDate())}`;
+}
+
+function addDays(day, n) {
+  const [y, m, d] = day.split('-').map(Number);
+  return today(new Date(y, m - 1, d + n));
+}
+
+export function load() {
+  try {
+    return JSON.parse(localStorage.getItem(KEY) || '[]');
+  } catch (_) {
+    return [];
+  }
+}
+
+export function save(cards) {
+  cards.sort((a, b) => a.surah - b.surah || a.ayah - b.ayah);
+  localStorage.setItem(KEY, JSON.stringify(cards));
+}
+
+// add new cards, due today, for the ayah not yet in the deck
+export function add(cards, surah, ayat) {
+  const have = new Set(cards.map((c) => c.surah + ':' + c.ayah));
+  let n = 0;
+  for (const a of ayat) {
+    if (have.has(surah + ':' + a)) continue;
+    cards.push({ surah, ayah: a, ease: 2.5, interval: 0, reps: 0, due: today(), grade: -1 });
+    n++;
+  }
+  return n;
+}
+
+// due cards, the most overdue first and in mushaf order within a day
+export function due(cards, day = today()) {
+  return cards
+    .filter((c) => c.due <= day)
+    .sort((a, b) => (a.due < b.due ? -1 : a.due > b.due ? 1 : a.surah - b.surah || a.ayah - b.ayah));
+}
+
+// SM-2: grade 0-5, below 3 starts the ayah over
+export function review(c, grade, day = today()) {
+  c = { ...c, ease: c.ease || 2.5 };
+  if (grade < 3) {
+    c.reps = 0;
+    c.interval = 1;
+  } else {
+    c.interval = c.reps === 0 ? 1 : c.reps === 1 ? 6 : Math.round(c.interval * c.ease);
+    c.reps++;
+  }
+  const q = 5 - grade;
+  c.ease = Math.max(1.3, c.ease + 0.1 - q * (0.08 + q * 0.02));
+  c.grade = grade;
+  c.due = addDays(day, c.interval);
+  return c;
+}
+
+export function suggested(c) {
+  return c.reps === 0 ? LEVEL_HINT : c.reps === 1 ? LEVEL_HALF : LEVEL_HIDDEN;
+}
+
+// mask hides words to level; tokens without letters (pause marks) are kept
+export function mask(text, level) {
+  if (level <= LEVEL_FULL) return text;
+  let n = 0;
+  return (text || '')
+    .split(/\s+/)
+    .filter(Boolean)
+    .map((w) => {
+      if (!/\p{L}/u.test(w)) return w;
+      const hidden = level >= LEVEL_HIDDEN || (level === LEVEL_HALF && n % 2 === 1);
+      n++;
+      if (hidden) return GAP;
+      const m = w.match(/^[^\p{L}]*\p{L}\p{M}*/u);
+      return m[0].length < w.length ? m[0] + GAP : w;
+    })
+    .join(' ');
+}
@@ -0,0 +1,68 @@
+// Offline copy of the text: the bundle from GET /bundle is kept in the Cache
+// Storage (where the service worker reads it) and what was saved is noted in
+// localStorage.
+
+import { DATA_CACHE, BUNDLE_KEY } from './bundle.js';
+
+const KEY = 'quran-offline';
+const base = () => import.meta.env.VITE_API_URL || '/api';
+
+export function supported() {
+  return typeof caches !== 'undefined' && 'serviceWorker' in navigator;
+}
+
+// the saved copy as { lang, version, bytes, saved }, or null
+export function status() {
+  try {
+    return JSON.parse(localStorage.getItem(KEY) || 'null');
+  } catch (_) {
+    return null;
+  }
+}
+
+// { primary, langs } from the API
+export async function langs() {
+  const res = await fetch(base() + '/bundle/langs');
+  if (!res.ok) throw new Error(await res.text());
+  return await res.json();
+}
+
+// tell the service worker to reread the bundle
+function notify() {
+  navigator.serviceWorker?.controller?.postMessage({ type: 'bundle' });
+}
+
+async function fetchBundle(lang, version) {
+  const headers = version ? { 'If-None-Match': `"${version}"` } : {};
+  const res = await fetch(base() + '/bundle?lang=' + encodeURIComponent(lang || ''), { headers, cache: 'no-store' });
+  if (res.status === 304) return false;
+  if (!res.ok) throw new Error(await res.text());
+  const body = await res.text();
+  const b = JSON.parse(body);
+  const cache = await caches.open(DATA_CACHE);
+  await cache.put(BUNDLE_KEY, new Response(body, { headers: { 'Content-Type': 'application/json' } }));
+  localStorage.setItem(KEY, JSON.stringify({ lang: b.lang, version: b.version, bytes: body.length, saved: new Date().toISOString() }));
+  notify();
+  return true;
+}
+
+// download saves the text with the translation in lang (empty for the
+// primary one), replacing any saved copy.
+export function download(lang) {
+  return fetchBundle(lang, '');
+}
+
+// update fetches the saved language again unless the server still has the
+// same version; it reports whether anything changed.
+export function update() {
+  const s = status();
+  if (!s) return Promise.resolve(false);
+  return fetchBundle(s.lang, s.version);
+}
+
+export async function remove() {
+  const cache = await caches.open(DATA_CACHE);
+  await cache.delete(BUNDLE_KEY);
+  localStorage.removeItem(KEY);
+  notify();
+}
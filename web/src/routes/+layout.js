@@ -0,0 +1,3 @@
+// Offline, every route is served the cached app shell, so pages render in
+// the browser rather than on the server.
+export const ssr = false;
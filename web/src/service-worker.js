@@ -0,0 +1,100 @@
+/// <reference types="@sveltejs/kit" />
+/// <reference lib="webworker" />
+
+// Keeps the app usable offline: the app shell is precached per build, pages
+// fall back to the cached shell, and the API's reading routes (surah list,
+// surah, search) fall back to the bundle saved from /offline.
+
+import { build, files, version } from '$service-worker';
+import { DATA_CACHE, BUNDLE_KEY, surahList, surah, search } from '$lib/bundle';
+
+const SHELL = `shell-${version}`;
+const ASSETS = new Set([...build, ...files, '/']);
+const API = new URL(import.meta.env.VITE_API_URL || '/api', self.location.origin).href.replace(/\/$/, '');
+
+self.addEventListener('install', (event) => {
+  event.waitUntil(caches.open(SHELL).then((c) => c.addAll([...ASSETS])).then(() => self.skipWaiting()));
+});
+
+self.addEventListener('activate', (event) => {
+  event.waitUntil((async () => {
+    for (const key of await caches.keys()) {
+      if (key.startsWith('shell-') && key !== SHELL) await caches.delete(key);
+    }
+    await self.clients.claim();
+  })());
+});
+
+let saved; // the parsed bundle, read once
+function bundle() {
+  saved ||= caches.open(DATA_CACHE)
+    .then((c) => c.match(BUNDLE_KEY))
+    .then((res) => (res ? res.json() : null))
+    .catch(() => null);
+  return saved;
+}
+
+self.addEventListener('message', (event) => {
+  if (event.data?.type === 'bundle') saved = undefined;
+});
+
+const json = (body, status = 200) =>
+  new Response(JSON.stringify(body), { status, headers: { 'Content-Type': 'application/json', 'X-Offline': '1' } });
+
+// answer computes a reading route from the bundle, or returns null.
+async function answer(url) {
+  const path = url.href.slice(API.length).split('?')[0];
+  const route = /^\/surah(?:\/(\d+))?$/.exec(path);
+  if (!route && path !== '/search') return null;
+  const b = await bundle();
+  if (!b) return null;
+  if (path === '/search') return json(search(b, (url.searchParams.get('q') || '').trim()));
+  if (!route[1]) return json(surahList(b));
+  const s = surah(b, Number(route[1]));
+  return s ? json(s) : json({ error: 'invalid surah number' }, 400);
+}
+
+// The API is network-first so reads stay current; a failed request (or a
+// proxy error while the API is down) is answered from the bundle.
+async function fromAPI(request) {
+  const url = new URL(request.url);
+  try {
+    const res = await fetch(request);
+    if (res.status < 500) return res;
+    return (await answer(url)) || res;
+  } catch (err) {
+    const res = await answer(url);
+    if (res) return res;
+    throw err;
+  }
+}
+
+async function fromShell(request) {
+  const cache = await caches.open(SHELL);
+  return (await cache.match(request)) || fetch(request);
+}
+
+// Pages are network-first; offline, every route gets the cached shell,
+// which renders client side.
+async function page(request) {
+  try {
+    return await fetch(request);
+  } catch (err) {
+    const res = await (await caches.open(SHELL)).match('/');
+    if (res) return res;
+    throw err;
+  }
+}
+
+self.addEventListener('fetch', (event) => {
+  const { request } = event;
+  if (request.method !== 'GET') return;
+  const url = new URL(request.url);
+  if (url.href.startsWith(API + '/')) {
+    event.respondWith(fromAPI(request));
+    return;
+  }
+  if (url.origin !== self.location.origin) return;
+  if (ASSETS.has(url.pathname) && url.pathname !== '/') event.respondWith(fromShell(request));
+  else if (request.mode === 'navigate') event.respondWith(page(request));
+});